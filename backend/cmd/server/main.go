@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,94 +11,176 @@ import (
 	"time"
 
 	"gatekeep/internal/api"
+	"gatekeep/internal/attestation"
+	"gatekeep/internal/audit"
+	"gatekeep/internal/captcha"
 	"gatekeep/internal/config"
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/logging"
 	"gatekeep/internal/queue"
 	redisclient "gatekeep/internal/redis"
 	"gatekeep/internal/release"
 	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
 )
 
 func main() {
-	log.Println("Gatekeep server starting...")
+	slog.Info("Gatekeep server starting")
 
 	// Load configuration from environment variables
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to load configuration", err)
 	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+		fatal("Invalid configuration", err)
 	}
 
-	log.Printf("Configuration loaded successfully")
-	log.Printf("Server port: %d", cfg.Port)
-	log.Printf("Metrics port: %d", cfg.MetricsPort)
-	log.Printf("Redis address: %s", cfg.RedisAddr)
-	log.Printf("Log level: %s", cfg.LogLevel)
+	// Log as JSON at the configured level from here on
+	if err := logging.Setup(cfg.LogLevel); err != nil {
+		fatal("Invalid log level", err)
+	}
+
+	slog.Info("Configuration loaded",
+		"port", cfg.Port,
+		"metrics_port", cfg.MetricsPort,
+		"redis_addr", cfg.RedisAddr,
+		"log_level", cfg.LogLevel,
+	)
 
 	// Initialize Redis client
 	redisClient, err := redisclient.NewClient(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize Redis client: %v", err)
+		fatal("Failed to initialize Redis client", err)
 	}
 	defer redisClient.Close()
 
-	log.Println("Redis connection established")
+	slog.Info("Redis connection established")
 
 	// Verify Redis health
 	ctx := context.Background()
 	if err := redisClient.HealthCheck(ctx); err != nil {
-		log.Fatalf("Redis health check failed: %v", err)
+		fatal("Redis health check failed", err)
 	}
 
-	log.Println("Redis health check passed")
+	slog.Info("Redis health check passed")
 
 	// Initialize queue manager
 	queueManager := queue.NewManager(redisClient)
-	log.Println("Queue manager initialized")
+	slog.Info("Queue manager initialized")
+
+	// Initialize device attestation (optional)
+	if cfg.AttestationRootsFile != "" {
+		roots, err := attestation.LoadRoots(cfg.AttestationRootsFile)
+		if err != nil {
+			fatal("Failed to load attestation roots", err)
+		}
+		policy := attestation.DefaultPolicy()
+		if len(cfg.AttestationAllow) > 0 {
+			policy.Allow = cfg.AttestationAllow
+		}
+		if len(cfg.AttestationDeprioritize) > 0 {
+			policy.Deprioritize = cfg.AttestationDeprioritize
+		}
+		queueManager.SetAttestationVerifier(attestation.NewJWSVerifier(roots, policy))
+		slog.Info("Device attestation enabled")
+	}
 
 	// Initialize token generator
 	tokenGen := token.NewGenerator(redisClient, cfg.TokenSecret)
-	log.Println("Token generator initialized")
+	tokenGen.SetIssuer(cfg.TokenIssuer)
+	queueManager.SetTokenSealer(token.NewSealer(cfg.TokenSecret))
+	slog.Info("Token generator initialized")
 
 	// Initialize token verifier
 	tokenVerifier := token.NewVerifier(redisClient, cfg.TokenSecret)
-	log.Println("Token verifier initialized")
-	_ = tokenVerifier // Will be used in future API endpoints
+	tokenVerifier.SetIssuer(cfg.TokenIssuer)
+	tokenVerifier.SetLeeway(cfg.TokenLeeway)
+	slog.Info("Token verifier initialized")
 
 	// Initialize release controller
 	releaseController := release.NewController(redisClient, tokenGen)
-	log.Println("Release controller initialized")
+	slog.Info("Release controller initialized")
 
 	// Load release state from Redis
 	if err := releaseController.LoadState(); err != nil {
-		log.Printf("Warning: Failed to load release state: %v", err)
+		slog.Warn("Failed to load release state", "error", err)
 	} else {
-		log.Println("Release state loaded from Redis")
+		slog.Info("Release state loaded from Redis")
+	}
+
+	// Initialize lifecycle event publishing (optional)
+	var publisher lifecycle.Publisher
+	if cfg.EventStreamMaxLen > 0 {
+		publisher = lifecycle.NewStreamPublisher(redisClient, cfg.EventStreamMaxLen)
+		queueManager.SetLifecyclePublisher(publisher)
+		releaseController.SetLifecyclePublisher(publisher)
+		slog.Info("Lifecycle events published to Redis stream", "stream", lifecycle.StreamKey)
 	}
 
+	// Start webhook delivery
+	webhooks := webhook.NewDispatcher(redisClient)
+	webhooks.SetAllowPrivateNetworks(cfg.WebhookAllowPrivateNetworks)
+	queueManager.SetWebhookDispatcher(webhooks)
+	releaseController.SetWebhookDispatcher(webhooks)
+	webhooks.Start()
+	slog.Info("Webhook dispatcher started")
+	defer webhooks.Stop()
+
 	// Start release scheduler
 	releaseController.Start()
-	log.Println("Release scheduler started")
+	slog.Info("Release scheduler started")
 	defer releaseController.Stop()
 
+	// Start status watcher for streaming clients
+	statusWatcher := queue.NewWatcher(queueManager)
+	statusWatcher.Start()
+	slog.Info("Status watcher started")
+	defer statusWatcher.Stop()
+
+	// Initialize admin audit log
+	auditLog := audit.NewLog(redisClient)
+	if cfg.AuditLogFile != "" {
+		auditFile, err := audit.OpenFileSink(cfg.AuditLogFile)
+		if err != nil {
+			fatal("Failed to open audit log file", err)
+		}
+		defer auditFile.Close()
+		auditLog.SetSink(auditFile)
+		slog.Info("Audit entries appended to file", "path", cfg.AuditLogFile)
+	}
+
+	// Initialize human verification (optional)
+	var captchaChecker *captcha.Checker
+	if cfg.CaptchaVerifyURL != "" {
+		verifier := captcha.NewSiteVerifier(cfg.CaptchaVerifyURL, cfg.CaptchaSecret)
+		captchaChecker = captcha.NewChecker(redisClient, verifier, cfg.CaptchaCacheWindow)
+		slog.Info("Human verification enabled")
+	}
+
 	// Initialize API server
-	apiServer := api.NewServer(cfg, queueManager, releaseController)
-	log.Println("API server initialized")
+	apiServer := api.NewServer(cfg, queueManager, releaseController, statusWatcher, auditLog, tokenVerifier, captchaChecker, webhooks, publisher)
+	slog.Info("API server initialized")
 
 	// Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Start server in a goroutine
+	// Start servers in a goroutine
+	adminPort := cfg.Port
+	if cfg.AdminOnMetricsPort {
+		adminPort = cfg.MetricsPort
+	}
 	serverErrChan := make(chan error, 1)
 	go func() {
-		log.Printf("HTTP server listening on port %d", cfg.Port)
-		log.Printf("Metrics endpoint available at http://localhost:%d/metrics", cfg.Port)
-		log.Printf("Health check available at http://localhost:%d/health", cfg.Port)
-		log.Printf("Admin API available at http://localhost:%d/admin/*", cfg.Port)
+		slog.Info("HTTP server listening", "port", cfg.Port)
+		slog.Info("Metrics server listening", "port", cfg.MetricsPort)
+		slog.Info("Metrics endpoint available", "url", fmt.Sprintf("http://localhost:%d/metrics", cfg.MetricsPort))
+		slog.Info("Profiling available", "url", fmt.Sprintf("http://localhost:%d/debug/pprof/", cfg.MetricsPort))
+		slog.Info("Health check available", "url", fmt.Sprintf("http://localhost:%d/health", cfg.Port))
+		slog.Info("Admin API available", "url", fmt.Sprintf("http://localhost:%d/admin/*", adminPort))
 		if err := apiServer.Start(); err != nil && err != http.ErrServerClosed {
 			serverErrChan <- err
 		}
@@ -106,16 +189,22 @@ func main() {
 	// Wait for interrupt signal or server error
 	select {
 	case sig := <-sigChan:
-		log.Printf("Received signal: %v, shutting down gracefully...", sig)
-		// Graceful shutdown with timeout
+		slog.Info("Shutting down gracefully", "signal", sig.String())
+		// Graceful shutdown of both servers with timeout
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		if err := apiServer.Shutdown(shutdownCtx); err != nil {
-			log.Printf("Error during server shutdown: %v", err)
+			slog.Error("Error during server shutdown", "error", err)
 		}
 	case err := <-serverErrChan:
-		log.Fatalf("Server error: %v", err)
+		fatal("Server error", err)
 	}
 
-	log.Println("Server shutdown complete")
+	slog.Info("Server shutdown complete")
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gatekeep/internal/queue"
+)
+
+// ListEventsResponse represents a page of events
+type ListEventsResponse struct {
+	Events []*queue.EventSummary `json:"events"`
+	queue.Page
+}
+
+// ListEntriesResponse represents a page of entries in an event's bucket
+type ListEntriesResponse struct {
+	EventID string              `json:"event_id"`
+	Bucket  string              `json:"bucket"`
+	Entries []*queue.QueueEntry `json:"entries"`
+	queue.Page
+}
+
+// LookupResponse represents the entries matching an admin lookup
+type LookupResponse struct {
+	Entries []*queue.EntryInfo `json:"entries"`
+}
+
+// parsePage parses the offset and limit query parameters
+func parsePage(r *http.Request) (int, int, error) {
+	offset, limit := 0, 0
+	var err error
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid offset: %s", value)
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit: %s", value)
+		}
+	}
+	offset, limit = queue.NormalizePage(offset, limit)
+	return offset, limit, nil
+}
+
+// HandleListEvents handles GET /admin/events
+func (h *Handler) HandleListEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	offset, limit, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	events, page, err := h.queueManager.ListEvents(offset, limit)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ListEventsResponse{Events: events, Page: page})
+}
+
+// HandleListEntries handles GET /admin/entries
+func (h *Handler) HandleListEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = "normal"
+	}
+
+	offset, limit, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	entries, page, err := h.queueManager.ListEntries(eventID, bucket, offset, limit)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ListEntriesResponse{
+		EventID: eventID,
+		Bucket:  bucket,
+		Entries: entries,
+		Page:    page,
+	})
+}
+
+// HandleLookup handles GET /admin/lookup
+func (h *Handler) HandleLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	queueID := query.Get("queue_id")
+	eventID := query.Get("event_id")
+	deviceID := query.Get("device_id")
+	userID := query.Get("user_id")
+
+	if queueID == "" && deviceID == "" && userID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "one of queue_id, device_id or user_id is required")
+		return
+	}
+	if queueID == "" && eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required to look up by device_id or user_id")
+		return
+	}
+
+	entries, err := h.queueManager.FindEntries(eventID, queueID, deviceID, userID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(LookupResponse{Entries: entries})
+}
+
+// HandleGetConfig handles GET /admin/config
+func (h *Handler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	config, err := h.queueManager.GetEventConfig(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(config)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gatekeep/internal/queue"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantOffset int
+		wantLimit  int
+		wantErr    bool
+	}{
+		{"defaults", "", 0, queue.DefaultPageLimit, false},
+		{"explicit", "offset=10&limit=25", 10, 25, false},
+		{"limit capped", "limit=100000", 0, queue.MaxPageLimit, false},
+		{"negative offset", "offset=-1", 0, 0, true},
+		{"zero limit", "limit=0", 0, 0, true},
+		{"non-numeric", "offset=abc", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/events?"+tt.query, nil)
+			offset, limit, err := parsePage(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (offset != tt.wantOffset || limit != tt.wantLimit) {
+				t.Errorf("parsePage() = (%d, %d), expected (%d, %d)", offset, limit, tt.wantOffset, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestHandleLookup_Validation(t *testing.T) {
+	handler := &Handler{}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"no identifier", "event_id=evt"},
+		{"device without event", "device_id=dev"},
+		{"user without event", "user_id=usr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/lookup?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.HandleLookup(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleGetConfig(t *testing.T) {
+	handler, apiKey, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/admin/config?event_id=test-event-get-config", nil)
+	req.Header.Set("X-API-Key", apiKey)
+	rr := httptest.NewRecorder()
+
+	handler.HandleGetConfig(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
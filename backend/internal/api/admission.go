@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"gatekeep/internal/queue"
+	"gatekeep/internal/token"
+)
+
+// RefreshRequest represents a request to refresh an admission token
+type RefreshRequest struct {
+	Token   string `json:"token"`
+	EventID string `json:"event_id,omitempty"`
+}
+
+// RefreshResponse represents a refreshed admission token
+type RefreshResponse struct {
+	AdmissionToken *queue.AdmissionToken `json:"admission_token"`
+}
+
+// VerifyRequest represents a request to verify an admission token. Bound
+// tokens also need the client's DPoP proof and the method and URL of the
+// request it was made for.
+type VerifyRequest struct {
+	Token   string `json:"token"`
+	EventID string `json:"event_id,omitempty"`
+	Proof   string `json:"proof,omitempty"`
+	Method  string `json:"htm,omitempty"`
+	URL     string `json:"htu,omitempty"`
+}
+
+// VerifyResponse reports whether an admission token is valid and, if so, its
+// claims
+type VerifyResponse struct {
+	Valid         bool               `json:"valid"`
+	EventID       string             `json:"event_id,omitempty"`
+	DeviceID      string             `json:"device_id,omitempty"`
+	UserID        string             `json:"user_id,omitempty"`
+	QueueID       string             `json:"queue_id,omitempty"`
+	IssuedAt      *time.Time         `json:"issued_at,omitempty"`
+	ExpiresAt     *time.Time         `json:"expires_at,omitempty"`
+	Entitlements  token.Entitlements `json:"entitlements,omitempty"`
+	Metadata      map[string]string  `json:"metadata,omitempty"`
+	KeyThumbprint string             `json:"key_thumbprint,omitempty"`
+	Code          string             `json:"code,omitempty"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// RegisterAdmissionRoutes registers routes for clients holding an admission token
+func (h *Handler) RegisterAdmissionRoutes(r *mux.Router) {
+	admissionRouter := r.PathPrefix("/admission").Subrouter()
+
+	admissionRouter.Use(RequestLoggingMiddleware())
+	admissionRouter.Use(RateLimitMiddleware())
+
+	admissionRouter.HandleFunc("/verify", h.HandleVerifyAdmission).Methods("POST")
+	admissionRouter.HandleFunc("/refresh", h.HandleRefreshAdmission).Methods("POST")
+}
+
+// HandleVerifyAdmission handles POST /admission/verify. Tokens that are
+// well-formed but invalid (bad signature, expired, revoked, other event, or
+// bound without a valid proof) are reported with valid=false; only malformed
+// requests are rejected.
+func (h *Handler) HandleVerifyAdmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.tokenVerifier == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "token verification is not available")
+		return
+	}
+
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "token is required")
+		return
+	}
+	if req.Proof != "" && (req.Method == "" || req.URL == "") {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "htm and htu are required with proof")
+		return
+	}
+
+	payload, err := h.tokenVerifier.VerifyToken(req.Token, req.EventID)
+	if err == nil {
+		// Bound tokens are only valid for the device holding the key
+		err = h.tokenVerifier.VerifyProof(req.Proof, payload, req.Token, req.Method, req.URL)
+	}
+	var resp VerifyResponse
+	if err != nil {
+		// Rejected tokens are a verification result; malformed tokens and
+		// failures to check revocation are errors
+		status, code := errorStatus(err)
+		if status != http.StatusUnauthorized {
+			writeDomainError(w, err)
+			return
+		}
+		resp = VerifyResponse{Valid: false, Code: code, Error: err.Error()}
+	} else {
+		annotateLog(r, payload.EventID, payload.QueueID)
+		resp = VerifyResponse{
+			Valid:         true,
+			EventID:       payload.EventID,
+			DeviceID:      payload.DeviceID,
+			UserID:        payload.UserID,
+			QueueID:       payload.QueueID,
+			IssuedAt:      &payload.IssuedAt,
+			ExpiresAt:     &payload.ExpiresAt,
+			Entitlements:  payload.Entitlements,
+			Metadata:      payload.Metadata,
+			KeyThumbprint: payload.KeyThumbprint,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// HandleRefreshAdmission handles POST /admission/refresh. A valid, unexpired
+// token is revoked and exchanged for a new one. The revocation is claimed
+// atomically before the new token is issued, so a token is refreshed once.
+func (h *Handler) HandleRefreshAdmission(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.tokenVerifier == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "token refresh is not available")
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "token is required")
+		return
+	}
+
+	old, err := h.tokenVerifier.VerifyToken(req.Token, req.EventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	annotateLog(r, old.EventID, old.QueueID)
+
+	// Bound tokens are only refreshed by the device holding the key
+	if err := h.tokenVerifier.VerifyProof(r.Header.Get("DPoP"), old, req.Token, r.Method, requestURL(r)); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	tokenString, payload, err := h.releaseController.RefreshAdmission(old, func() error {
+		return h.tokenVerifier.ClaimToken(req.Token, old)
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(RefreshResponse{
+		AdmissionToken: &queue.AdmissionToken{
+			Token:     tokenString,
+			TokenHash: token.TokenHash(tokenString),
+			EventID:   payload.EventID,
+			DeviceID:  payload.DeviceID,
+			UserID:    payload.UserID,
+			QueueID:   payload.QueueID,
+			IssuedAt:  payload.IssuedAt,
+			ExpiresAt: payload.ExpiresAt,
+		},
+	})
+}
+
+// requestURL reconstructs the URL a client used, for matching the "htu" of
+// proofs. TLS terminated by a trusted proxy is taken from the scheme resolved
+// by ClientIPMiddleware.
+func requestURL(r *http.Request) string {
+	return getRequestScheme(r) + "://" + r.Host + r.URL.Path
+}
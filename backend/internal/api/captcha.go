@@ -0,0 +1,20 @@
+package api
+
+import (
+	"fmt"
+
+	"gatekeep/internal/captcha"
+	"gatekeep/internal/queue"
+)
+
+// checkCaptcha enforces human verification on a join if its event requires
+// it for the bucket. Without a configured provider such joins fail closed.
+func (h *Handler) checkCaptcha(req JoinQueueRequest, config *queue.EventConfig, bucket, clientIP string) error {
+	if !config.RequiresCaptcha(bucket) {
+		return nil
+	}
+	if h.captchaChecker == nil {
+		return fmt.Errorf("%w: no provider is configured", captcha.ErrUnavailable)
+	}
+	return h.captchaChecker.Check(req.EventID, req.DeviceID, req.CaptchaToken, clientIP)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gatekeep/internal/captcha"
+	"gatekeep/internal/queue"
+)
+
+func TestHandleJoinQueue_Captcha(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("response") == "pass" {
+			_, _ = w.Write([]byte(`{"success":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	defer provider.Close()
+
+	eventID := "test-event-captcha"
+	if err := handler.queueManager.SetEventConfig(&queue.EventConfig{
+		EventID: eventID, Enabled: true, MaxSize: 100, CaptchaBuckets: []string{"presale"},
+		PriorityBuckets: []string{"presale", "general"},
+	}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	join := func(deviceID, bucket, captchaToken string) int {
+		body := fmt.Sprintf(`{"event_id":%q,"device_id":%q,"priority_bucket":%q,"captcha_token":%q}`,
+			eventID, deviceID, bucket, captchaToken)
+		req := httptest.NewRequest("POST", "/queue/join", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		handler.HandleJoinQueue(rr, req)
+		return rr.Code
+	}
+
+	// No provider configured: buckets requiring verification fail closed
+	if status := join("dev-1", "presale", "pass"); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 without a provider, got %d", status)
+	}
+
+	handler.captchaChecker = captcha.NewChecker(nil, captcha.NewSiteVerifier(provider.URL, "secret"), 0)
+
+	tests := []struct {
+		name         string
+		deviceID     string
+		bucket       string
+		captchaToken string
+		wantStatus   int
+	}{
+		{"bucket without verification", "dev-2", "general", "", http.StatusOK},
+		{"missing token", "dev-3", "presale", "", http.StatusForbidden},
+		{"rejected token", "dev-3", "presale", "bot", http.StatusForbidden},
+		{"verified", "dev-3", "presale", "pass", http.StatusOK},
+		{"bucket not offered", "dev-4", "presale2", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := join(tt.deviceID, tt.bucket, tt.captchaToken); status != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"gatekeep/internal/queue"
+	"gatekeep/internal/token"
+)
+
+// ChallengeResponse represents a proof-of-work challenge for joining a queue
+type ChallengeResponse struct {
+	Required   bool       `json:"required"`
+	Challenge  string     `json:"challenge,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// HandleGetChallenge handles GET /queue/challenge. Events without
+// proof-of-work return required=false.
+func (h *Handler) HandleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	deviceID := r.URL.Query().Get("device_id")
+	if eventID == "" || deviceID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id and device_id are required")
+		return
+	}
+	annotateLog(r, eventID, "")
+
+	difficulty, err := h.queueManager.ChallengeDifficulty(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	resp := ChallengeResponse{}
+	if difficulty > 0 {
+		if h.tokenVerifier == nil {
+			writeError(w, http.StatusServiceUnavailable, CodeInternal, "proof-of-work is not available")
+			return
+		}
+		signed, challenge, err := h.tokenVerifier.IssueChallenge(eventID, deviceID, difficulty)
+		if err != nil {
+			writeDomainError(w, err)
+			return
+		}
+		resp = ChallengeResponse{
+			Required:   true,
+			Challenge:  signed,
+			Difficulty: challenge.Difficulty,
+			ExpiresAt:  &challenge.ExpiresAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// checkChallenge verifies the proof-of-work of a join if its event requires
+// one. The returned challenge is nil when the event does not.
+func (h *Handler) checkChallenge(req JoinQueueRequest, config *queue.EventConfig) (*token.Challenge, error) {
+	if config.ChallengeDifficulty <= 0 {
+		return nil, nil
+	}
+	if h.tokenVerifier == nil {
+		return nil, fmt.Errorf("proof-of-work is required but no token verifier is configured")
+	}
+	return h.tokenVerifier.VerifyChallenge(req.Challenge, req.Solution, req.EventID, req.DeviceID)
+}
+
+// releaseChallenge frees the challenge of a failed join for a retry. The
+// join is rejected either way, so a failure is only logged; the challenge
+// then stays used until it expires.
+func (h *Handler) releaseChallenge(challenge *token.Challenge) {
+	if challenge == nil {
+		return
+	}
+	if err := h.tokenVerifier.ReleaseChallenge(challenge); err != nil {
+		slog.Warn("failed to release challenge",
+			"event_id", challenge.EventID, "device_id", challenge.DeviceID, "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gatekeep/internal/config"
+	"gatekeep/internal/queue"
+	redisclient "gatekeep/internal/redis"
+	"gatekeep/internal/token"
+)
+
+func TestHandleGetChallenge_Validation(t *testing.T) {
+	handler := &Handler{}
+
+	for _, query := range []string{"", "?event_id=evt", "?device_id=dev"} {
+		req := httptest.NewRequest("GET", "/queue/challenge"+query, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleGetChallenge(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Query %q: expected status 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestHandleJoinQueue_ProofOfWork(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+	handler.tokenVerifier = token.NewVerifier(nil, "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+
+	eventID := "test-event-pow"
+	if err := handler.queueManager.SetEventConfig(&queue.EventConfig{
+		EventID: eventID, Enabled: true, MaxSize: 100, ChallengeDifficulty: 4,
+	}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/queue/challenge?event_id="+eventID+"&device_id=dev-pow", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleGetChallenge(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var challenge ChallengeResponse
+	if err := json.NewDecoder(rr.Body).Decode(&challenge); err != nil {
+		t.Fatalf("Failed to decode challenge: %v", err)
+	}
+	if !challenge.Required || challenge.Difficulty != 4 {
+		t.Fatalf("Unexpected challenge: %+v", challenge)
+	}
+
+	solution := token.SolveChallenge(challenge.Challenge, challenge.Difficulty)
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"missing solution", fmt.Sprintf(`{"event_id":%q,"device_id":"dev-pow"}`, eventID), http.StatusForbidden},
+		{"other device", fmt.Sprintf(`{"event_id":%q,"device_id":"dev-other","challenge":%q,"solution":%q}`, eventID, challenge.Challenge, solution), http.StatusForbidden},
+		{"solved", fmt.Sprintf(`{"event_id":%q,"device_id":"dev-pow","challenge":%q,"solution":%q}`, eventID, challenge.Challenge, solution), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/queue/join", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.HandleJoinQueue(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleJoinQueue_ChallengeUsedOnlyOnJoin(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	redisClient, err := redisclient.NewClient(&config.Config{RedisAddr: "localhost:6379"})
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+	}
+	defer redisClient.Close()
+	handler.tokenVerifier = token.NewVerifier(redisClient, "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+
+	eventID := "test-event-pow-retry"
+	eventConfig := &queue.EventConfig{EventID: eventID, Enabled: false, MaxSize: 100, ChallengeDifficulty: 4}
+	if err := handler.queueManager.SetEventConfig(eventConfig); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	signed, _, err := handler.tokenVerifier.IssueChallenge(eventID, "dev-pow-retry", 4)
+	if err != nil {
+		t.Fatalf("IssueChallenge() failed: %v", err)
+	}
+	body := fmt.Sprintf(`{"event_id":%q,"device_id":"dev-pow-retry","challenge":%q,"solution":%q}`,
+		eventID, signed, token.SolveChallenge(signed, 4))
+	join := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.HandleJoinQueue(rr, httptest.NewRequest("POST", "/queue/join", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	// A join rejected after the challenge check leaves the challenge usable
+	if rr := join(); rr.Code != http.StatusServiceUnavailable || !bytes.Contains(rr.Body.Bytes(), []byte(CodeEventDisabled)) {
+		t.Fatalf("Expected event_disabled, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	eventConfig.Enabled = true
+	if err := handler.queueManager.SetEventConfig(eventConfig); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+	if rr := join(); rr.Code != http.StatusOK {
+		t.Fatalf("Expected retry with the same challenge to succeed, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// A successful join uses it up
+	if rr := join(); rr.Code != http.StatusForbidden || !bytes.Contains(rr.Body.Bytes(), []byte(CodeChallengeFailed)) {
+		t.Errorf("Expected challenge_failed, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeep/internal/attestation"
+	"gatekeep/internal/audit"
+	"gatekeep/internal/captcha"
+	"gatekeep/internal/queue"
+	"gatekeep/internal/release"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
+)
+
+// Error codes returned in ErrorResponse.Code. Clients should branch on these
+// rather than on the human-readable message.
+const (
+	CodeInvalidRequest         = "invalid_request"
+	CodeMethodNotAllowed       = "method_not_allowed"
+	CodeUnauthorized           = "unauthorized"
+	CodeNotFound               = "not_found"
+	CodeRateLimited            = "rate_limited"
+	CodeQueueFull              = "queue_full"
+	CodeEventDisabled          = "event_disabled"
+	CodeBanned                 = "banned"
+	CodeAlreadyAdmitted        = "already_admitted"
+	CodeReleasePaused          = "release_paused"
+	CodeCapacityReached        = "capacity_reached"
+	CodeTokenInvalid           = "token_invalid"
+	CodeTokenExpired           = "token_expired"
+	CodeTokenRevoked           = "token_revoked"
+	CodeRefreshLimit           = "refresh_limit_reached"
+	CodeProofInvalid           = "proof_invalid"
+	CodeChallengeFailed        = "challenge_failed"
+	CodeCaptchaFailed          = "captcha_failed"
+	CodeCaptchaUnavailable     = "captcha_unavailable"
+	CodeAttestationFailed      = "attestation_failed"
+	CodeAttestationUnavailable = "attestation_unavailable"
+	CodeInternal               = "internal_error"
+)
+
+// ErrorResponse is the JSON body returned for every API error
+type ErrorResponse struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds
+}
+
+// writeError writes a JSON error response with the given status and code
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorResponse(w, status, ErrorResponse{Code: code, Message: message})
+}
+
+// writeRateLimited writes a 429 response with a Retry-After header
+func writeRateLimited(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeErrorResponse(w, http.StatusTooManyRequests, ErrorResponse{
+		Code:       CodeRateLimited,
+		Message:    message,
+		RetryAfter: seconds,
+	})
+}
+
+// writeErrorResponse encodes body as JSON with the given status
+func writeErrorResponse(w http.ResponseWriter, status int, body ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeDomainError maps an error returned by the queue, release or token
+// packages to an HTTP status and error code. Unrecognized errors are treated
+// as internal errors; their messages are logged rather than returned.
+func writeDomainError(w http.ResponseWriter, err error) {
+	var rateLimitErr *queue.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		writeRateLimited(w, err.Error(), rateLimitErr.RetryAfter)
+		return
+	}
+
+	status, code := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		slog.Error("internal error", "error", err)
+		writeError(w, status, code, "Internal server error")
+		return
+	}
+	writeError(w, status, code, err.Error())
+}
+
+// errorStatus returns the HTTP status and error code for a domain error
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, queue.ErrNotFound), errors.Is(err, token.ErrNotFound), errors.Is(err, release.ErrNotFound),
+		errors.Is(err, webhook.ErrNotFound):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, webhook.ErrInvalidSubscription), errors.Is(err, audit.ErrInvalidFilter),
+		errors.Is(err, token.ErrInvalidRevocation):
+		return http.StatusBadRequest, CodeInvalidRequest
+	case errors.Is(err, queue.ErrRateLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	case errors.Is(err, queue.ErrQueueFull):
+		return http.StatusServiceUnavailable, CodeQueueFull
+	case errors.Is(err, queue.ErrEventDisabled):
+		return http.StatusServiceUnavailable, CodeEventDisabled
+	case errors.Is(err, queue.ErrBanned):
+		return http.StatusForbidden, CodeBanned
+	case errors.Is(err, release.ErrAlreadyAdmitted), errors.Is(err, queue.ErrAlreadyAdmitted):
+		return http.StatusConflict, CodeAlreadyAdmitted
+	case errors.Is(err, release.ErrPaused):
+		return http.StatusConflict, CodeReleasePaused
+	case errors.Is(err, release.ErrCapacityReached):
+		return http.StatusConflict, CodeCapacityReached
+	case errors.Is(err, token.ErrTokenExpired):
+		return http.StatusUnauthorized, CodeTokenExpired
+	case errors.Is(err, token.ErrTokenRevoked):
+		return http.StatusUnauthorized, CodeTokenRevoked
+	case errors.Is(err, token.ErrInvalidProof):
+		return http.StatusUnauthorized, CodeProofInvalid
+	case errors.Is(err, token.ErrInvalidChallenge):
+		return http.StatusForbidden, CodeChallengeFailed
+	case errors.Is(err, queue.ErrInvalidMetadata), errors.Is(err, queue.ErrInvalidBucket),
+		errors.Is(err, queue.ErrInvalidIdentifier):
+		return http.StatusBadRequest, CodeInvalidRequest
+	case errors.Is(err, queue.ErrAttestationFailed):
+		return http.StatusForbidden, CodeAttestationFailed
+	case errors.Is(err, attestation.ErrUnavailable):
+		return http.StatusServiceUnavailable, CodeAttestationUnavailable
+	case errors.Is(err, captcha.ErrFailed):
+		return http.StatusForbidden, CodeCaptchaFailed
+	case errors.Is(err, captcha.ErrUnavailable):
+		return http.StatusServiceUnavailable, CodeCaptchaUnavailable
+	case errors.Is(err, token.ErrMaxLifetimeReached):
+		return http.StatusForbidden, CodeRefreshLimit
+	case errors.Is(err, token.ErrMalformedToken):
+		return http.StatusBadRequest, CodeTokenInvalid
+	case errors.Is(err, token.ErrInvalidSignature), errors.Is(err, token.ErrEventMismatch),
+		errors.Is(err, token.ErrIssuerMismatch), errors.Is(err, token.ErrTokenNotYetValid):
+		return http.StatusUnauthorized, CodeTokenInvalid
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
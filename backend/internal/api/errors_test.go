@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gatekeep/internal/attestation"
+	"gatekeep/internal/audit"
+	"gatekeep/internal/captcha"
+	"gatekeep/internal/queue"
+	"gatekeep/internal/release"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
+)
+
+func TestWriteDomainError_StatusMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"queue full", fmt.Errorf("%w (max size: %d)", queue.ErrQueueFull, 2), http.StatusServiceUnavailable, CodeQueueFull},
+		{"event disabled", fmt.Errorf("%w: %s", queue.ErrEventDisabled, "evt"), http.StatusServiceUnavailable, CodeEventDisabled},
+		{"queue entry not found", fmt.Errorf("%w: %s", queue.ErrNotFound, "q1"), http.StatusNotFound, CodeNotFound},
+		{"banned", fmt.Errorf("%w: %s %s", queue.ErrBanned, "device", "d1"), http.StatusForbidden, CodeBanned},
+		{"already admitted", fmt.Errorf("%w: %s", release.ErrAlreadyAdmitted, "q1"), http.StatusConflict, CodeAlreadyAdmitted},
+		{"move admitted to front", fmt.Errorf("%w: %s", queue.ErrAlreadyAdmitted, "q1"), http.StatusConflict, CodeAlreadyAdmitted},
+		{"admit not found", fmt.Errorf("%w: %s", release.ErrNotFound, "q1"), http.StatusNotFound, CodeNotFound},
+		{"release paused", release.ErrPaused, http.StatusConflict, CodeReleasePaused},
+		{"capacity reached", release.ErrCapacityReached, http.StatusConflict, CodeCapacityReached},
+		{"token expired", token.ErrTokenExpired, http.StatusUnauthorized, CodeTokenExpired},
+		{"token revoked", token.ErrTokenRevoked, http.StatusUnauthorized, CodeTokenRevoked},
+		{"token not yet valid", token.ErrTokenNotYetValid, http.StatusUnauthorized, CodeTokenInvalid},
+		{"invalid proof", fmt.Errorf("%w: proof already used", token.ErrInvalidProof), http.StatusUnauthorized, CodeProofInvalid},
+		{"refresh limit", fmt.Errorf("%w: admitted at %s", token.ErrMaxLifetimeReached, "2026-01-01T00:00:00Z"), http.StatusForbidden, CodeRefreshLimit},
+		{"challenge failed", fmt.Errorf("%w: challenge already used", token.ErrInvalidChallenge), http.StatusForbidden, CodeChallengeFailed},
+		{"captcha failed", fmt.Errorf("%w: invalid-input-response", captcha.ErrFailed), http.StatusForbidden, CodeCaptchaFailed},
+		{"captcha unavailable", fmt.Errorf("%w: provider returned status 500", captcha.ErrUnavailable), http.StatusServiceUnavailable, CodeCaptchaUnavailable},
+		{"attestation failed", fmt.Errorf("%w: no integrity verdicts", queue.ErrAttestationFailed), http.StatusForbidden, CodeAttestationFailed},
+		{"attestation unavailable", fmt.Errorf("%w: no verifier is configured", attestation.ErrUnavailable), http.StatusServiceUnavailable, CodeAttestationUnavailable},
+		{"token malformed", fmt.Errorf("%w: expected 3 parts, got 1", token.ErrMalformedToken), http.StatusBadRequest, CodeTokenInvalid},
+		{"webhook not found", fmt.Errorf("%w: subscription %s", webhook.ErrNotFound, "s1"), http.StatusNotFound, CodeNotFound},
+		{"invalid webhook", fmt.Errorf("%w: unknown event type %q", webhook.ErrInvalidSubscription, "paused"), http.StatusBadRequest, CodeInvalidRequest},
+		{"invalid audit filter", fmt.Errorf("%w: malformed cursor", audit.ErrInvalidFilter), http.StatusBadRequest, CodeInvalidRequest},
+		{"invalid revocation", fmt.Errorf("%w: issued_after must be before issued_before", token.ErrInvalidRevocation), http.StatusBadRequest, CodeInvalidRequest},
+		{"unknown", errors.New("redis: connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			writeDomainError(rr, tt.err)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", ct)
+			}
+
+			var body ErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error body: %v", err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, body.Code)
+			}
+			// Internal errors are not echoed to clients
+			wantMessage := tt.err.Error()
+			if tt.wantStatus == http.StatusInternalServerError {
+				wantMessage = "Internal server error"
+			}
+			if body.Message != wantMessage {
+				t.Errorf("Expected message %q, got %q", wantMessage, body.Message)
+			}
+		})
+	}
+}
+
+func TestWriteDomainError_RateLimited(t *testing.T) {
+	err := &queue.RateLimitError{
+		Limit:      queue.MaxJoinsPerWindow,
+		Window:     queue.RateLimitWindow,
+		RetryAfter: 42 * time.Second,
+	}
+	if !errors.Is(err, queue.ErrRateLimited) {
+		t.Fatal("RateLimitError should match queue.ErrRateLimited")
+	}
+
+	rr := httptest.NewRecorder()
+	writeDomainError(rr, fmt.Errorf("join failed: %w", err))
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "42" {
+		t.Errorf("Expected Retry-After 42, got %q", got)
+	}
+
+	var body ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+	if body.Code != CodeRateLimited || body.RetryAfter != 42 {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+}
+
+func TestHandleJoinQueue_ErrorBody(t *testing.T) {
+	handler := &Handler{}
+
+	req := httptest.NewRequest("POST", "/queue/join", nil)
+	req.Body = http.NoBody
+	rr := httptest.NewRecorder()
+
+	handler.HandleJoinQueue(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+
+	var body ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+	if body.Code != CodeInvalidRequest {
+		t.Errorf("Expected code %s, got %s", CodeInvalidRequest, body.Code)
+	}
+}
@@ -2,18 +2,34 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"gatekeep/internal/audit"
+	"gatekeep/internal/captcha"
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/metrics"
 	"gatekeep/internal/queue"
 	"gatekeep/internal/release"
+	"gatekeep/internal/risk"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
 )
 
 // Handler holds dependencies for API handlers
 type Handler struct {
 	queueManager      *queue.Manager
 	releaseController *release.Controller
+	watcher           *queue.Watcher      // optional; enables /queue/stream
+	auditLog          *audit.Log          // optional; records admin actions
+	tokenVerifier     *token.Verifier     // optional; enables token revocation
+	captchaChecker    *captcha.Checker    // optional; enables human verification
+	webhooks          *webhook.Dispatcher // optional; enables webhooks
+	lifecycle         lifecycle.Publisher // optional; publishes revocations
 }
 
 // NewHandler creates a new API handler
@@ -24,6 +40,14 @@ func NewHandler(queueManager *queue.Manager, releaseController *release.Controll
 	}
 }
 
+// Audit actions recorded by the release and config handlers
+const (
+	AuditRelease      = "release"
+	AuditPause        = "pause"
+	AuditResume       = "resume"
+	AuditUpdateConfig = "update_config"
+)
+
 // ReleaseRequest represents a request to release users
 type ReleaseRequest struct {
 	EventID string `json:"event_id"`
@@ -39,32 +63,38 @@ type ReleaseResponse struct {
 // HandleRelease handles POST /admin/release
 func (h *Handler) HandleRelease(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req ReleaseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if req.EventID == "" {
-		http.Error(w, "event_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
 		return
 	}
 
 	if req.Count <= 0 {
-		http.Error(w, "count must be > 0", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "count must be > 0")
 		return
 	}
+	annotateLog(r, req.EventID, "")
 
 	released, err := h.releaseController.ReleaseUsers(req.EventID, req.Count)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDomainError(w, err)
 		return
 	}
 
+	h.recordAudit(r, AuditRelease, req.EventID, "", map[string]string{
+		"requested": strconv.Itoa(req.Count),
+		"released":  strconv.Itoa(released),
+	})
+
 	response := ReleaseResponse{
 		Released: released,
 		EventID:  req.EventID,
@@ -82,23 +112,28 @@ type PauseRequest struct {
 // HandlePause handles POST /admin/pause
 func (h *Handler) HandlePause(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req PauseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
+	before := h.releaseController.GetState()
+	action := AuditResume
 	if req.Paused {
 		h.releaseController.Pause()
+		action = AuditPause
 	} else {
 		h.releaseController.Resume()
 	}
 
 	state := h.releaseController.GetState()
+	h.recordAuditChanges(r, action, "", "", map[string]string{},
+		audit.Diff(map[string]bool{"paused": before.Paused}, map[string]bool{"paused": state.Paused}))
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(state)
 }
@@ -109,62 +144,214 @@ type ConfigRequest struct {
 	Enabled     *bool  `json:"enabled,omitempty"`
 	MaxSize     *int   `json:"max_size,omitempty"`
 	ReleaseRate *int   `json:"release_rate,omitempty"`
+
+	TokenTTLSeconds         *int `json:"token_ttl_seconds,omitempty"`
+	MaxTokenLifetimeSeconds *int `json:"max_token_lifetime_seconds,omitempty"`
+
+	// Entitlements replace the event's entitlements; {} clears them
+	Entitlements map[string]interface{} `json:"entitlements,omitempty"`
+	// BucketEntitlements replace the per-bucket overrides; {} clears them
+	BucketEntitlements map[string]map[string]interface{} `json:"bucket_entitlements,omitempty"`
+	// PriorityBuckets replace the buckets clients may request; [] clears them
+	PriorityBuckets []string `json:"priority_buckets,omitempty"`
+
+	ChallengeDifficulty     *int `json:"challenge_difficulty,omitempty"`
+	ChallengeTargetJoinRate *int `json:"challenge_target_join_rate,omitempty"`
+	ChallengeMaxDifficulty  *int `json:"challenge_max_difficulty,omitempty"`
+
+	CaptchaRequired *bool `json:"captcha_required,omitempty"`
+	// CaptchaBuckets replace the buckets requiring human verification; [] clears them
+	CaptchaBuckets []string `json:"captcha_buckets,omitempty"`
+
+	AttestationRequired  *bool `json:"attestation_required,omitempty"`
+	AttestationPreferred *bool `json:"attestation_preferred,omitempty"`
+
+	RiskReviewThreshold    *int `json:"risk_review_threshold,omitempty"`
+	RiskShadowBanThreshold *int `json:"risk_shadow_ban_threshold,omitempty"`
+
+	// TokenMetadataKeys replace the join metadata keys copied into tokens; [] clears them
+	TokenMetadataKeys []string `json:"token_metadata_keys,omitempty"`
 }
 
+// maxTokenLifetimeSeconds bounds the token settings of an event config
+const maxTokenLifetimeSeconds = int(token.MaxTokenLifetime / time.Second)
+
 // HandleConfig handles POST /admin/config
 func (h *Handler) HandleConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req ConfigRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if req.EventID == "" {
-		http.Error(w, "event_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
 		return
 	}
 
+	annotateLog(r, req.EventID, "")
+
 	// Get current config
 	config, err := h.queueManager.GetEventConfig(req.EventID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDomainError(w, err)
 		return
 	}
 
+	before := *config
+
 	// Update config if provided
 	if req.Enabled != nil {
 		config.Enabled = *req.Enabled
 	}
 	if req.MaxSize != nil {
 		if *req.MaxSize < 0 {
-			http.Error(w, "max_size must be >= 0", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "max_size must be >= 0")
 			return
 		}
 		config.MaxSize = *req.MaxSize
 	}
+	if req.TokenTTLSeconds != nil {
+		if *req.TokenTTLSeconds < 0 || *req.TokenTTLSeconds > maxTokenLifetimeSeconds {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("token_ttl_seconds must be between 0 and %d", maxTokenLifetimeSeconds))
+			return
+		}
+		config.TokenTTLSeconds = *req.TokenTTLSeconds
+	}
+	if req.MaxTokenLifetimeSeconds != nil {
+		if *req.MaxTokenLifetimeSeconds < 0 || *req.MaxTokenLifetimeSeconds > maxTokenLifetimeSeconds {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("max_token_lifetime_seconds must be between 0 and %d", maxTokenLifetimeSeconds))
+			return
+		}
+		config.MaxTokenLifetimeSeconds = *req.MaxTokenLifetimeSeconds
+	}
+	if config.MaxTokenLifetimeSeconds > 0 && config.MaxTokenLifetimeSeconds < config.TokenTTLSeconds {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "max_token_lifetime_seconds must be >= token_ttl_seconds")
+		return
+	}
+	if req.Entitlements != nil {
+		config.Entitlements = req.Entitlements
+	}
+	if req.BucketEntitlements != nil {
+		config.BucketEntitlements = req.BucketEntitlements
+	}
+	// Validate what tokens will carry: the event's entitlements merged with
+	// each bucket's overrides
+	if err := token.Entitlements(config.Entitlements).Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	for bucket, overrides := range config.BucketEntitlements {
+		if err := token.MergeEntitlements(config.Entitlements, overrides).Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("bucket %s: %v", bucket, err))
+			return
+		}
+	}
+	if req.PriorityBuckets != nil {
+		for _, bucket := range req.PriorityBuckets {
+			if err := queue.ValidatePriorityBucket(bucket); err != nil {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid priority_buckets: "+err.Error())
+				return
+			}
+		}
+		config.PriorityBuckets = req.PriorityBuckets
+	}
+	if req.ChallengeDifficulty != nil {
+		if *req.ChallengeDifficulty < 0 || *req.ChallengeDifficulty > token.MaxChallengeDifficulty {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("challenge_difficulty must be between 0 and %d", token.MaxChallengeDifficulty))
+			return
+		}
+		config.ChallengeDifficulty = *req.ChallengeDifficulty
+	}
+	if req.ChallengeMaxDifficulty != nil {
+		if *req.ChallengeMaxDifficulty < 0 || *req.ChallengeMaxDifficulty > token.MaxChallengeDifficulty {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("challenge_max_difficulty must be between 0 and %d", token.MaxChallengeDifficulty))
+			return
+		}
+		config.ChallengeMaxDifficulty = *req.ChallengeMaxDifficulty
+	}
+	if req.ChallengeTargetJoinRate != nil {
+		if *req.ChallengeTargetJoinRate < 0 {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "challenge_target_join_rate must be >= 0")
+			return
+		}
+		config.ChallengeTargetJoinRate = *req.ChallengeTargetJoinRate
+	}
+	if config.ChallengeMaxDifficulty > 0 && config.ChallengeMaxDifficulty < config.ChallengeDifficulty {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "challenge_max_difficulty must be >= challenge_difficulty")
+		return
+	}
+	if req.CaptchaRequired != nil {
+		config.CaptchaRequired = *req.CaptchaRequired
+	}
+	if req.CaptchaBuckets != nil {
+		config.CaptchaBuckets = req.CaptchaBuckets
+	}
+	if req.AttestationRequired != nil {
+		config.AttestationRequired = *req.AttestationRequired
+	}
+	if req.AttestationPreferred != nil {
+		config.AttestationPreferred = *req.AttestationPreferred
+	}
+	if req.RiskReviewThreshold != nil {
+		if *req.RiskReviewThreshold < 0 || *req.RiskReviewThreshold > risk.MaxScore {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("risk_review_threshold must be between 0 and %d", risk.MaxScore))
+			return
+		}
+		config.RiskReviewThreshold = *req.RiskReviewThreshold
+	}
+	if req.RiskShadowBanThreshold != nil {
+		if *req.RiskShadowBanThreshold < 0 || *req.RiskShadowBanThreshold > risk.MaxScore {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("risk_shadow_ban_threshold must be between 0 and %d", risk.MaxScore))
+			return
+		}
+		config.RiskShadowBanThreshold = *req.RiskShadowBanThreshold
+	}
+	if req.TokenMetadataKeys != nil {
+		if len(req.TokenMetadataKeys) > queue.MaxMetadataKeys {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("token_metadata_keys must have at most %d keys", queue.MaxMetadataKeys))
+			return
+		}
+		for _, key := range req.TokenMetadataKeys {
+			if err := queue.ValidateMetadataKey(key); err != nil {
+				writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid token_metadata_keys: "+err.Error())
+				return
+			}
+		}
+		config.TokenMetadataKeys = req.TokenMetadataKeys
+	}
 	if req.ReleaseRate != nil {
 		if *req.ReleaseRate < 0 {
-			http.Error(w, "release_rate must be >= 0", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "release_rate must be >= 0")
 			return
 		}
 		config.ReleaseRate = *req.ReleaseRate
 		if err := h.releaseController.SetReleaseRate(config.ReleaseRate); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDomainError(w, err)
 			return
 		}
 	}
 
 	// Save config
 	if err := h.queueManager.SetEventConfig(config); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDomainError(w, err)
 		return
 	}
 
+	h.recordAuditChanges(r, AuditUpdateConfig, config.EventID, "", map[string]string{}, audit.Diff(before, config))
+
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(config)
 }
@@ -172,7 +359,7 @@ func (h *Handler) HandleConfig(w http.ResponseWriter, r *http.Request) {
 // HandleMetrics handles GET /admin/metrics
 func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -199,31 +386,70 @@ type JoinQueueRequest struct {
 	UserID         string            `json:"user_id,omitempty"`
 	PriorityBucket string            `json:"priority_bucket,omitempty"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
+	// PublicKey optionally binds admission tokens to the device; requests
+	// presenting them must then carry a proof signed with the private key
+	PublicKey *token.JWK `json:"public_key,omitempty"`
+	// Challenge and Solution carry the proof-of-work for events requiring it
+	Challenge string `json:"challenge,omitempty"`
+	Solution  string `json:"solution,omitempty"`
+	// CaptchaToken is the human-verification widget response, for events
+	// requiring it
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	// Attestation is a device attestation (e.g. Play Integrity or App Attest)
+	// relayed as a signed JWS
+	Attestation string `json:"attestation,omitempty"`
 }
 
 // HandleJoinQueue handles POST /queue/join
 func (h *Handler) HandleJoinQueue(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req JoinQueueRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if req.EventID == "" {
-		http.Error(w, "event_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
 		return
 	}
 
 	if req.DeviceID == "" {
-		http.Error(w, "device_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "device_id is required")
+		return
+	}
+	// Ids are carried in admission tokens, which have a size limit
+	ids := []struct{ name, value string }{{"event_id", req.EventID}, {"device_id", req.DeviceID}, {"user_id", req.UserID}}
+	for _, id := range ids {
+		if id.value == "" {
+			continue
+		}
+		if err := queue.ValidateIdentifier(id.name, id.value); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+	}
+	annotateLog(r, req.EventID, "")
+
+	if err := queue.ValidateMetadata(req.Metadata); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
+	var keyThumbprint string
+	if req.PublicKey != nil {
+		thumbprint, err := req.PublicKey.Thumbprint()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid public_key: "+err.Error())
+			return
+		}
+		keyThumbprint = thumbprint
+	}
+
 	// Use device_id as user_id if user_id is not provided (queue manager will handle this)
 	userID := req.UserID
 	if userID == "" {
@@ -236,67 +462,119 @@ func (h *Handler) HandleJoinQueue(w http.ResponseWriter, r *http.Request) {
 		priorityBucket = "normal"
 	}
 
+	config, err := h.queueManager.GetEventConfig(req.EventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	// Clients may only request the buckets the event offers; the others carry
+	// entitlements or are assigned by the service
+	if !config.AllowsBucket(priorityBucket) {
+		rejectJoin(w, h.rejectionEvent(req.EventID), fmt.Errorf("%w: %s", queue.ErrInvalidBucket, priorityBucket))
+		return
+	}
+	challenge, err := h.checkChallenge(req, config)
+	if err != nil {
+		rejectJoin(w, h.rejectionEvent(req.EventID), err)
+		return
+	}
+	if err := h.checkCaptcha(req, config, priorityBucket, getClientIP(r)); err != nil {
+		h.releaseChallenge(challenge)
+		rejectJoin(w, h.rejectionEvent(req.EventID), err)
+		return
+	}
+
 	queueReq := queue.JoinQueueRequest{
 		EventID:        req.EventID,
 		DeviceID:       req.DeviceID,
 		UserID:         userID,
 		PriorityBucket: priorityBucket,
+		ClientIP:       getClientIP(r),
+		KeyThumbprint:  keyThumbprint,
+		Attestation:    req.Attestation,
+		UserAgent:      r.UserAgent(),
+		Metadata:       req.Metadata,
 	}
 
 	entry, err := h.queueManager.JoinQueue(queueReq)
 	if err != nil {
-		// Check for specific error types
-		if err.Error() == "queue is full (max size: 0)" ||
-			err.Error() == "queue for event "+req.EventID+" is disabled" {
-			http.Error(w, err.Error(), http.StatusServiceUnavailable)
-			return
-		}
-		if err.Error() == "rate limit exceeded: maximum 5 joins per 1m0s" {
-			http.Error(w, err.Error(), http.StatusTooManyRequests)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.releaseChallenge(challenge)
+		rejectJoin(w, h.rejectionEvent(req.EventID), err)
 		return
 	}
+	annotateLog(r, "", entry.QueueID)
 
 	// Convert QueueEntry to QueueStatus for response
 	status, err := h.queueManager.GetQueueStatus(entry.QueueID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDomainError(w, err)
 		return
 	}
 
+	status.Version = queue.StatusVersion(status)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(status)
 }
 
+// unknownEvent labels rejections of joins for events without a stored
+// configuration, so clients cannot create metric series with made-up ids
+const unknownEvent = "unknown"
+
+// rejectionEvent returns the event label of a rejected join: the event id
+// if the event is configured, unknownEvent otherwise
+func (h *Handler) rejectionEvent(eventID string) string {
+	configured, err := h.queueManager.EventConfigured(eventID)
+	if err != nil || !configured {
+		return unknownEvent
+	}
+	return eventID
+}
+
+// rejectJoin writes the error of a rejected join and counts the rejection by
+// its error code. Server errors are not rejections and are not counted.
+func rejectJoin(w http.ResponseWriter, eventID string, err error) {
+	if status, code := errorStatus(err); status < http.StatusInternalServerError {
+		metrics.QueueRejections.WithLabelValues(eventID, code).Inc()
+	}
+	writeDomainError(w, err)
+}
+
 // HandleGetQueueStatus handles GET /queue/status
+//
+// With wait (e.g. "30s", capped at MaxStatusWait) and since_version or
+// If-None-Match, the request blocks until the status, position bucket or
+// admission token changes. Unchanged statuses are answered with 304.
 func (h *Handler) HandleGetQueueStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	queueID := r.URL.Query().Get("queue_id")
 	if queueID == "" {
-		http.Error(w, "queue_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "queue_id is required")
 		return
 	}
+	annotateLog(r, "", queueID)
 
-	status, err := h.queueManager.GetQueueStatus(queueID)
+	wait, err := parseStatusWait(r.URL.Query().Get("wait"))
 	if err != nil {
-		if err.Error() == "queue entry not found: "+queueID {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
+	if wait > 0 {
+		// Long polls outlive the server's default write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + streamWriteTimeout))
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(status)
+	status, err := h.waitForStatusChange(r.Context(), queueID, requestedVersion(r), wait)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	writeStatus(w, r, status)
 }
 
 // HeartbeatRequest represents a request to send a heartbeat
@@ -307,42 +585,79 @@ type HeartbeatRequest struct {
 // HandleHeartbeat handles POST /queue/heartbeat
 func (h *Handler) HandleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req HeartbeatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if req.QueueID == "" {
-		http.Error(w, "queue_id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "queue_id is required")
 		return
 	}
 
+	annotateLog(r, "", req.QueueID)
+
 	status, err := h.queueManager.SendHeartbeat(req.QueueID)
 	if err != nil {
-		if err.Error() == "queue entry not found: "+req.QueueID {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDomainError(w, err)
 		return
 	}
+	metrics.QueueHeartbeats.WithLabelValues(status.EventID).Inc()
 
+	status.Version = queue.StatusVersion(status)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(status)
 }
 
-// RegisterRoutes registers all admin routes
-func (h *Handler) RegisterRoutes(r *mux.Router, adminAPIKey string) {
+// LeaveQueueRequest represents a request to leave the queue
+type LeaveQueueRequest struct {
+	QueueID string `json:"queue_id"`
+}
+
+// HandleLeaveQueue handles POST /queue/leave
+func (h *Handler) HandleLeaveQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req LeaveQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	if req.QueueID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "queue_id is required")
+		return
+	}
+
+	annotateLog(r, "", req.QueueID)
+
+	if err := h.queueManager.LeaveQueue(req.QueueID); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"left": true,
+	})
+}
+
+// RegisterRoutes registers all admin routes, authenticated with adminKeys
+// keyed by name
+func (h *Handler) RegisterRoutes(r *mux.Router, adminKeys map[string]string) {
 	adminRouter := r.PathPrefix("/admin").Subrouter()
 
 	// Apply middleware
-	adminRouter.Use(AdminAuthMiddleware(adminAPIKey))
+	adminRouter.Use(AdminAuthMiddleware(adminKeys))
 	adminRouter.Use(RequestLoggingMiddleware())
 	adminRouter.Use(RateLimitMiddleware())
 
@@ -350,6 +665,24 @@ func (h *Handler) RegisterRoutes(r *mux.Router, adminAPIKey string) {
 	adminRouter.HandleFunc("/release", h.HandleRelease).Methods("POST")
 	adminRouter.HandleFunc("/pause", h.HandlePause).Methods("POST")
 	adminRouter.HandleFunc("/config", h.HandleConfig).Methods("POST")
+	adminRouter.HandleFunc("/config", h.HandleGetConfig).Methods("GET")
+	adminRouter.HandleFunc("/events", h.HandleListEvents).Methods("GET")
+	adminRouter.HandleFunc("/entries", h.HandleListEntries).Methods("GET")
+	adminRouter.HandleFunc("/lookup", h.HandleLookup).Methods("GET")
+	adminRouter.HandleFunc("/entries/remove", h.HandleRemoveEntry).Methods("POST")
+	adminRouter.HandleFunc("/entries/front", h.HandleMoveToFront).Methods("POST")
+	adminRouter.HandleFunc("/entries/admit", h.HandleAdmitEntry).Methods("POST")
+	adminRouter.HandleFunc("/ban", h.HandleBan).Methods("POST")
+	adminRouter.HandleFunc("/unban", h.HandleUnban).Methods("POST")
+	adminRouter.HandleFunc("/bans", h.HandleListBans).Methods("GET")
+	adminRouter.HandleFunc("/audit", h.HandleAudit).Methods("GET")
+	adminRouter.HandleFunc("/tokens/revoke", h.HandleRevokeTokens).Methods("POST")
+	adminRouter.HandleFunc("/revocations", h.HandleRevocationList).Methods("GET")
+	adminRouter.HandleFunc("/webhooks", h.HandleListWebhooks).Methods("GET")
+	adminRouter.HandleFunc("/webhooks", h.HandleCreateWebhook).Methods("POST")
+	adminRouter.HandleFunc("/webhooks/remove", h.HandleRemoveWebhook).Methods("POST")
+	adminRouter.HandleFunc("/webhooks/deliveries", h.HandleListDeadLetters).Methods("GET")
+	adminRouter.HandleFunc("/webhooks/replay", h.HandleReplayWebhook).Methods("POST")
 	adminRouter.HandleFunc("/metrics", h.HandleMetrics).Methods("GET")
 }
 
@@ -362,7 +695,10 @@ func (h *Handler) RegisterQueueRoutes(r *mux.Router) {
 	queueRouter.Use(RateLimitMiddleware())
 
 	// Register queue endpoints
+	queueRouter.HandleFunc("/challenge", h.HandleGetChallenge).Methods("GET")
 	queueRouter.HandleFunc("/join", h.HandleJoinQueue).Methods("POST")
 	queueRouter.HandleFunc("/status", h.HandleGetQueueStatus).Methods("GET")
 	queueRouter.HandleFunc("/heartbeat", h.HandleHeartbeat).Methods("POST")
+	queueRouter.HandleFunc("/leave", h.HandleLeaveQueue).Methods("POST")
+	queueRouter.HandleFunc("/stream", h.HandleQueueStream).Methods("GET")
 }
@@ -3,11 +3,17 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"gatekeep/internal/config"
+	"gatekeep/internal/metrics"
 	"gatekeep/internal/queue"
 	redisclient "gatekeep/internal/redis"
 	"gatekeep/internal/release"
@@ -219,3 +225,243 @@ func TestHandleMetrics(t *testing.T) {
 		t.Error("Metrics should contain release_state")
 	}
 }
+
+func TestHandleJoinQueue_InvalidPublicKey(t *testing.T) {
+	handler := &Handler{}
+
+	body := `{"event_id":"evt","device_id":"dev","public_key":{"kty":"EC","crv":"P-256","x":"AA","y":"AA"}}`
+	req := httptest.NewRequest("POST", "/queue/join", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler.HandleJoinQueue(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHandleJoinQueue_InvalidMetadata(t *testing.T) {
+	handler := &Handler{}
+
+	body := `{"event_id":"evt","device_id":"dev","metadata":{"campaign source":"newsletter"}}`
+	req := httptest.NewRequest("POST", "/queue/join", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	handler.HandleJoinQueue(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHandleLeaveQueue_InvalidRequest(t *testing.T) {
+	handler := &Handler{}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid body", `{`},
+		{"missing queue_id", `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/queue/leave", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.HandleLeaveQueue(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleConfig_Entitlements(t *testing.T) {
+	handler, apiKey, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"event_id":"test-event","entitlements":{"max_tickets":4},"bucket_entitlements":{"vip":{"max_tickets":8}}}`, http.StatusOK},
+		{"too large", `{"event_id":"test-event","entitlements":{"sections":"` + strings.Repeat("A", token.MaxEntitlementsSize) + `"}}`, http.StatusBadRequest},
+		{"bucket too large", `{"event_id":"test-event","bucket_entitlements":{"vip":{"sections":"` + strings.Repeat("A", token.MaxEntitlementsSize) + `"}}}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/config", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-API-Key", apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.HandleConfig(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Rejected updates leave the stored entitlements alone
+	config, err := handler.queueManager.GetEventConfig("test-event")
+	if err != nil {
+		t.Fatalf("GetEventConfig() failed: %v", err)
+	}
+	if config.Entitlements["max_tickets"] != float64(4) {
+		t.Errorf("Expected max_tickets 4, got %v", config.Entitlements["max_tickets"])
+	}
+	if config.BucketEntitlements["vip"]["max_tickets"] != float64(8) {
+		t.Errorf("Expected vip max_tickets 8, got %v", config.BucketEntitlements["vip"])
+	}
+}
+
+func TestHandleConfig_RiskThresholds(t *testing.T) {
+	handler, apiKey, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"event_id":"test-event","risk_review_threshold":50,"risk_shadow_ban_threshold":90}`, http.StatusOK},
+		{"negative review", `{"event_id":"test-event","risk_review_threshold":-1}`, http.StatusBadRequest},
+		{"shadow ban above max", `{"event_id":"test-event","risk_shadow_ban_threshold":101}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/config", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-API-Key", apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.HandleConfig(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	config, err := handler.queueManager.GetEventConfig("test-event")
+	if err != nil {
+		t.Fatalf("GetEventConfig() failed: %v", err)
+	}
+	if config.RiskReviewThreshold != 50 || config.RiskShadowBanThreshold != 90 {
+		t.Errorf("Expected thresholds 50/90, got %d/%d", config.RiskReviewThreshold, config.RiskShadowBanThreshold)
+	}
+}
+
+func TestHandleConfig_TokenMetadataKeys(t *testing.T) {
+	handler, apiKey, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"event_id":"test-event","token_metadata_keys":["source","app.version"]}`, http.StatusOK},
+		{"invalid key", `{"event_id":"test-event","token_metadata_keys":["app version"]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/config", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-API-Key", apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.HandleConfig(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	config, err := handler.queueManager.GetEventConfig("test-event")
+	if err != nil {
+		t.Fatalf("GetEventConfig() failed: %v", err)
+	}
+	if len(config.TokenMetadataKeys) != 2 || config.TokenMetadataKeys[1] != "app.version" {
+		t.Errorf("Expected token metadata keys [source app.version], got %v", config.TokenMetadataKeys)
+	}
+}
+
+func TestRejectJoin_CountsClientErrors(t *testing.T) {
+	banned := metrics.QueueRejections.WithLabelValues("rejecttest", CodeBanned)
+	before := testutil.ToFloat64(banned)
+
+	rejectJoin(httptest.NewRecorder(), "rejecttest", fmt.Errorf("%w: user-1", queue.ErrBanned))
+	if got := testutil.ToFloat64(banned) - before; got != 1 {
+		t.Errorf("Expected 1 banned rejection, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	rejectJoin(w, "rejecttest", errors.New("redis: connection refused"))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+	if metrics.QueueRejections.DeleteLabelValues("rejecttest", CodeInternal) {
+		t.Error("Expected server errors not to count as rejections")
+	}
+}
+
+func TestRejectionEvent(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	if err := handler.queueManager.SetEventConfig(&queue.EventConfig{EventID: "rejection-configured", Enabled: true, MaxSize: 10}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	if got := handler.rejectionEvent("rejection-configured"); got != "rejection-configured" {
+		t.Errorf("Expected configured event label, got %q", got)
+	}
+	if got := handler.rejectionEvent("rejection-made-up"); got != unknownEvent {
+		t.Errorf("Expected %q for an unconfigured event, got %q", unknownEvent, got)
+	}
+}
+
+func TestHandleHeartbeat_CountsHeartbeats(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	entry, err := handler.queueManager.JoinQueue(queue.JoinQueueRequest{EventID: "heartbeattest", DeviceID: "device-heartbeat"})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+	heartbeats := metrics.QueueHeartbeats.WithLabelValues("heartbeattest")
+	before := testutil.ToFloat64(heartbeats)
+
+	req := httptest.NewRequest("POST", "/queue/heartbeat", bytes.NewBufferString(fmt.Sprintf(`{"queue_id":%q}`, entry.QueueID)))
+	rr := httptest.NewRecorder()
+	handler.HandleHeartbeat(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := testutil.ToFloat64(heartbeats) - before; got != 1 {
+		t.Errorf("Expected 1 heartbeat, got %v", got)
+	}
+}
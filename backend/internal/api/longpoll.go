@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gatekeep/internal/queue"
+)
+
+const (
+	// MaxStatusWait caps the wait parameter of GET /queue/status
+	MaxStatusWait = 30 * time.Second
+	// statusPollInterval is used to detect changes when no watcher is available
+	statusPollInterval = 1 * time.Second
+)
+
+// parseStatusWait parses the wait parameter. It accepts Go durations ("30s")
+// and plain seconds ("30"), and caps the result at MaxStatusWait.
+func parseStatusWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait: %s", value)
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("wait must be >= 0")
+	}
+	if wait > MaxStatusWait {
+		wait = MaxStatusWait
+	}
+	return wait, nil
+}
+
+// requestedVersion returns the version the client already has, taken from
+// since_version or If-None-Match
+func requestedVersion(r *http.Request) string {
+	if version := r.URL.Query().Get("since_version"); version != "" {
+		return version
+	}
+	etag := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	etag = strings.TrimPrefix(etag, "W/")
+	return strings.Trim(etag, `"`)
+}
+
+// waitForStatusChange blocks until the entry's version differs from
+// sinceVersion or wait elapses, and returns the latest status
+func (h *Handler) waitForStatusChange(ctx context.Context, queueID, sinceVersion string, wait time.Duration) (*queue.QueueStatus, error) {
+	status, err := h.queueManager.GetQueueStatus(queueID)
+	if err != nil {
+		return nil, err
+	}
+	status.Version = queue.StatusVersion(status)
+	if wait <= 0 || sinceVersion == "" || status.Version != sinceVersion || isTerminalStatus(status) {
+		return status, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	// Prefer pushed updates; fall back to polling without a watcher
+	var updates <-chan *queue.QueueStatus
+	if h.watcher != nil {
+		sub, err := h.watcher.Watch(queueID)
+		if err == nil {
+			defer sub.Close()
+			updates = sub.Updates()
+		}
+	}
+	var pollC <-chan time.Time
+	if updates == nil {
+		poll := time.NewTicker(statusPollInterval)
+		defer poll.Stop()
+		pollC = poll.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status, nil
+		case next, ok := <-updates:
+			if !ok {
+				return status, nil
+			}
+			next.Version = queue.StatusVersion(next)
+			status = next
+		case <-pollC:
+			next, err := h.queueManager.GetQueueStatus(queueID)
+			if err != nil {
+				return nil, err
+			}
+			next.Version = queue.StatusVersion(next)
+			status = next
+		}
+		if status.Version != sinceVersion {
+			return status, nil
+		}
+	}
+}
+
+// writeStatus writes a queue status with its version as ETag. It replies
+// 304 Not Modified when the client already has this version.
+func writeStatus(w http.ResponseWriter, r *http.Request, status *queue.QueueStatus) {
+	if status.Version == "" {
+		status.Version = queue.StatusVersion(status)
+	}
+
+	w.Header().Set("ETag", `"`+status.Version+`"`)
+	w.Header().Set("Cache-Control", "no-cache")
+	if requestedVersion(r) == status.Version {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gatekeep/internal/queue"
+)
+
+func TestParseStatusWait(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"10s", 10 * time.Second, false},
+		{"15", 15 * time.Second, false},
+		{"5m", MaxStatusWait, false},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseStatusWait(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStatusWait(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseStatusWait(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStatus_ETag(t *testing.T) {
+	status := &queue.QueueStatus{QueueID: "q1", Position: 42, Status: "waiting"}
+	version := queue.StatusVersion(status)
+
+	req := httptest.NewRequest("GET", "/queue/status?queue_id=q1", nil)
+	rr := httptest.NewRecorder()
+	writeStatus(rr, req, status)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if etag := rr.Header().Get("ETag"); etag != `"`+version+`"` {
+		t.Errorf("Unexpected ETag: %s", etag)
+	}
+
+	var body queue.QueueStatus
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if body.Version != version {
+		t.Errorf("Expected version %s in body, got %s", version, body.Version)
+	}
+
+	req = httptest.NewRequest("GET", "/queue/status?queue_id=q1", nil)
+	req.Header.Set("If-None-Match", `W/"`+version+`"`)
+	rr = httptest.NewRecorder()
+	writeStatus(rr, req, &queue.QueueStatus{QueueID: "q1", Position: 42, Status: "waiting"})
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Error("304 response should have no body")
+	}
+}
+
+func TestHandleGetQueueStatus_LongPoll(t *testing.T) {
+	handler, _, cleanup := setupTestHandler(t)
+	if handler == nil {
+		return
+	}
+	defer cleanup()
+
+	body, _ := json.Marshal(JoinQueueRequest{EventID: "test-event-longpoll", DeviceID: "device-longpoll"})
+	req := httptest.NewRequest("POST", "/queue/join", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.HandleJoinQueue(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Join failed: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var joined queue.QueueStatus
+	if err := json.NewDecoder(rr.Body).Decode(&joined); err != nil {
+		t.Fatalf("Failed to decode join response: %v", err)
+	}
+
+	// Nothing changes, so the request waits and then reports not modified
+	start := time.Now()
+	req = httptest.NewRequest("GET", "/queue/status?queue_id="+joined.QueueID+"&wait=1s&since_version="+joined.Version, nil)
+	rr = httptest.NewRecorder()
+	handler.HandleGetQueueStatus(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("Long poll returned too early: %v", elapsed)
+	}
+
+	// A stale version returns immediately
+	req = httptest.NewRequest("GET", "/queue/status?queue_id="+joined.QueueID+"&wait=10s&since_version=stale", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleGetQueueStatus(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
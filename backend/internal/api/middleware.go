@@ -1,31 +1,59 @@
 package api
 
 import (
-	"log"
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+
+	"gatekeep/internal/logging"
+	"gatekeep/internal/metrics"
 )
 
-// AdminAuthMiddleware validates admin API key
-func AdminAuthMiddleware(adminAPIKey string) mux.MiddlewareFunc {
+// adminActorContextKey stores the name of the admin key a request used
+type adminActorContextKey struct{}
+
+// AdminAuthMiddleware validates the admin API key against adminKeys, keyed
+// by name, and records the key's name as the request's audit actor
+func AdminAuthMiddleware(adminKeys map[string]string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get API key from header
-			apiKey := r.Header.Get("X-API-Key")
-			if apiKey == "" {
-				// Try Authorization header as fallback
-				authHeader := r.Header.Get("Authorization")
-				if strings.HasPrefix(authHeader, "Bearer ") {
-					apiKey = strings.TrimPrefix(authHeader, "Bearer ")
+			apiKey := requestAPIKey(r)
+			actor := ""
+			// Compare against every key so timing does not reveal which matched
+			for name, key := range adminKeys {
+				if apiKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+					actor = name
 				}
 			}
+			if actor == "" {
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), adminActorContextKey{}, actor)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			if apiKey == "" || apiKey != adminAPIKey {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+// APIKeyMiddleware rejects requests that do not present key in the
+// X-API-Key header or as a bearer token
+func APIKeyMiddleware(key string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := requestAPIKey(r)
+			if apiKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) != 1 {
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 				return
 			}
 
@@ -34,7 +62,67 @@ func AdminAuthMiddleware(adminAPIKey string) mux.MiddlewareFunc {
 	}
 }
 
-// RequestLoggingMiddleware logs HTTP requests
+// requestAPIKey returns the API key of the X-API-Key header, falling back to
+// a bearer token
+func requestAPIKey(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	return ""
+}
+
+// RequestIDHeader carries the request id. A well-formed id sent by the client
+// or a proxy is kept, otherwise one is generated; it is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern matches the client-supplied request ids that are kept
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestIDContextKey stores the request id in the request context
+type requestIDContextKey struct{}
+
+// RequestIDMiddleware assigns every request an id and annotates its logs
+// with it
+func RequestIDMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !requestIDPattern.MatchString(id) {
+				id = uuid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := logging.NewContext(context.WithValue(r.Context(), requestIDContextKey{}, id))
+			logging.Annotate(ctx, slog.String("request_id", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// getRequestID returns the id assigned by RequestIDMiddleware
+func getRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// annotateLog adds an event and queue entry to the request's log records.
+// Empty values are skipped.
+func annotateLog(r *http.Request, eventID, queueID string) {
+	var attrs []slog.Attr
+	if eventID != "" {
+		attrs = append(attrs, slog.String("event_id", eventID))
+	}
+	if queueID != "" {
+		attrs = append(attrs, slog.String("queue_id", queueID))
+	}
+	logging.Annotate(r.Context(), attrs...)
+}
+
+// RequestLoggingMiddleware logs HTTP requests. Server errors are logged at
+// error level, client errors at warn and everything else at info.
 func RequestLoggingMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -45,12 +133,52 @@ func RequestLoggingMiddleware() mux.MiddlewareFunc {
 
 			next.ServeHTTP(wrapped, r)
 
-			duration := time.Since(start)
-			log.Printf("%s %s %d %v", r.Method, r.URL.Path, wrapped.statusCode, duration)
+			level := slog.LevelInfo
+			switch {
+			case wrapped.statusCode >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case wrapped.statusCode >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			slog.Log(r.Context(), level, "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", wrapped.statusCode,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"client_ip", getClientIP(r),
+			)
 		})
 	}
 }
 
+// MetricsMiddleware observes the duration of every request. Requests are
+// labeled by the matched route's template rather than the raw path, so
+// arbitrary paths cannot multiply series.
+func MetricsMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			metrics.APIRequestDuration.
+				WithLabelValues(r.Method, routeTemplate(r), strconv.Itoa(wrapped.statusCode)).
+				Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route that matched r
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -62,6 +190,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can flush
+// and hijack streaming responses
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RateLimitMiddleware provides basic rate limiting (simplified)
 func RateLimitMiddleware() mux.MiddlewareFunc {
 	// Simple in-memory rate limiter (in production, use Redis)
@@ -88,8 +222,9 @@ func RateLimitMiddleware() mux.MiddlewareFunc {
 
 			// Check rate limit (max 60 requests per minute)
 			if len(validRequests) >= 60 {
+				retryAfter := validRequests[0].Sub(cutoff)
 				mu.Unlock()
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				writeRateLimited(w, "Rate limit exceeded", retryAfter)
 				return
 			}
 
@@ -102,25 +237,230 @@ func RateLimitMiddleware() mux.MiddlewareFunc {
 	}
 }
 
-// getClientIP extracts client IP from request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		ips := strings.Split(forwarded, ",")
-		return strings.TrimSpace(ips[0])
+// clientIPContextKey is the context key under which the resolved client IP is stored
+type clientIPContextKey struct{}
+
+// requestSchemeContextKey is the context key under which the resolved request
+// scheme is stored
+type requestSchemeContextKey struct{}
+
+// ClientIPResolver determines the originating client IP of a request, honoring
+// forwarding headers only when the immediate peer is a trusted proxy
+type ClientIPResolver struct {
+	trustedProxies    []*net.IPNet
+	cloudflareProxies []*net.IPNet
+}
+
+// NewClientIPResolver creates a resolver that trusts the given proxy networks.
+// Cloudflare edge networks are trusted too, and are the only hops whose
+// CF-Connecting-IP header is honored.
+func NewClientIPResolver(trustedProxies, cloudflareProxies []*net.IPNet) *ClientIPResolver {
+	return &ClientIPResolver{trustedProxies: trustedProxies, cloudflareProxies: cloudflareProxies}
+}
+
+// ClientIP returns the client IP for the request.
+//
+// The TCP peer is used unless it is a trusted proxy. For a trusted peer the
+// headers are consulted in order: CF-Connecting-IP (only when the request came
+// through Cloudflare), RFC 7239 Forwarded, X-Forwarded-For and X-Real-IP.
+// Forwarded chains are walked right to left and the first hop that is not a
+// trusted proxy is returned, so entries prepended by the client cannot be
+// used to spoof the address.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	peer := remoteIP(r.RemoteAddr)
+	if !c.isTrusted(peer) {
+		return peer
 	}
 
-	// Check X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
+	if c.viaCloudflare(peer, r) {
+		if cfIP := parseIP(r.Header.Get("CF-Connecting-IP")); cfIP != "" {
+			return cfIP
+		}
+	}
+
+	if hops := forwardedFor(r.Header.Values("Forwarded")); len(hops) > 0 {
+		if ip := c.walkChain(hops); ip != "" {
+			return ip
+		}
+	}
+
+	if hops := xForwardedFor(r.Header.Values("X-Forwarded-For")); len(hops) > 0 {
+		if ip := c.walkChain(hops); ip != "" {
+			return ip
+		}
+	}
+
+	if realIP := parseIP(r.Header.Get("X-Real-IP")); realIP != "" {
 		return realIP
 	}
 
-	// Fallback to RemoteAddr
-	ip := r.RemoteAddr
-	if idx := strings.LastIndex(ip, ":"); idx != -1 {
-		ip = ip[:idx]
+	return peer
+}
+
+// Scheme returns the scheme the client used: https if the connection is TLS,
+// or if a trusted proxy terminated TLS and says so in X-Forwarded-Proto.
+// The header is ignored from any other peer.
+func (c *ClientIPResolver) Scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if !c.isTrusted(remoteIP(r.RemoteAddr)) {
+		return "http"
+	}
+	// The nearest proxy appends its value last
+	values := strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")
+	if strings.EqualFold(strings.TrimSpace(values[len(values)-1]), "https") {
+		return "https"
+	}
+	return "http"
+}
+
+// viaCloudflare reports whether the hop that handed the request to the
+// trusted proxies is a Cloudflare edge: the peer itself, or the first hop
+// of X-Forwarded-For, right to left, that is not an internal proxy
+func (c *ClientIPResolver) viaCloudflare(peer string, r *http.Request) bool {
+	if len(c.cloudflareProxies) == 0 {
+		return false
+	}
+	if inNetworks(peer, c.cloudflareProxies) {
+		return true
+	}
+	hops := xForwardedFor(r.Header.Values("X-Forwarded-For"))
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if inNetworks(ip, c.cloudflareProxies) {
+			return true
+		}
+		if !inNetworks(ip, c.trustedProxies) {
+			return false
+		}
+	}
+	return false
+}
+
+// walkChain returns the right-most hop that is not a trusted proxy. If every
+// hop is trusted the left-most one is returned. An unparseable hop ends the
+// walk without a result, since neither it nor anything to its left can be
+// trusted and every hop to its right is a proxy.
+func (c *ClientIPResolver) walkChain(hops []string) string {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if ip == "" {
+			return ""
+		}
+		if !c.isTrusted(ip) || i == 0 {
+			return ip
+		}
+	}
+	return ""
+}
+
+// isTrusted reports whether ip belongs to a trusted proxy or Cloudflare
+// network
+func (c *ClientIPResolver) isTrusted(ip string) bool {
+	return inNetworks(ip, c.trustedProxies) || inNetworks(ip, c.cloudflareProxies)
+}
+
+// inNetworks reports whether ip belongs to one of the networks
+func inNetworks(ip string, networks []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPMiddleware resolves the client IP and scheme once per request and
+// stores them in the request context for downstream middleware and handlers
+func ClientIPMiddleware(resolver *ClientIPResolver) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPContextKey{}, resolver.ClientIP(r))
+			ctx = context.WithValue(ctx, requestSchemeContextKey{}, resolver.Scheme(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// getClientIP extracts client IP from request. It prefers the address resolved
+// by ClientIPMiddleware and otherwise falls back to the TCP peer; forwarding
+// headers are never trusted here.
+func getClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	return remoteIP(r.RemoteAddr)
+}
+
+// getRequestScheme returns the scheme resolved by ClientIPMiddleware, or
+// without it whether the connection itself is TLS
+func getRequestScheme(r *http.Request) string {
+	if scheme, ok := r.Context().Value(requestSchemeContextKey{}).(string); ok && scheme != "" {
+		return scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// remoteIP strips the port from a RemoteAddr value
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// parseIP normalizes a header value to an IP string, accepting optional
+// ports, IPv6 brackets and surrounding quotes. It returns "" if the value is
+// not an IP address (e.g. "unknown" or an obfuscated Forwarded identifier).
+func parseIP(value string) string {
+	value = strings.Trim(strings.TrimSpace(value), "\"")
+	if value == "" {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// xForwardedFor flattens X-Forwarded-For header values into a list of hops
+func xForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedFor extracts the "for" parameters from RFC 7239 Forwarded header
+// values, preserving hop order
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(name, "for") {
+					hops = append(hops, val)
+				}
+			}
+		}
 	}
-	return ip
+	return hops
 }
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gorilla/mux"
+
+	"gatekeep/internal/config"
+	"gatekeep/internal/logging"
+	"gatekeep/internal/metrics"
 )
 
 func TestAdminAuthMiddleware_ValidKey(t *testing.T) {
 	adminAPIKey := "test-api-key"
 	router := mux.NewRouter()
-	router.Use(AdminAuthMiddleware(adminAPIKey))
+	router.Use(AdminAuthMiddleware(map[string]string{"admin": adminAPIKey}))
 	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods("GET")
@@ -30,7 +36,7 @@ func TestAdminAuthMiddleware_ValidKey(t *testing.T) {
 func TestAdminAuthMiddleware_InvalidKey(t *testing.T) {
 	adminAPIKey := "test-api-key"
 	router := mux.NewRouter()
-	router.Use(AdminAuthMiddleware(adminAPIKey))
+	router.Use(AdminAuthMiddleware(map[string]string{"admin": adminAPIKey}))
 	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods("GET")
@@ -49,7 +55,7 @@ func TestAdminAuthMiddleware_InvalidKey(t *testing.T) {
 func TestAdminAuthMiddleware_MissingKey(t *testing.T) {
 	adminAPIKey := "test-api-key"
 	router := mux.NewRouter()
-	router.Use(AdminAuthMiddleware(adminAPIKey))
+	router.Use(AdminAuthMiddleware(map[string]string{"admin": adminAPIKey}))
 	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods("GET")
@@ -67,7 +73,7 @@ func TestAdminAuthMiddleware_MissingKey(t *testing.T) {
 func TestAdminAuthMiddleware_BearerToken(t *testing.T) {
 	adminAPIKey := "test-api-key"
 	router := mux.NewRouter()
-	router.Use(AdminAuthMiddleware(adminAPIKey))
+	router.Use(AdminAuthMiddleware(map[string]string{"admin": adminAPIKey}))
 	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods("GET")
@@ -110,3 +116,211 @@ func TestRateLimitMiddleware(t *testing.T) {
 		t.Errorf("Expected status 429, got %d", rr.Code)
 	}
 }
+
+func newTestResolver(t *testing.T, trusted, cloudflare string) *ClientIPResolver {
+	networks, err := config.ParseTrustedProxies(trusted)
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() failed: %v", err)
+	}
+	cloudflareNetworks, err := config.ParseTrustedProxies(cloudflare)
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() failed: %v", err)
+	}
+	return NewClientIPResolver(networks, cloudflareNetworks)
+}
+
+func TestClientIPResolver_ClientIP(t *testing.T) {
+	resolver := newTestResolver(t, "10.0.0.0/8", "173.245.48.0/20")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4", "CF-Connecting-IP": "1.2.3.4"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted peer without headers",
+			remoteAddr: "10.0.0.5:5000",
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "xff right-to-left stops at first untrusted hop",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.9, 10.0.0.2"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "xff all trusted returns left-most",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.9, 10.0.0.2"},
+			want:       "10.0.0.9",
+		},
+		{
+			name:       "cf-connecting-ip preferred from trusted peer",
+			remoteAddr: "173.245.48.1:443",
+			headers:    map[string]string{"CF-Connecting-IP": "198.51.100.20", "X-Forwarded-For": "198.51.100.21"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "cf-connecting-ip through load balancer behind cloudflare",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"CF-Connecting-IP": "198.51.100.20", "X-Forwarded-For": "198.51.100.20, 173.245.48.1"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "cf-connecting-ip ignored when not through cloudflare",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"CF-Connecting-IP": "1.2.3.4", "X-Forwarded-For": "198.51.100.22"},
+			want:       "198.51.100.22",
+		},
+		{
+			name:       "xff unparseable hop after proxies falls back",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, garbage, 10.0.0.2", "X-Real-IP": "198.51.100.32"},
+			want:       "198.51.100.32",
+		},
+		{
+			name:       "xff unparseable hop left of client is ignored",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Forwarded-For": "garbage, 198.51.100.33, 10.0.0.2"},
+			want:       "198.51.100.33",
+		},
+		{
+			name:       "forwarded header",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711", for=10.0.0.3`},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "forwarded obfuscated identifier falls back",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"Forwarded": "for=_hidden", "X-Real-IP": "198.51.100.30"},
+			want:       "198.51.100.30",
+		},
+		{
+			name:       "x-real-ip from trusted peer",
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.31"},
+			want:       "198.51.100.31",
+		},
+		{
+			name:       "ipv6 peer",
+			remoteAddr: "[2001:db8::2]:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "2001:db8::2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_SpoofedForwardedFor(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(ClientIPMiddleware(newTestResolver(t, "", "")))
+	router.Use(RateLimitMiddleware())
+	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	// Rotating X-Forwarded-For must not reset the limit for an untrusted peer
+	for i := 0; i < 60; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-For", "198.51.100.250")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var gotID string
+	var gotAttrs []slog.Attr
+	router := mux.NewRouter()
+	router.Use(RequestIDMiddleware())
+	router.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		annotateLog(r, "evt", "")
+		gotID = getRequestID(r)
+		gotAttrs = logging.Attrs(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{"generated", "", false},
+		{"client supplied", "req-123.abc", true},
+		{"malformed", "bad id\nwith newline", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			if gotID == "" || rr.Header().Get(RequestIDHeader) != gotID {
+				t.Fatalf("Expected the request id %q echoed, got %q", gotID, rr.Header().Get(RequestIDHeader))
+			}
+			if (gotID == tt.header) != tt.wantKept {
+				t.Errorf("Expected kept=%v for %q, got id %q", tt.wantKept, tt.header, gotID)
+			}
+			if len(gotAttrs) != 2 || gotAttrs[0].Value.String() != gotID || gotAttrs[1].Key != "event_id" {
+				t.Errorf("Expected request_id and event_id annotations, got %v", gotAttrs)
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware_RouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware())
+	router.HandleFunc("/events/{event_id}/metricstest", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for _, eventID := range []string{"a", "b"} {
+		req := httptest.NewRequest(http.MethodGet, "/events/"+eventID+"/metricstest", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Both requests share the template's series; deleting it reports
+	// whether it was observed
+	if !metrics.APIRequestDuration.DeleteLabelValues(http.MethodGet, "/events/{event_id}/metricstest", "204") {
+		t.Error("Expected the request to be observed by route template")
+	}
+	if metrics.APIRequestDuration.DeleteLabelValues(http.MethodGet, "/events/a/metricstest", "204") {
+		t.Error("Expected no series for the raw path")
+	}
+}
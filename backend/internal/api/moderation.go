@@ -0,0 +1,385 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gatekeep/internal/audit"
+	"gatekeep/internal/queue"
+	"gatekeep/internal/token"
+)
+
+// Audit actions recorded by the moderation handlers
+const (
+	AuditRemoveEntry = "remove_entry"
+	AuditMoveToFront = "move_to_front"
+	AuditAdmitEntry  = "admit_entry"
+	AuditBan         = "ban"
+	AuditUnban       = "unban"
+)
+
+// EntryActionRequest represents an admin action on a single queue entry
+type EntryActionRequest struct {
+	QueueID string `json:"queue_id"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// AdmitResponse represents the result of a manual admission
+type AdmitResponse struct {
+	QueueID        string                `json:"queue_id"`
+	AdmissionToken *queue.AdmissionToken `json:"admission_token"`
+}
+
+// BanRequest represents a request to ban or unban a device, user or IP
+type BanRequest struct {
+	EventID string `json:"event_id"`
+	Kind    string `json:"kind"` // "device", "user" or "ip"
+	Value   string `json:"value"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// BansResponse represents an event's bans
+type BansResponse struct {
+	EventID string                     `json:"event_id"`
+	Bans    map[queue.BanKind][]string `json:"bans"`
+}
+
+// AuditResponse represents a page of audit log entries, newest first.
+// NextCursor, when set, is passed as "before" to fetch older entries.
+type AuditResponse struct {
+	Entries    []*audit.Entry `json:"entries"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// AdminActorHeader lets admin clients name the person behind a shared key.
+// It is not authenticated, so it is only recorded as the claimed_actor detail.
+const AdminActorHeader = "X-Admin-Actor"
+
+// adminActor identifies who performed an admin request: the name of the
+// admin key it was authenticated with
+func adminActor(r *http.Request) string {
+	if actor, _ := r.Context().Value(adminActorContextKey{}).(string); actor != "" {
+		return actor
+	}
+	return "admin"
+}
+
+// recordAudit records an admin action. Failures are logged but do not fail
+// the request, since the action has already been applied.
+func (h *Handler) recordAudit(r *http.Request, action, eventID, target string, details map[string]string) {
+	h.recordAuditChanges(r, action, eventID, target, details, nil)
+}
+
+// recordAuditChanges records an admin action along with the changes it made,
+// as computed by audit.Diff
+func (h *Handler) recordAuditChanges(r *http.Request, action, eventID, target string, details map[string]string, changes []audit.Change) {
+	if h.auditLog == nil {
+		return
+	}
+	if claimed := r.Header.Get(AdminActorHeader); claimed != "" {
+		if details == nil {
+			details = make(map[string]string)
+		}
+		details["claimed_actor"] = claimed
+	}
+	for key, value := range details {
+		if value == "" {
+			delete(details, key)
+		}
+	}
+	entry := audit.Entry{
+		Actor:     adminActor(r),
+		ClientIP:  getClientIP(r),
+		RequestID: getRequestID(r),
+		UserAgent: r.UserAgent(),
+		Action:    action,
+		EventID:   eventID,
+		Target:    target,
+		Details:   details,
+		Changes:   changes,
+	}
+	if err := h.auditLog.Record(entry); err != nil {
+		slog.ErrorContext(r.Context(), "failed to record audit entry", "action", action, "error", err)
+	}
+}
+
+// decodeEntryAction decodes and validates an EntryActionRequest
+func decodeEntryAction(w http.ResponseWriter, r *http.Request) (*EntryActionRequest, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return nil, false
+	}
+
+	var req EntryActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return nil, false
+	}
+	if req.QueueID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "queue_id is required")
+		return nil, false
+	}
+	annotateLog(r, "", req.QueueID)
+	return &req, true
+}
+
+// HandleRemoveEntry handles POST /admin/entries/remove
+func (h *Handler) HandleRemoveEntry(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeEntryAction(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.queueManager.RemoveEntry(req.QueueID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAudit(r, AuditRemoveEntry, entry.EventID, entry.QueueID, map[string]string{
+		"device_id": entry.DeviceID,
+		"user_id":   entry.UserID,
+		"reason":    req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// HandleMoveToFront handles POST /admin/entries/front
+func (h *Handler) HandleMoveToFront(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeEntryAction(w, r)
+	if !ok {
+		return
+	}
+
+	before, err := h.queueManager.GetQueueEntry(req.QueueID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	entry, err := h.queueManager.MoveToFront(req.QueueID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAuditChanges(r, AuditMoveToFront, entry.EventID, entry.QueueID, map[string]string{
+		"position": strconv.Itoa(entry.Position),
+		"reason":   req.Reason,
+	}, audit.Diff(
+		map[string]string{"priority_bucket": before.PriorityBucket},
+		map[string]string{"priority_bucket": entry.PriorityBucket},
+	))
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
+
+// HandleAdmitEntry handles POST /admin/entries/admit
+func (h *Handler) HandleAdmitEntry(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeEntryAction(w, r)
+	if !ok {
+		return
+	}
+
+	tokenString, payload, err := h.releaseController.AdmitEntry(req.QueueID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAudit(r, AuditAdmitEntry, payload.EventID, req.QueueID, map[string]string{
+		"device_id":  payload.DeviceID,
+		"user_id":    payload.UserID,
+		"token_hash": token.TokenHash(tokenString),
+		"expires_at": payload.ExpiresAt.UTC().Format(time.RFC3339),
+		"reason":     req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(AdmitResponse{
+		QueueID: req.QueueID,
+		AdmissionToken: &queue.AdmissionToken{
+			Token:     tokenString,
+			TokenHash: token.TokenHash(tokenString),
+			EventID:   payload.EventID,
+			DeviceID:  payload.DeviceID,
+			UserID:    payload.UserID,
+			QueueID:   payload.QueueID,
+			IssuedAt:  payload.IssuedAt,
+			ExpiresAt: payload.ExpiresAt,
+		},
+	})
+}
+
+// decodeBan decodes and validates a BanRequest
+func decodeBan(w http.ResponseWriter, r *http.Request) (*BanRequest, queue.BanKind, bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return nil, "", false
+	}
+
+	var req BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return nil, "", false
+	}
+	if req.EventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return nil, "", false
+	}
+	if req.Value == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "value is required")
+		return nil, "", false
+	}
+	kind, err := queue.ParseBanKind(req.Kind)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return nil, "", false
+	}
+	annotateLog(r, req.EventID, "")
+	return &req, kind, true
+}
+
+// HandleBan handles POST /admin/ban
+func (h *Handler) HandleBan(w http.ResponseWriter, r *http.Request) {
+	req, kind, ok := decodeBan(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.queueManager.Ban(req.EventID, kind, req.Value); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAudit(r, AuditBan, req.EventID, string(kind)+":"+req.Value, map[string]string{
+		"reason": req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"event_id": req.EventID,
+		"kind":     kind,
+		"value":    req.Value,
+		"banned":   true,
+	})
+}
+
+// HandleUnban handles POST /admin/unban
+func (h *Handler) HandleUnban(w http.ResponseWriter, r *http.Request) {
+	req, kind, ok := decodeBan(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := h.queueManager.Unban(req.EventID, kind, req.Value)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, CodeNotFound, "ban not found")
+		return
+	}
+
+	h.recordAudit(r, AuditUnban, req.EventID, string(kind)+":"+req.Value, map[string]string{
+		"reason": req.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"event_id": req.EventID,
+		"kind":     kind,
+		"value":    req.Value,
+		"banned":   false,
+	})
+}
+
+// HandleListBans handles GET /admin/bans
+func (h *Handler) HandleListBans(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	bans, err := h.queueManager.ListBans(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(BansResponse{EventID: eventID, Bans: bans})
+}
+
+// HandleAudit handles GET /admin/audit
+func (h *Handler) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.auditLog == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "audit log is not available")
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	entries, next, err := h.auditLog.Query(filter)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(AuditResponse{Entries: entries, NextCursor: next})
+}
+
+// parseAuditFilter reads an audit filter from the query string. Times are
+// RFC 3339.
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	_, limit, err := parsePage(r)
+	if err != nil {
+		return audit.Filter{}, err
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		EventID: query.Get("event_id"),
+		Action:  query.Get("action"),
+		Actor:   query.Get("actor"),
+		Target:  query.Get("target"),
+		Before:  query.Get("before"),
+		Limit:   limit,
+	}
+	for name, bound := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("%s must be an RFC 3339 time", name)
+		}
+		*bound = parsed
+	}
+	return filter, filter.Validate()
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"gatekeep/internal/audit"
+)
+
+func TestModerationHandlers_Validation(t *testing.T) {
+	handler := &Handler{}
+
+	tests := []struct {
+		name       string
+		handle     http.HandlerFunc
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"remove wrong method", handler.HandleRemoveEntry, "GET", "", http.StatusMethodNotAllowed},
+		{"remove missing queue_id", handler.HandleRemoveEntry, "POST", `{}`, http.StatusBadRequest},
+		{"front invalid body", handler.HandleMoveToFront, "POST", `{`, http.StatusBadRequest},
+		{"admit missing queue_id", handler.HandleAdmitEntry, "POST", `{"reason":"support"}`, http.StatusBadRequest},
+		{"ban missing event_id", handler.HandleBan, "POST", `{"kind":"device","value":"d1"}`, http.StatusBadRequest},
+		{"ban missing value", handler.HandleBan, "POST", `{"event_id":"evt","kind":"device"}`, http.StatusBadRequest},
+		{"ban invalid kind", handler.HandleBan, "POST", `{"event_id":"evt","kind":"email","value":"x"}`, http.StatusBadRequest},
+		{"unban invalid kind", handler.HandleUnban, "POST", `{"event_id":"evt","kind":"","value":"x"}`, http.StatusBadRequest},
+		{"audit unavailable", handler.HandleAudit, "GET", "", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			tt.handle(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAdminActor(t *testing.T) {
+	req := httptest.NewRequest("POST", "/admin/ban", nil)
+	if actor := adminActor(req); actor != "admin" {
+		t.Errorf("Expected default actor 'admin', got %q", actor)
+	}
+
+	var actor string
+	router := mux.NewRouter()
+	router.Use(AdminAuthMiddleware(map[string]string{"alice": "key-alice", "bob": "key-bob"}))
+	router.HandleFunc("/admin/ban", func(w http.ResponseWriter, r *http.Request) {
+		actor = adminActor(r)
+	})
+
+	// The actor is the name of the key, whatever the header claims
+	req = httptest.NewRequest("POST", "/admin/ban", nil)
+	req.Header.Set("X-API-Key", "key-bob")
+	req.Header.Set(AdminActorHeader, "alice")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if actor != "bob" {
+		t.Errorf("Expected actor 'bob', got %q", actor)
+	}
+}
+
+func TestParseAuditFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"defaults", "", false},
+		{"all filters", "?event_id=evt&action=ban&actor=alice&target=device:d1&since=2025-01-01T00:00:00Z&until=2025-01-02T00:00:00Z&before=1735689600000-0&limit=50", false},
+		{"limit too large is capped", "?limit=5000", false},
+		{"limit not a number", "?limit=ten", true},
+		{"since not RFC 3339", "?since=yesterday", true},
+		{"until before since", "?since=2025-01-02T00:00:00Z&until=2025-01-01T00:00:00Z", true},
+		{"malformed cursor", "?before=latest", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/audit"+tt.query, nil)
+			filter, err := parseAuditFilter(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (filter.Limit < 1 || filter.Limit > audit.MaxLimit) {
+				t.Errorf("Expected a limit between 1 and %d, got %d", audit.MaxLimit, filter.Limit)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/admin/audit?action=ban&actor=alice&since=2025-01-01T00:00:00Z", nil)
+	filter, _ := parseAuditFilter(req)
+	if filter.Action != "ban" || filter.Actor != "alice" || filter.Since.IsZero() {
+		t.Errorf("Unexpected filter: %+v", filter)
+	}
+}
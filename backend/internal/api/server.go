@@ -2,24 +2,37 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/pprof"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"gatekeep/internal/audit"
+	"gatekeep/internal/captcha"
 	"gatekeep/internal/config"
+	"gatekeep/internal/lifecycle"
 	"gatekeep/internal/queue"
 	"gatekeep/internal/release"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
 )
 
-// Server wraps the HTTP server
+// metricsWriteTimeout bounds responses on the metrics listener. It leaves
+// room for CPU profiles and traces, which run for 30 seconds by default.
+const metricsWriteTimeout = 2 * time.Minute
+
+// Server wraps the public HTTP server and the internal metrics server
 type Server struct {
-	handler *Handler
-	router  *mux.Router
-	config  *config.Config
-	server  *http.Server
+	handler       *Handler
+	router        *mux.Router
+	metricsRouter *mux.Router
+	config        *config.Config
+	server        *http.Server
+	metricsServer *http.Server
 }
 
 // NewServer creates a new API server
@@ -27,24 +40,47 @@ func NewServer(
 	cfg *config.Config,
 	queueManager *queue.Manager,
 	releaseController *release.Controller,
+	watcher *queue.Watcher,
+	auditLog *audit.Log,
+	tokenVerifier *token.Verifier,
+	captchaChecker *captcha.Checker,
+	webhooks *webhook.Dispatcher,
+	publisher lifecycle.Publisher,
 ) *Server {
 	handler := NewHandler(queueManager, releaseController)
+	handler.watcher = watcher
+	handler.auditLog = auditLog
+	handler.tokenVerifier = tokenVerifier
+	handler.captchaChecker = captchaChecker
+	handler.webhooks = webhooks
+	handler.lifecycle = publisher
 	router := mux.NewRouter()
 
+	// Assign request ids first so every log record of a request carries one
+	router.Use(RequestIDMiddleware())
+	router.Use(MetricsMiddleware())
+
+	// Resolve client IPs before any route middleware (rate limiting) runs
+	router.Use(ClientIPMiddleware(NewClientIPResolver(cfg.TrustedProxies, cfg.CloudflareProxies)))
+
 	// Register queue client routes
 	handler.RegisterQueueRoutes(router)
 
-	// Register admin routes
-	handler.RegisterRoutes(router, cfg.AdminAPIKey)
+	// Register routes for admitted clients
+	handler.RegisterAdmissionRoutes(router)
 
-	// Register Prometheus metrics endpoint
-	router.Path("/metrics").Handler(promhttp.Handler())
+	// Register the revocation list export for offline verifiers
+	handler.RegisterRevocationRoutes(router, cfg.RevocationListKey)
+
+	// Register admin routes, unless they are only served internally
+	if !cfg.AdminOnMetricsPort {
+		handler.RegisterRoutes(router, cfg.AdminCredentials())
+	}
 
 	// Health check endpoint
-	router.Path("/health").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+	router.Path("/health").HandlerFunc(handleHealth)
+
+	metricsRouter := newMetricsRouter(handler, cfg)
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	server := &http.Server{
@@ -55,20 +91,84 @@ func NewServer(
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Streams never go idle, so end them when shutdown begins
+	if watcher != nil {
+		server.RegisterOnShutdown(watcher.Stop)
+	}
+
+	metricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:      metricsRouter,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: metricsWriteTimeout,
+		IdleTimeout:  60 * time.Second,
+	}
+
 	return &Server{
-		handler: handler,
-		router:  router,
-		config:  cfg,
-		server:  server,
+		handler:       handler,
+		router:        router,
+		metricsRouter: metricsRouter,
+		config:        cfg,
+		server:        server,
+		metricsServer: metricsServer,
+	}
+}
+
+// newMetricsRouter creates the router of the internal listener: Prometheus
+// metrics, pprof profiles and, if configured, the admin API. None of it is
+// reachable through the public port.
+func newMetricsRouter(handler *Handler, cfg *config.Config) *mux.Router {
+	router := mux.NewRouter()
+
+	router.Use(RequestIDMiddleware())
+	router.Use(MetricsMiddleware())
+	router.Use(ClientIPMiddleware(NewClientIPResolver(cfg.TrustedProxies, cfg.CloudflareProxies)))
+
+	// Register Prometheus metrics endpoint
+	router.Path("/metrics").Handler(promhttp.Handler())
+
+	// Register profiling endpoints; pprof.Index also serves the named
+	// profiles, e.g. /debug/pprof/heap
+	router.Path("/debug/pprof/cmdline").HandlerFunc(pprof.Cmdline)
+	router.Path("/debug/pprof/profile").HandlerFunc(pprof.Profile)
+	router.Path("/debug/pprof/symbol").HandlerFunc(pprof.Symbol)
+	router.Path("/debug/pprof/trace").HandlerFunc(pprof.Trace)
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+
+	if cfg.AdminOnMetricsPort {
+		handler.RegisterRoutes(router, cfg.AdminCredentials())
 	}
+
+	router.Path("/health").HandlerFunc(handleHealth)
+
+	return router
 }
 
-// Start starts the HTTP server
+// handleHealth handles GET /health
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK"))
+}
+
+// Start starts the public and metrics servers and blocks until either stops.
+// If one fails, the other is closed too, so neither outlives the other.
 func (s *Server) Start() error {
-	return s.server.ListenAndServe()
+	errChan := make(chan error, 2)
+	go func() { errChan <- s.metricsServer.ListenAndServe() }()
+	go func() { errChan <- s.server.ListenAndServe() }()
+
+	err := <-errChan
+	if err != http.ErrServerClosed {
+		_ = s.server.Close()
+		_ = s.metricsServer.Close()
+	}
+	return err
 }
 
-// Shutdown gracefully shuts down the HTTP server
+// Shutdown gracefully shuts down both servers
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	errChan := make(chan error, 1)
+	go func() { errChan <- s.metricsServer.Shutdown(ctx) }()
+	err := s.server.Shutdown(ctx)
+	return errors.Join(err, <-errChan)
 }
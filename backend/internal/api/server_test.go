@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gatekeep/internal/config"
+)
+
+func newTestServer(adminOnMetricsPort bool) *Server {
+	cfg := &config.Config{
+		Port:               8080,
+		MetricsPort:        9090,
+		AdminAPIKey:        "test-admin-key",
+		AdminOnMetricsPort: adminOnMetricsPort,
+	}
+	return NewServer(cfg, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+func TestServer_Routes(t *testing.T) {
+	tests := []struct {
+		name               string
+		adminOnMetricsPort bool
+		path               string
+		wantPublic         int
+		wantMetrics        int
+	}{
+		{"metrics internal only", false, "/metrics", http.StatusNotFound, http.StatusOK},
+		{"pprof internal only", false, "/debug/pprof/", http.StatusNotFound, http.StatusOK},
+		{"named profile", false, "/debug/pprof/goroutine?debug=1", http.StatusNotFound, http.StatusOK},
+		{"health on both", false, "/health", http.StatusOK, http.StatusOK},
+		// Admin routes reject the missing API key; unregistered ones are not found
+		{"admin public by default", false, "/admin/metrics", http.StatusUnauthorized, http.StatusNotFound},
+		{"admin moved internal", true, "/admin/metrics", http.StatusNotFound, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(tt.adminOnMetricsPort)
+
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantPublic {
+				t.Errorf("Expected public status %d, got %d", tt.wantPublic, w.Code)
+			}
+
+			w = httptest.NewRecorder()
+			server.metricsRouter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantMetrics {
+				t.Errorf("Expected metrics status %d, got %d", tt.wantMetrics, w.Code)
+			}
+		})
+	}
+}
+
+func TestServer_MetricsListener(t *testing.T) {
+	server := newTestServer(false)
+
+	if server.metricsServer.Addr != ":9090" {
+		t.Errorf("Expected metrics server on :9090, got %s", server.metricsServer.Addr)
+	}
+	if server.server.Addr != ":8080" {
+		t.Errorf("Expected public server on :8080, got %s", server.server.Addr)
+	}
+}
+
+func TestServer_ShutdownStopsBoth(t *testing.T) {
+	server := newTestServer(false)
+	server.server.Addr = "127.0.0.1:0"
+	server.metricsServer.Addr = "127.0.0.1:0"
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- server.Start() }()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() failed: %v", err)
+	}
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Errorf("Expected ErrServerClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Start() to return after shutdown")
+	}
+}
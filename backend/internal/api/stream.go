@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gatekeep/internal/queue"
+)
+
+const (
+	// streamKeepaliveInterval is how often idle streams send a keepalive
+	streamKeepaliveInterval = 15 * time.Second
+	// streamWriteTimeout bounds each write to a stream client
+	streamWriteTimeout = 10 * time.Second
+)
+
+// HandleQueueStream handles GET /queue/stream
+//
+// The connection receives the current status immediately and then every
+// status change, including the admission token the moment the entry is
+// released. Clients that send a WebSocket upgrade get a WebSocket; all others
+// get Server-Sent Events. While connected the entry is kept alive, so no
+// separate heartbeats are needed. The stream ends once the entry is admitted
+// or expired.
+func (h *Handler) HandleQueueStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	queueID := r.URL.Query().Get("queue_id")
+	if queueID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "queue_id is required")
+		return
+	}
+	annotateLog(r, "", queueID)
+
+	if h.watcher == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "status streaming is not available")
+		return
+	}
+
+	sub, err := h.watcher.Watch(queueID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	defer sub.Close()
+
+	if isWebSocketUpgrade(r) {
+		h.serveWebSocketStream(w, r, sub)
+		return
+	}
+	h.serveSSEStream(w, r, sub)
+}
+
+// serveSSEStream writes status updates as Server-Sent Events
+func (h *Handler) serveSSEStream(w http.ResponseWriter, r *http.Request, sub *queue.Subscription) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := writeSSE(w, rc, fmt.Sprintf("retry: %d\n\n", streamKeepaliveInterval.Milliseconds())); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(streamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case status, ok := <-sub.Updates():
+			if !ok {
+				return
+			}
+			status.Version = queue.StatusVersion(status)
+			data, err := json.Marshal(status)
+			if err != nil {
+				return
+			}
+			if err := writeSSE(w, rc, fmt.Sprintf("event: %s\ndata: %s\n\n", streamEventName(status), data)); err != nil {
+				return
+			}
+			if isTerminalStatus(status) {
+				return
+			}
+		case <-keepalive.C:
+			if err := writeSSE(w, rc, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSSE writes a raw SSE chunk and flushes it to the client
+func writeSSE(w http.ResponseWriter, rc *http.ResponseController, chunk string) error {
+	_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if _, err := w.Write([]byte(chunk)); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// serveWebSocketStream writes status updates as WebSocket text messages
+func (h *Handler) serveWebSocketStream(w http.ResponseWriter, r *http.Request, sub *queue.Subscription) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Read loop: handles pings/close and detects dead peers via the deadline
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.readFrame(2 * streamKeepaliveInterval); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepalive := time.NewTicker(streamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close(1001, "going away")
+			return
+		case status, ok := <-sub.Updates():
+			if !ok {
+				_ = conn.Close(1001, "going away")
+				return
+			}
+			status.Version = queue.StatusVersion(status)
+			data, err := json.Marshal(status)
+			if err != nil {
+				_ = conn.Close(1011, "internal error")
+				return
+			}
+			if err := conn.WriteText(data); err != nil {
+				_ = conn.Close(1011, "write failed")
+				return
+			}
+			if isTerminalStatus(status) {
+				_ = conn.Close(1000, status.Status)
+				return
+			}
+		case <-keepalive.C:
+			if err := conn.Ping(); err != nil {
+				_ = conn.Close(1011, "write failed")
+				return
+			}
+		}
+	}
+}
+
+// streamEventName returns the SSE event name for a status update
+func streamEventName(status *queue.QueueStatus) string {
+	if status.Status == "admitted" {
+		return "admitted"
+	}
+	return "status"
+}
+
+// isTerminalStatus reports whether no further updates will follow
+func isTerminalStatus(status *queue.QueueStatus) bool {
+	return status.Status == "admitted" || status.Status == "expired"
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebsocketAccept(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	got := websocketAccept("dGhlIHNhbXBsZSBub25jZQ==")
+	if got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("websocketAccept() = %s", got)
+	}
+}
+
+func TestWebSocket_HandshakeAndFrames(t *testing.T) {
+	serverDone := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isWebSocketUpgrade(r) {
+			serverDone <- io.ErrUnexpectedEOF
+			return
+		}
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			serverDone <- err
+			return
+		}
+		if err := conn.WriteText([]byte(`{"status":"waiting"}`)); err != nil {
+			serverDone <- err
+			return
+		}
+		// Expect the client's ping to be answered, then a close
+		_, _, err = conn.readFrame(2 * time.Second)
+		serverDone <- err
+		conn.conn.Close()
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	_, _ = conn.Write([]byte("GET /queue/stream?queue_id=q1 HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + encodedKey + "\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(encodedKey) {
+		t.Error("Sec-WebSocket-Accept mismatch")
+	}
+
+	// Read the server's text frame
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		t.Fatalf("Failed to read frame header: %v", err)
+	}
+	if head[0] != 0x80|wsOpText {
+		t.Errorf("Expected final text frame, got 0x%x", head[0])
+	}
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("Failed to read payload: %v", err)
+	}
+	if string(payload) != `{"status":"waiting"}` {
+		t.Errorf("Unexpected payload: %s", payload)
+	}
+
+	// Send a masked ping and expect a pong
+	writeClientFrame(t, conn, wsOpPing, []byte("hi"))
+	if _, err := io.ReadFull(reader, head); err != nil {
+		t.Fatalf("Failed to read pong header: %v", err)
+	}
+	if head[0] != 0x80|wsOpPong || head[1] != 2 {
+		t.Errorf("Expected pong frame, got 0x%x 0x%x", head[0], head[1])
+	}
+	_, _ = io.ReadFull(reader, make([]byte, 2))
+
+	// Close and expect the server's read loop to end
+	closePayload := binary.BigEndian.AppendUint16(nil, 1000)
+	writeClientFrame(t, conn, wsOpClose, closePayload)
+
+	select {
+	case err := <-serverDone:
+		if err != errWebSocketClosed {
+			t.Errorf("Expected errWebSocketClosed, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for server")
+	}
+}
+
+func TestHandleQueueStream_Unavailable(t *testing.T) {
+	handler := &Handler{}
+
+	req := httptest.NewRequest("GET", "/queue/stream?queue_id=q1", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleQueueStream(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/queue/stream", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleQueueStream(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+// writeClientFrame writes a masked frame as a WebSocket client would
+func writeClientFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Failed to write client frame: %v", err)
+	}
+}
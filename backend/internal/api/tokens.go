@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
+)
+
+// AuditRevokeTokens is the audit action recorded for token revocations
+const AuditRevokeTokens = "revoke_tokens"
+
+// RevokeTokensRequest represents a request to revoke one token or every
+// token matching a filter
+type RevokeTokensRequest struct {
+	Token     string `json:"token,omitempty"`
+	TokenHash string `json:"token_hash,omitempty"`
+	token.RevocationFilter
+	Reason string `json:"reason,omitempty"`
+}
+
+// HandleRevokeTokens handles POST /admin/tokens/revoke
+func (h *Handler) HandleRevokeTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.tokenVerifier == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "token revocation is not available")
+		return
+	}
+
+	var req RevokeTokensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	annotateLog(r, req.EventID, "")
+
+	details := map[string]string{
+		"user_id":   req.UserID,
+		"device_id": req.DeviceID,
+		"reason":    req.Reason,
+	}
+	if !req.IssuedAfter.IsZero() {
+		details["issued_after"] = req.IssuedAfter.Format(time.RFC3339Nano)
+	}
+	if !req.IssuedBefore.IsZero() {
+		details["issued_before"] = req.IssuedBefore.Format(time.RFC3339Nano)
+	}
+
+	switch {
+	case req.Token != "":
+		if err := h.tokenVerifier.RevokeToken(req.Token); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+		details["scope"] = "token"
+		details["token_hash"] = token.TokenHash(req.Token)
+	case req.TokenHash != "":
+		if err := h.tokenVerifier.RevokeTokenByHash(req.TokenHash); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+		details["scope"] = "token"
+		details["token_hash"] = req.TokenHash
+	default:
+		if err := h.tokenVerifier.RevokeTokens(req.RevocationFilter); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+		details["scope"] = "filter"
+	}
+
+	h.emitRevoked(req, details)
+	h.recordAudit(r, AuditRevokeTokens, req.EventID, "", details)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"revoked": true,
+	})
+}
+
+// emitRevoked sends a "revoked" webhook to the event's subscribers and
+// publishes a revoked lifecycle event. Single tokens are attributed from their
+// metadata; filters without an event, e.g. a user's tokens across events,
+// have no event to report.
+func (h *Handler) emitRevoked(req RevokeTokensRequest, details map[string]string) {
+	if h.webhooks == nil && h.lifecycle == nil {
+		return
+	}
+
+	event := webhook.Event{
+		Type:      webhook.EventRevoked,
+		EventID:   req.EventID,
+		DeviceID:  req.DeviceID,
+		UserID:    req.UserID,
+		TokenHash: details["token_hash"],
+		Details:   map[string]string{"scope": details["scope"]},
+	}
+	if req.Reason != "" {
+		event.Details["reason"] = req.Reason
+	}
+	if event.TokenHash != "" {
+		if metadata, err := h.tokenVerifier.GetTokenMetadataByHash(event.TokenHash); err == nil {
+			event.EventID = metadata.EventID
+			event.QueueID = metadata.QueueID
+			event.DeviceID = metadata.DeviceID
+			event.UserID = metadata.UserID
+		}
+	}
+	if event.EventID == "" {
+		return
+	}
+
+	h.webhooks.Emit(event)
+	lifecycle.Emit(h.lifecycle, lifecycle.Event{
+		Type:      lifecycle.Revoked,
+		EventID:   event.EventID,
+		QueueID:   event.QueueID,
+		DeviceID:  event.DeviceID,
+		UserID:    event.UserID,
+		TokenHash: event.TokenHash,
+		Details:   event.Details,
+	})
+}
+
+// RegisterRevocationRoutes registers the revocation list export for offline
+// verifiers. The list is signed, so it is served without authentication
+// unless a read-only key is configured; the admin key is never required.
+func (h *Handler) RegisterRevocationRoutes(r *mux.Router, revocationListKey string) {
+	revocationRouter := r.PathPrefix("/revocations").Subrouter()
+
+	if revocationListKey != "" {
+		revocationRouter.Use(APIKeyMiddleware(revocationListKey))
+	}
+	revocationRouter.Use(RequestLoggingMiddleware())
+	revocationRouter.Use(RateLimitMiddleware())
+
+	revocationRouter.HandleFunc("", h.HandleRevocationList).Methods("GET")
+}
+
+// HandleRevocationList handles GET /revocations and GET /admin/revocations
+//
+// The body is the event's revocation list as a compact JWS signed with the
+// token secret. The ETag is the list version, so verifiers can poll cheaply
+// with If-None-Match.
+func (h *Handler) HandleRevocationList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if h.tokenVerifier == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "token revocation is not available")
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	// Answer unchanged polls without building the list
+	version, err := h.tokenVerifier.RevocationListVersion(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if requestedVersion(r) == version {
+		w.Header().Set("ETag", `"`+version+`"`)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	list, err := h.tokenVerifier.RevocationList(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+	signed, err := h.tokenVerifier.SignRevocationList(list)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", `"`+list.Version+`"`)
+	w.Header().Set("Content-Type", "application/jwt")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(signed))
+}
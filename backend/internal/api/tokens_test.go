@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"gatekeep/internal/config"
+	redisclient "gatekeep/internal/redis"
+	"gatekeep/internal/token"
+)
+
+func TestHandleRevokeTokens_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    *Handler
+		body       string
+		wantStatus int
+	}{
+		{"unavailable", &Handler{}, `{"event_id":"evt"}`, http.StatusServiceUnavailable},
+		{"invalid body", &Handler{tokenVerifier: token.NewVerifier(nil, "secret")}, `{`, http.StatusBadRequest},
+		{"empty filter", &Handler{tokenVerifier: token.NewVerifier(nil, "secret")}, `{}`, http.StatusBadRequest},
+		{"window with user", &Handler{tokenVerifier: token.NewVerifier(nil, "secret")},
+			`{"user_id":"u1","issued_after":"2024-01-15T10:00:00Z"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/tokens/revoke", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			tt.handler.HandleRevokeTokens(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleRevocationList_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    *Handler
+		query      string
+		wantStatus int
+	}{
+		{"unavailable", &Handler{}, "event_id=evt", http.StatusServiceUnavailable},
+		{"missing event_id", &Handler{tokenVerifier: token.NewVerifier(nil, "secret")}, "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/revocations?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			tt.handler.HandleRevocationList(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleRefreshAdmission_Validation(t *testing.T) {
+	verifier := token.NewVerifier(nil, "secret")
+	tests := []struct {
+		name       string
+		handler    *Handler
+		body       string
+		wantStatus int
+	}{
+		{"unavailable", &Handler{}, `{"token":"a.b.c"}`, http.StatusServiceUnavailable},
+		{"invalid body", &Handler{tokenVerifier: verifier}, `{`, http.StatusBadRequest},
+		{"missing token", &Handler{tokenVerifier: verifier}, `{}`, http.StatusBadRequest},
+		{"malformed token", &Handler{tokenVerifier: verifier}, `{"token":"not-a-token"}`, http.StatusBadRequest},
+		{"bad signature", &Handler{tokenVerifier: verifier}, `{"token":"e30.e30.c2ln"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admission/refresh", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			tt.handler.HandleRefreshAdmission(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleVerifyAdmission_Validation(t *testing.T) {
+	verifier := token.NewVerifier(nil, "secret")
+	tests := []struct {
+		name       string
+		handler    *Handler
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unavailable", &Handler{}, `{"token":"a.b.c"}`, http.StatusServiceUnavailable, ""},
+		{"invalid body", &Handler{tokenVerifier: verifier}, `{`, http.StatusBadRequest, ""},
+		{"missing token", &Handler{tokenVerifier: verifier}, `{}`, http.StatusBadRequest, ""},
+		{"malformed token", &Handler{tokenVerifier: verifier}, `{"token":"not-a-token"}`, http.StatusBadRequest, ""},
+		{"bad signature", &Handler{tokenVerifier: verifier}, `{"token":"e30.e30.c2ln"}`, http.StatusOK, CodeTokenInvalid},
+		{"proof without htu", &Handler{tokenVerifier: verifier}, `{"token":"a.b.c","proof":"a.b.c","htm":"POST"}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admission/verify", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			tt.handler.HandleVerifyAdmission(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp VerifyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Valid || resp.Code != tt.wantCode {
+				t.Errorf("Expected invalid with code %s, got %+v", tt.wantCode, resp)
+			}
+		})
+	}
+}
+
+func TestHandleVerifyAdmission_BoundToken(t *testing.T) {
+	secret := "this-is-a-very-long-secret-key-that-is-at-least-32-characters"
+	redisClient, err := redisclient.NewClient(&config.Config{RedisAddr: "localhost:6379", TokenSecret: secret})
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+	}
+	handler := &Handler{tokenVerifier: token.NewVerifier(redisClient, secret)}
+	bound, _, err := token.NewGenerator(redisClient, secret).Issue(token.IssueRequest{
+		EventID:       "test-event-verify-bound",
+		DeviceID:      "device-verify-bound",
+		KeyThumbprint: "thumbprint-1",
+	})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"without proof", `{"token":"` + bound + `"}`},
+		{"with invalid proof", `{"token":"` + bound + `","proof":"a.b.c","htm":"POST","htu":"https://shop.example.com/checkout"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admission/verify", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.HandleVerifyAdmission(rr, req)
+
+			var resp VerifyResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.Valid || resp.Code != CodeProofInvalid {
+				t.Errorf("Expected invalid with code %s, got %+v", CodeProofInvalid, resp)
+			}
+		})
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"plain", "10.0.0.5:443", nil, "http://gatekeep.example.com/admission/refresh"},
+		{"behind TLS proxy", "10.0.0.5:443", map[string]string{"X-Forwarded-Proto": "https"}, "https://gatekeep.example.com/admission/refresh"},
+		{"nearest proxy wins", "10.0.0.5:443", map[string]string{"X-Forwarded-Proto": "https, http"}, "http://gatekeep.example.com/admission/refresh"},
+		{"untrusted peer", "203.0.113.7:443", map[string]string{"X-Forwarded-Proto": "https"}, "http://gatekeep.example.com/admission/refresh"},
+	}
+
+	resolver := newTestResolver(t, "10.0.0.0/8", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "http://gatekeep.example.com/admission/refresh?x=1", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			var got string
+			ClientIPMiddleware(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = requestURL(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterRevocationRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		header     string
+		wantStatus int
+	}{
+		// The handler answers 503 without a verifier, so reaching it means the
+		// request was authorized
+		{"public without key", "", "", http.StatusServiceUnavailable},
+		{"missing read key", "read-key", "", http.StatusUnauthorized},
+		{"admin key rejected", "read-key", "admin-key", http.StatusUnauthorized},
+		{"read key", "read-key", "read-key", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mux.NewRouter()
+			(&Handler{}).RegisterRevocationRoutes(router, tt.key)
+
+			req := httptest.NewRequest("GET", "/revocations?event_id=evt", nil)
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gatekeep/internal/queue"
+	"gatekeep/internal/webhook"
+)
+
+// Audit actions recorded by the webhook handlers
+const (
+	AuditCreateWebhook = "create_webhook"
+	AuditRemoveWebhook = "remove_webhook"
+	AuditReplayWebhook = "replay_webhook"
+)
+
+// CreateWebhookRequest represents a request to subscribe a URL to an event's
+// webhooks. Secret is generated when omitted; Events defaults to all types.
+type CreateWebhookRequest struct {
+	EventID string   `json:"event_id"`
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret,omitempty"`
+	Events  []string `json:"events,omitempty"`
+}
+
+// RemoveWebhookRequest represents a request to remove a subscription
+type RemoveWebhookRequest struct {
+	EventID        string `json:"event_id"`
+	SubscriptionID string `json:"subscription_id"`
+}
+
+// ReplayWebhookRequest represents a request to replay one dead-lettered
+// delivery, or all of the event's when DeliveryID is empty
+type ReplayWebhookRequest struct {
+	EventID    string `json:"event_id"`
+	DeliveryID string `json:"delivery_id,omitempty"`
+}
+
+// WebhooksResponse represents an event's webhook subscriptions
+type WebhooksResponse struct {
+	EventID       string                  `json:"event_id"`
+	Subscriptions []*webhook.Subscription `json:"subscriptions"`
+}
+
+// DeadLettersResponse represents a page of an event's failed deliveries
+type DeadLettersResponse struct {
+	EventID    string              `json:"event_id"`
+	Deliveries []*webhook.Delivery `json:"deliveries"`
+	Page       queue.Page          `json:"page"`
+}
+
+// requireWebhooks writes an error when webhooks are not configured
+func (h *Handler) requireWebhooks(w http.ResponseWriter) bool {
+	if h.webhooks == nil {
+		writeError(w, http.StatusServiceUnavailable, CodeInternal, "webhooks are not available")
+		return false
+	}
+	return true
+}
+
+// HandleListWebhooks handles GET /admin/webhooks
+func (h *Handler) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireWebhooks(w) {
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	subs, err := h.webhooks.Subscriptions(eventID)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(WebhooksResponse{
+		EventID:       eventID,
+		Subscriptions: subs,
+	})
+}
+
+// HandleCreateWebhook handles POST /admin/webhooks. The response is the only
+// place the subscription's secret is returned.
+func (h *Handler) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireWebhooks(w) {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	sub, err := h.webhooks.Subscribe(webhook.Subscription{
+		EventID: req.EventID,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Events:  req.Events,
+	})
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAudit(r, AuditCreateWebhook, sub.EventID, sub.ID, map[string]string{
+		"url":    sub.URL,
+		"events": strings.Join(sub.Events, ","),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(sub)
+}
+
+// HandleRemoveWebhook handles POST /admin/webhooks/remove
+func (h *Handler) HandleRemoveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireWebhooks(w) {
+		return
+	}
+
+	var req RemoveWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.EventID == "" || req.SubscriptionID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id and subscription_id are required")
+		return
+	}
+
+	if err := h.webhooks.Unsubscribe(req.EventID, req.SubscriptionID); err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	h.recordAudit(r, AuditRemoveWebhook, req.EventID, req.SubscriptionID, map[string]string{})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed": true,
+	})
+}
+
+// HandleListDeadLetters handles GET /admin/webhooks/deliveries
+func (h *Handler) HandleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireWebhooks(w) {
+		return
+	}
+
+	eventID := r.URL.Query().Get("event_id")
+	if eventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	offset, limit, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	deliveries, total, err := h.webhooks.DeadLetters(eventID, offset, limit)
+	if err != nil {
+		writeDomainError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(DeadLettersResponse{
+		EventID:    eventID,
+		Deliveries: deliveries,
+		Page:       queue.Page{Offset: offset, Limit: limit, Total: int(total)},
+	})
+}
+
+// HandleReplayWebhook handles POST /admin/webhooks/replay
+func (h *Handler) HandleReplayWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireWebhooks(w) {
+		return
+	}
+
+	var req ReplayWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	if req.EventID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "event_id is required")
+		return
+	}
+
+	replayed := 1
+	if req.DeliveryID != "" {
+		if err := h.webhooks.Replay(req.EventID, req.DeliveryID); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+	} else {
+		var err error
+		if replayed, err = h.webhooks.ReplayAll(req.EventID); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+	}
+
+	h.recordAudit(r, AuditReplayWebhook, req.EventID, req.DeliveryID, map[string]string{
+		"replayed": strconv.Itoa(replayed),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"replayed": replayed,
+	})
+}
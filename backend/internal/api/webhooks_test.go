@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gatekeep/internal/webhook"
+)
+
+func TestWebhookHandlers_Validation(t *testing.T) {
+	available := &Handler{webhooks: webhook.NewDispatcher(nil)}
+
+	tests := []struct {
+		name       string
+		handler    *Handler
+		handle     func(h *Handler) http.HandlerFunc
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"list unavailable", &Handler{}, func(h *Handler) http.HandlerFunc { return h.HandleListWebhooks },
+			"GET", "/admin/webhooks?event_id=evt", "", http.StatusServiceUnavailable},
+		{"list missing event_id", available, func(h *Handler) http.HandlerFunc { return h.HandleListWebhooks },
+			"GET", "/admin/webhooks", "", http.StatusBadRequest},
+		{"create unavailable", &Handler{}, func(h *Handler) http.HandlerFunc { return h.HandleCreateWebhook },
+			"POST", "/admin/webhooks", `{"event_id":"evt","url":"https://example.com"}`, http.StatusServiceUnavailable},
+		{"create invalid body", available, func(h *Handler) http.HandlerFunc { return h.HandleCreateWebhook },
+			"POST", "/admin/webhooks", `{`, http.StatusBadRequest},
+		{"create invalid url", available, func(h *Handler) http.HandlerFunc { return h.HandleCreateWebhook },
+			"POST", "/admin/webhooks", `{"event_id":"evt","url":"example.com/hooks"}`, http.StatusBadRequest},
+		{"create unknown event type", available, func(h *Handler) http.HandlerFunc { return h.HandleCreateWebhook },
+			"POST", "/admin/webhooks", `{"event_id":"evt","url":"https://example.com","events":["paused"]}`, http.StatusBadRequest},
+		{"remove missing subscription_id", available, func(h *Handler) http.HandlerFunc { return h.HandleRemoveWebhook },
+			"POST", "/admin/webhooks/remove", `{"event_id":"evt"}`, http.StatusBadRequest},
+		{"deliveries missing event_id", available, func(h *Handler) http.HandlerFunc { return h.HandleListDeadLetters },
+			"GET", "/admin/webhooks/deliveries", "", http.StatusBadRequest},
+		{"deliveries invalid limit", available, func(h *Handler) http.HandlerFunc { return h.HandleListDeadLetters },
+			"GET", "/admin/webhooks/deliveries?event_id=evt&limit=0", "", http.StatusBadRequest},
+		{"replay missing event_id", available, func(h *Handler) http.HandlerFunc { return h.HandleReplayWebhook },
+			"POST", "/admin/webhooks/replay", `{"delivery_id":"d1"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			tt.handle(tt.handler)(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package attestation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+// Verdict is the outcome of checking a device's attestation
+type Verdict string
+
+const (
+	// Allow lets the device join as requested
+	Allow Verdict = "allow"
+	// Deprioritize lets the device join behind every other entry
+	Deprioritize Verdict = "deprioritize"
+	// Deny rejects the join
+	Deny Verdict = "deny"
+)
+
+// Sentinel errors returned by verifiers. Match them with errors.Is.
+var (
+	// ErrInvalid is returned when attestation evidence is malformed, forged,
+	// stale or issued for another device
+	ErrInvalid = errors.New("invalid attestation")
+	// ErrUnavailable is returned when attestation cannot be checked, e.g. a
+	// remote verifier is down
+	ErrUnavailable = errors.New("attestation unavailable")
+)
+
+// Request is the attestation presented with a join
+type Request struct {
+	EventID  string
+	DeviceID string
+	// Token is the attestation evidence, e.g. a Play Integrity or App Attest
+	// result relayed as a signed JWS
+	Token string
+}
+
+// Verifier checks a device's attestation. Implementations return ErrInvalid
+// for evidence that cannot be trusted and a verdict otherwise.
+type Verifier interface {
+	Verify(ctx context.Context, req Request) (Verdict, string, error)
+}
+
+// Nonce returns the nonce an attestation for the device must carry:
+// base64url(SHA-256(len(event_id) + ":" + event_id + device_id)), with the
+// byte length of event_id in decimal. Binding the nonce to the event and
+// device keeps evidence from being reused by other devices, and the length
+// prefix keeps IDs containing ":" from colliding with other pairs.
+func Nonce(eventID, deviceID string) string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(len(eventID)) + ":" + eventID + deviceID))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
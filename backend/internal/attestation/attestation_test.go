@@ -0,0 +1,15 @@
+package attestation
+
+import "testing"
+
+func TestNonce(t *testing.T) {
+	// base64url(SHA-256("7:event-1device-1"))
+	if got, want := Nonce("event-1", "device-1"), "CiDhUSuBnTUhJNLE4zjrge2BGrI4MYyf2Ml6fFUkxhY"; got != want {
+		t.Errorf("Expected nonce %s, got %s", want, got)
+	}
+
+	// IDs containing the separator must not collide
+	if Nonce("a:b", "c") == Nonce("a", "b:c") {
+		t.Error("Expected different nonces for different event and device IDs")
+	}
+}
@@ -0,0 +1,242 @@
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxTokenLength bounds the size of attestation tokens, which carry a
+	// certificate chain
+	MaxTokenLength = 16 << 10
+	// DefaultMaxAge is how old an attestation may be
+	DefaultMaxAge = 5 * time.Minute
+	// clockSkew is how far in the future an attestation's "iat" may be
+	clockSkew = time.Minute
+)
+
+// Policy maps the integrity labels of an attestation to a verdict. Labels in
+// Allow win over labels in Deprioritize; attestations with neither are denied.
+type Policy struct {
+	Allow        []string
+	Deprioritize []string
+	MaxAge       time.Duration
+}
+
+// DefaultPolicy follows the Play Integrity device verdicts: device and strong
+// integrity are allowed, basic integrity (e.g. rooted or emulated) is
+// deprioritized
+func DefaultPolicy() Policy {
+	return Policy{
+		Allow:        []string{"MEETS_STRONG_INTEGRITY", "MEETS_DEVICE_INTEGRITY"},
+		Deprioritize: []string{"MEETS_BASIC_INTEGRITY"},
+		MaxAge:       DefaultMaxAge,
+	}
+}
+
+// jwsHeader is the protected header of an attestation
+type jwsHeader struct {
+	Algorithm string   `json:"alg"`
+	Chain     []string `json:"x5c"`
+}
+
+// jwsClaims are the claims of an attestation
+type jwsClaims struct {
+	Nonce    string   `json:"nonce"`
+	IssuedAt int64    `json:"iat"`
+	Verdicts []string `json:"verdicts"`
+}
+
+// JWSVerifier checks attestations relayed as compact JWS (ES256 or RS256)
+// whose "x5c" certificate chain leads to one of the configured roots. The
+// claims carry the nonce for the device, the issue time in seconds and the
+// integrity labels reported by the platform.
+type JWSVerifier struct {
+	roots  *x509.CertPool
+	policy Policy
+}
+
+// NewJWSVerifier creates a verifier trusting the given roots
+func NewJWSVerifier(roots *x509.CertPool, policy Policy) *JWSVerifier {
+	if policy.MaxAge <= 0 {
+		policy.MaxAge = DefaultMaxAge
+	}
+	return &JWSVerifier{roots: roots, policy: policy}
+}
+
+// LoadRoots reads PEM-encoded root certificates from a file
+func LoadRoots(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation roots: %w", err)
+	}
+	return ParseRoots(data)
+}
+
+// ParseRoots parses PEM-encoded root certificates
+func ParseRoots(data []byte) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid root certificate: %w", err)
+		}
+		roots.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("no PEM certificates found")
+	}
+	return roots, nil
+}
+
+// Verify checks the attestation's chain, signature, nonce and age, then maps
+// its integrity labels to a verdict. The string is a reason for the verdict.
+func (v *JWSVerifier) Verify(ctx context.Context, req Request) (Verdict, string, error) {
+	if len(req.Token) > MaxTokenLength {
+		return Deny, "", fmt.Errorf("%w: longer than %d bytes", ErrInvalid, MaxTokenLength)
+	}
+	parts := strings.Split(req.Token, ".")
+	if len(parts) != 3 {
+		return Deny, "", fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalid, len(parts))
+	}
+
+	headerJSON, errHeader := base64.RawURLEncoding.Strict().DecodeString(parts[0])
+	claimsJSON, errClaims := base64.RawURLEncoding.Strict().DecodeString(parts[1])
+	signature, errSignature := base64.RawURLEncoding.Strict().DecodeString(parts[2])
+	if errHeader != nil || errClaims != nil || errSignature != nil {
+		return Deny, "", fmt.Errorf("%w: invalid base64url", ErrInvalid)
+	}
+
+	var header jwsHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return Deny, "", fmt.Errorf("%w: invalid header", ErrInvalid)
+	}
+	leaf, err := v.verifyChain(header.Chain)
+	if err != nil {
+		return Deny, "", err
+	}
+	if err := verifySignature(header.Algorithm, leaf.PublicKey, parts[0]+"."+parts[1], signature); err != nil {
+		return Deny, "", err
+	}
+
+	var claims jwsClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return Deny, "", fmt.Errorf("%w: invalid claims", ErrInvalid)
+	}
+	if claims.Nonce != Nonce(req.EventID, req.DeviceID) {
+		return Deny, "", fmt.Errorf("%w: nonce does not match the device", ErrInvalid)
+	}
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if age := time.Since(issuedAt); claims.IssuedAt == 0 || age > v.policy.MaxAge || age < -clockSkew {
+		return Deny, "", fmt.Errorf("%w: issued at %s, outside the allowed window", ErrInvalid, issuedAt.UTC().Format(time.RFC3339))
+	}
+
+	verdict, reason := v.policy.verdict(claims.Verdicts)
+	return verdict, reason, nil
+}
+
+// verifyChain checks that an x5c chain leads to a trusted root and returns
+// the leaf certificate
+func (v *JWSVerifier) verifyChain(chain []string) (*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: x5c is required", ErrInvalid)
+	}
+
+	certs := make([]*x509.Certificate, 0, len(chain))
+	for _, encoded := range chain {
+		// x5c uses standard base64, not base64url (RFC 7515 section 4.1.6)
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid x5c encoding", ErrInvalid)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid x5c certificate: %v", ErrInvalid, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("%w: untrusted certificate chain: %v", ErrInvalid, err)
+	}
+	return certs[0], nil
+}
+
+// verifySignature checks a JWS signature made with the leaf certificate's key
+func verifySignature(algorithm string, publicKey crypto.PublicKey, input string, signature []byte) error {
+	digest := sha256.Sum256([]byte(input))
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if algorithm != "ES256" {
+			return fmt.Errorf("%w: alg %q does not match the EC key", ErrInvalid, algorithm)
+		}
+		// JWS encodes ES256 signatures as r || s
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: invalid signature", ErrInvalid)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return fmt.Errorf("%w: invalid signature", ErrInvalid)
+		}
+	case *rsa.PublicKey:
+		if algorithm != "RS256" {
+			return fmt.Errorf("%w: alg %q does not match the RSA key", ErrInvalid, algorithm)
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: invalid signature", ErrInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported key type %T", ErrInvalid, publicKey)
+	}
+	return nil
+}
+
+// verdict maps integrity labels to a verdict and a reason
+func (p Policy) verdict(labels []string) (Verdict, string) {
+	for _, allowed := range p.Allow {
+		for _, label := range labels {
+			if label == allowed {
+				return Allow, label
+			}
+		}
+	}
+	for _, deprioritized := range p.Deprioritize {
+		for _, label := range labels {
+			if label == deprioritized {
+				return Deprioritize, label
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return Deny, "no integrity verdicts"
+	}
+	return Deny, "integrity verdicts " + strings.Join(labels, ", ")
+}
@@ -0,0 +1,230 @@
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCA is a locally generated root that issues attestation signing keys
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// testSigner is a leaf certificate and its key
+type testSigner struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return roots
+}
+
+// issue creates a leaf certificate for the key
+func (ca *testCA) issue(t *testing.T, key crypto.Signer) *testSigner {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "attest.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testSigner{cert: cert, key: key}
+}
+
+func newECSigner(t *testing.T, ca *testCA) *testSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return ca.issue(t, key)
+}
+
+func newRSASigner(t *testing.T, ca *testCA) *testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return ca.issue(t, key)
+}
+
+// sign creates an attestation with the given algorithm and claims
+func (s *testSigner) sign(t *testing.T, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]interface{}{
+		"alg": alg,
+		"x5c": []string{base64.StdEncoding.EncodeToString(s.cert.Raw)},
+	})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		r, sig, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		sig.FillBytes(signature[32:])
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// claimsFor returns valid claims for event-1/device-1 with the given verdicts
+func claimsFor(verdicts ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"nonce":    Nonce("event-1", "device-1"),
+		"iat":      time.Now().Unix(),
+		"verdicts": verdicts,
+	}
+}
+
+func TestJWSVerifier_Verdicts(t *testing.T) {
+	ca := newTestCA(t)
+	verifier := NewJWSVerifier(ca.pool(), DefaultPolicy())
+	ecSigner := newECSigner(t, ca)
+	rsaSigner := newRSASigner(t, ca)
+
+	tests := []struct {
+		name    string
+		token   string
+		verdict Verdict
+	}{
+		{"ES256 device integrity", ecSigner.sign(t, "ES256", claimsFor("MEETS_BASIC_INTEGRITY", "MEETS_DEVICE_INTEGRITY")), Allow},
+		{"RS256 strong integrity", rsaSigner.sign(t, "RS256", claimsFor("MEETS_STRONG_INTEGRITY")), Allow},
+		{"basic integrity", ecSigner.sign(t, "ES256", claimsFor("MEETS_BASIC_INTEGRITY")), Deprioritize},
+		{"no integrity", ecSigner.sign(t, "ES256", claimsFor()), Deny},
+		{"unknown label", ecSigner.sign(t, "ES256", claimsFor("MEETS_VIRTUAL_INTEGRITY")), Deny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, reason, err := verifier.Verify(context.Background(), Request{EventID: "event-1", DeviceID: "device-1", Token: tt.token})
+			if err != nil {
+				t.Fatalf("Verify() failed: %v", err)
+			}
+			if verdict != tt.verdict {
+				t.Errorf("Expected verdict %s, got %s (%s)", tt.verdict, verdict, reason)
+			}
+			if reason == "" {
+				t.Error("Expected a reason")
+			}
+		})
+	}
+}
+
+func TestJWSVerifier_Rejects(t *testing.T) {
+	ca := newTestCA(t)
+	verifier := NewJWSVerifier(ca.pool(), DefaultPolicy())
+	signer := newECSigner(t, ca)
+	untrusted := newECSigner(t, newTestCA(t))
+
+	withClaim := func(claim string, value interface{}) string {
+		claims := claimsFor("MEETS_DEVICE_INTEGRITY")
+		claims[claim] = value
+		return signer.sign(t, "ES256", claims)
+	}
+	valid := signer.sign(t, "ES256", claimsFor("MEETS_DEVICE_INTEGRITY"))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-an-attestation"},
+		{"invalid base64", "a.b.c!"},
+		{"untrusted root", untrusted.sign(t, "ES256", claimsFor("MEETS_DEVICE_INTEGRITY"))},
+		{"tampered", valid[:len(valid)-4] + "AAAA"},
+		{"alg mismatch", signer.sign(t, "RS256", claimsFor("MEETS_DEVICE_INTEGRITY"))},
+		{"other device", withClaim("nonce", Nonce("event-1", "device-2"))},
+		{"stale", withClaim("iat", time.Now().Add(-10*time.Minute).Unix())},
+		{"future", withClaim("iat", time.Now().Add(10*time.Minute).Unix())},
+		{"missing iat", withClaim("iat", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := verifier.Verify(context.Background(), Request{EventID: "event-1", DeviceID: "device-1", Token: tt.token})
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseRoots(t *testing.T) {
+	ca := newTestCA(t)
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+
+	roots, err := ParseRoots(data)
+	if err != nil {
+		t.Fatalf("ParseRoots() failed: %v", err)
+	}
+
+	signer := newECSigner(t, ca)
+	verifier := NewJWSVerifier(roots, DefaultPolicy())
+	token := signer.sign(t, "ES256", claimsFor("MEETS_DEVICE_INTEGRITY"))
+	if _, _, err := verifier.Verify(context.Background(), Request{EventID: "event-1", DeviceID: "device-1", Token: token}); err != nil {
+		t.Errorf("Verify() failed with parsed roots: %v", err)
+	}
+
+	if _, err := ParseRoots([]byte("not pem")); err == nil {
+		t.Error("Expected error for data without certificates")
+	}
+}
@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	redisclient "gatekeep/internal/redis"
+)
+
+const (
+	// StreamKey is the Redis stream audit entries are appended to
+	StreamKey = "audit:stream"
+	// MaxEntries is roughly how many audit entries the stream retains
+	MaxEntries = 100000
+
+	// DefaultLimit and MaxLimit bound the entries returned by a query
+	DefaultLimit = 50
+	MaxLimit     = 500
+
+	// scanBatch is how many stream entries a query reads per round trip
+	scanBatch = 500
+)
+
+// ErrInvalidFilter is returned for a malformed query filter
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// cursorPattern matches the stream ids used as query cursors
+var cursorPattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?$`)
+
+// Entry records a single administrative or security action
+type Entry struct {
+	ID        string            `json:"id,omitempty"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor"`
+	ClientIP  string            `json:"client_ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Action    string            `json:"action"`
+	EventID   string            `json:"event_id,omitempty"`
+	Target    string            `json:"target,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	Changes   []Change          `json:"changes,omitempty"`
+}
+
+// Sink receives a copy of every recorded entry, e.g. for shipping to
+// long-term storage outside Redis
+type Sink interface {
+	Write(entry Entry) error
+}
+
+// Log is an append-only record of administrative actions
+type Log struct {
+	redisClient *redisclient.Client
+	sink        Sink
+	ctx         context.Context
+}
+
+// NewLog creates a new audit log
+func NewLog(redisClient *redisclient.Client) *Log {
+	return &Log{
+		redisClient: redisClient,
+		ctx:         context.Background(),
+	}
+}
+
+// SetSink sets a sink that receives every entry in addition to the stream
+func (l *Log) SetSink(sink Sink) {
+	l.sink = sink
+}
+
+// Record appends an entry to the audit log. Entries are also written to the
+// process log and the sink so they survive a Redis outage.
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	slog.Info("audit", "entry", json.RawMessage(data))
+
+	ctx, cancel := context.WithTimeout(l.ctx, 2*time.Second)
+	defer cancel()
+
+	id, streamErr := l.redisClient.GetClient().XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey,
+		MaxLen: MaxEntries,
+		Approx: true,
+		Values: map[string]interface{}{
+			"action": entry.Action,
+			"data":   data,
+		},
+	}).Result()
+	entry.ID = id
+
+	if l.sink != nil {
+		if err := l.sink.Write(entry); err != nil {
+			slog.Error("failed to write audit entry to sink", "action", entry.Action, "error", err)
+		}
+	}
+	if streamErr != nil {
+		return fmt.Errorf("failed to record audit entry: %w", streamErr)
+	}
+	return nil
+}
+
+// Filter selects audit entries. Empty fields match every entry.
+type Filter struct {
+	EventID string
+	Action  string
+	Actor   string
+	Target  string
+	// Since and Until bound the entry time, inclusive
+	Since time.Time
+	Until time.Time
+	// Before is a cursor: only entries older than this entry id match
+	Before string
+	// Limit is the most entries returned; 0 uses DefaultLimit
+	Limit int
+}
+
+// Matches reports whether the entry's fields match the filter. The time
+// range and cursor are applied by Query.
+func (f Filter) Matches(entry *Entry) bool {
+	return (f.EventID == "" || entry.EventID == f.EventID) &&
+		(f.Action == "" || entry.Action == f.Action) &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.Target == "" || entry.Target == f.Target)
+}
+
+// Validate checks the filter and applies the default limit
+func (f *Filter) Validate() error {
+	if f.Limit < 0 || f.Limit > MaxLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidFilter, MaxLimit)
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultLimit
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		return fmt.Errorf("%w: until must not be before since", ErrInvalidFilter)
+	}
+	if f.Before != "" && !cursorPattern.MatchString(f.Before) {
+		return fmt.Errorf("%w: malformed cursor %q", ErrInvalidFilter, f.Before)
+	}
+	return nil
+}
+
+// Query returns the entries matching the filter, newest first. When more
+// entries may match, next is the cursor for the following page.
+func (l *Log) Query(filter Filter) (entries []*Entry, next string, err error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(l.ctx, 2*time.Second)
+	defer cancel()
+
+	// Stream ids start with the millisecond they were appended, so the time
+	// range maps onto an id range
+	end, start := "+", "-"
+	if !filter.Until.IsZero() {
+		end = strconv.FormatInt(filter.Until.UnixMilli(), 10)
+	}
+	if filter.Before != "" {
+		end = "(" + filter.Before
+	}
+	if !filter.Since.IsZero() {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+
+	entries = make([]*Entry, 0)
+	for {
+		messages, err := l.redisClient.GetClient().XRevRangeN(ctx, StreamKey, end, start, scanBatch).Result()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		for _, message := range messages {
+			data, _ := message.Values["data"].(string)
+			var entry Entry
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				continue
+			}
+			entry.ID = message.ID
+			if !filter.Matches(&entry) {
+				continue
+			}
+			entries = append(entries, &entry)
+			if len(entries) == filter.Limit {
+				return entries, entry.ID, nil
+			}
+		}
+
+		if len(messages) < scanBatch {
+			return entries, "", nil
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+}
@@ -0,0 +1,230 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gatekeep/internal/config"
+	redisclient "gatekeep/internal/redis"
+)
+
+func setupTestLog(t *testing.T) (*Log, func()) {
+	cfg := &config.Config{
+		Port:          8080,
+		RedisAddr:     "localhost:6379",
+		RedisPassword: "",
+		TokenSecret:   "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+		AdminAPIKey:   "admin-key",
+		LogLevel:      "info",
+		MetricsPort:   9090,
+	}
+
+	redisClient, err := redisclient.NewClient(cfg)
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+		return nil, nil
+	}
+
+	cleanup := func() {
+		redisClient.GetClient().Del(context.Background(), StreamKey)
+	}
+	cleanup()
+
+	return NewLog(redisClient), cleanup
+}
+
+func TestDiff(t *testing.T) {
+	type settings struct {
+		Enabled bool              `json:"enabled"`
+		MaxSize int               `json:"max_size"`
+		Labels  map[string]string `json:"labels,omitempty"`
+	}
+
+	changes := Diff(
+		settings{Enabled: true, MaxSize: 100, Labels: map[string]string{"tier": "a"}},
+		settings{Enabled: true, MaxSize: 500},
+	)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Field != "labels" || changes[0].After != nil || string(changes[0].Before) != `{"tier":"a"}` {
+		t.Errorf("Unexpected removed field: %+v", changes[0])
+	}
+	if changes[1].Field != "max_size" || string(changes[1].Before) != "100" || string(changes[1].After) != "500" {
+		t.Errorf("Unexpected changed field: %+v", changes[1])
+	}
+
+	if changes := Diff(settings{MaxSize: 1}, &settings{MaxSize: 1}); len(changes) != 0 {
+		t.Errorf("Expected no changes for equal values, got %+v", changes)
+	}
+	if changes := Diff(nil, map[string]int{"count": 1}); len(changes) != 1 || changes[0].Before != nil {
+		t.Errorf("Expected an added field, got %+v", changes)
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	entry := &Entry{Actor: "alice", Action: "ban", EventID: "evt", Target: "device:d1"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"all fields", Filter{EventID: "evt", Action: "ban", Actor: "alice", Target: "device:d1"}, true},
+		{"other event", Filter{EventID: "other"}, false},
+		{"other action", Filter{Action: "unban"}, false},
+		{"other actor", Filter{Actor: "bob"}, false},
+		{"other target", Filter{Target: "device:d2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(entry); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{"empty", Filter{}, false},
+		{"cursor", Filter{Before: "1735689600000-3"}, false},
+		{"negative limit", Filter{Limit: -1}, true},
+		{"limit too large", Filter{Limit: MaxLimit + 1}, true},
+		{"reversed range", Filter{Since: now, Until: now.Add(-time.Hour)}, true},
+		{"malformed cursor", Filter{Before: "+"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("Expected ErrInvalidFilter, got %v", err)
+			}
+			if err == nil && tt.filter.Limit != DefaultLimit {
+				t.Errorf("Expected default limit %d, got %d", DefaultLimit, tt.filter.Limit)
+			}
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	for _, action := range []string{"ban", "unban"} {
+		sink, err := OpenFileSink(path)
+		if err != nil {
+			t.Fatalf("OpenFileSink() failed: %v", err)
+		}
+		if err := sink.Write(Entry{Actor: "alice", Action: action}); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	var actions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Malformed line %q: %v", scanner.Text(), err)
+		}
+		actions = append(actions, entry.Action)
+	}
+	if len(actions) != 2 || actions[0] != "ban" || actions[1] != "unban" {
+		t.Errorf("Expected entries appended in order, got %v", actions)
+	}
+}
+
+// recordingSink collects the entries written to it
+type recordingSink struct {
+	entries []Entry
+}
+
+func (s *recordingSink) Write(entry Entry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestLog_RecordAndQuery(t *testing.T) {
+	auditLog, cleanup := setupTestLog(t)
+	if auditLog == nil {
+		return
+	}
+	defer cleanup()
+
+	sink := &recordingSink{}
+	auditLog.SetSink(sink)
+
+	records := []Entry{
+		{Actor: "alice", Action: "ban", EventID: "evt-1", Target: "device:d1"},
+		{Actor: "bob", Action: "update_config", EventID: "evt-1", Changes: Diff(
+			map[string]int{"max_size": 100}, map[string]int{"max_size": 500},
+		)},
+		{Actor: "alice", Action: "ban", EventID: "evt-2", Target: "ip:203.0.113.7"},
+		{Actor: "alice", Action: "unban", EventID: "evt-1", Target: "device:d1"},
+	}
+	for _, entry := range records {
+		if err := auditLog.Record(entry); err != nil {
+			t.Fatalf("Record() failed: %v", err)
+		}
+	}
+	if len(sink.entries) != len(records) || sink.entries[0].ID == "" {
+		t.Errorf("Expected every entry written to the sink with its id, got %+v", sink.entries)
+	}
+
+	entries, next, err := auditLog.Query(Filter{Actor: "alice"})
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(entries) != 3 || next != "" {
+		t.Fatalf("Expected 3 entries by alice and no cursor, got %d (%q)", len(entries), next)
+	}
+	if entries[0].Action != "unban" || entries[2].Target != "device:d1" {
+		t.Errorf("Expected entries newest first, got %s, %s", entries[0].Action, entries[2].Target)
+	}
+
+	entries, _, _ = auditLog.Query(Filter{EventID: "evt-1", Action: "update_config"})
+	if len(entries) != 1 || len(entries[0].Changes) != 1 || string(entries[0].Changes[0].After) != "500" {
+		t.Errorf("Expected the config change with its diff, got %+v", entries)
+	}
+
+	// Pages follow the cursor to older entries
+	page, next, _ := auditLog.Query(Filter{EventID: "evt-1", Limit: 2})
+	if len(page) != 2 || next == "" {
+		t.Fatalf("Expected a full page and a cursor, got %d (%q)", len(page), next)
+	}
+	page, next, _ = auditLog.Query(Filter{EventID: "evt-1", Limit: 2, Before: next})
+	if len(page) != 1 || page[0].Action != "ban" || next != "" {
+		t.Errorf("Expected the oldest entry on the last page, got %+v (%q)", page, next)
+	}
+
+	if entries, _, _ := auditLog.Query(Filter{Since: time.Now().Add(time.Hour)}); len(entries) != 0 {
+		t.Errorf("Expected no entries in the future, got %d", len(entries))
+	}
+}
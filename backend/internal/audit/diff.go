@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Change records a field whose value an action changed. Before is omitted for
+// fields the action added and After for fields it removed.
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Diff compares the top-level JSON fields of before and after, e.g. two
+// versions of an event config, and returns the changed fields sorted by name.
+// Values that do not encode as JSON objects compare as empty.
+func Diff(before, after interface{}) []Change {
+	beforeFields := fields(before)
+	afterFields := fields(after)
+
+	names := make([]string, 0, len(beforeFields)+len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		beforeValue, afterValue := beforeFields[name], afterFields[name]
+		if bytes.Equal(beforeValue, afterValue) {
+			continue
+		}
+		changes = append(changes, Change{Field: name, Before: beforeValue, After: afterValue})
+	}
+	return changes
+}
+
+// fields encodes value as a JSON object and returns its compacted fields
+func fields(value interface{}) map[string]json.RawMessage {
+	result := map[string]json.RawMessage{}
+	if value == nil {
+		return result
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return result
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return result
+	}
+
+	for name, field := range raw {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, field); err != nil {
+			continue
+		}
+		result[name] = compacted.Bytes()
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sentinel errors returned by verification. Match them with errors.Is.
+var (
+	// ErrFailed is returned when a response is missing or rejected
+	ErrFailed = errors.New("human verification failed")
+	// ErrUnavailable is returned when the provider cannot be reached or
+	// answers unexpectedly
+	ErrUnavailable = errors.New("human verification unavailable")
+)
+
+// Verifier validates the response a client got from a human-verification
+// widget
+type Verifier interface {
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// SiteVerifier validates responses against a siteverify endpoint, the
+// protocol shared by hCaptcha, Cloudflare Turnstile and reCAPTCHA
+type SiteVerifier struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+// siteVerifyResponse is the body returned by siteverify endpoints
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// NewSiteVerifier creates a verifier posting to the given siteverify endpoint
+func NewSiteVerifier(endpoint, secret string) *SiteVerifier {
+	return &SiteVerifier{
+		endpoint: endpoint,
+		secret:   secret,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Verify checks a response with the provider
+func (v *SiteVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return fmt.Errorf("%w: response is required", ErrFailed)
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {response}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: provider returned status %d", ErrUnavailable, resp.StatusCode)
+	}
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: invalid provider response: %v", ErrUnavailable, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFakeProvider starts a siteverify endpoint accepting the response "pass"
+func newFakeProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("secret") != "test-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("response") {
+		case "pass":
+			_, _ = w.Write([]byte(`{"success":true,"hostname":"example.com"}`))
+		case "outage":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "garbled":
+			_, _ = w.Write([]byte(`<html>`))
+		default:
+			_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSiteVerifier_Verify(t *testing.T) {
+	provider := newFakeProvider(t)
+	verifier := NewSiteVerifier(provider.URL, "test-secret")
+
+	tests := []struct {
+		name     string
+		response string
+		wantErr  error
+	}{
+		{"pass", "pass", nil},
+		{"missing", "", ErrFailed},
+		{"rejected", "bot", ErrFailed},
+		{"provider error", "outage", ErrUnavailable},
+		{"invalid provider response", "garbled", ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(context.Background(), tt.response, "203.0.113.7")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSiteVerifier_Unreachable(t *testing.T) {
+	provider := newFakeProvider(t)
+	provider.Close()
+
+	verifier := NewSiteVerifier(provider.URL, "test-secret")
+	if err := verifier.Verify(context.Background(), "pass", ""); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestSiteVerifier_WrongSecret(t *testing.T) {
+	provider := newFakeProvider(t)
+
+	verifier := NewSiteVerifier(provider.URL, "other-secret")
+	if err := verifier.Verify(context.Background(), "pass", ""); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	redisclient "gatekeep/internal/redis"
+)
+
+// DefaultCacheWindow is how long a verified device is not challenged again
+const DefaultCacheWindow = 10 * time.Minute
+
+// VerifiedKey returns the Redis key marking a device as verified for an event
+func VerifiedKey(eventID, deviceID string) string {
+	return fmt.Sprintf("captcha:verified:%s:%s", eventID, deviceID)
+}
+
+// Checker enforces human verification on join. Devices that pass are
+// remembered per event for a window, so retries are not challenged again.
+type Checker struct {
+	redisClient *redisclient.Client
+	verifier    Verifier
+	window      time.Duration
+	ctx         context.Context
+}
+
+// NewChecker creates a new checker. Without Redis, every check goes to the
+// verifier.
+func NewChecker(redisClient *redisclient.Client, verifier Verifier, window time.Duration) *Checker {
+	if window <= 0 {
+		window = DefaultCacheWindow
+	}
+	return &Checker{
+		redisClient: redisClient,
+		verifier:    verifier,
+		window:      window,
+		ctx:         context.Background(),
+	}
+}
+
+// Check passes devices verified within the window and otherwise verifies the
+// response
+func (c *Checker) Check(eventID, deviceID, response, remoteIP string) error {
+	key := VerifiedKey(eventID, deviceID)
+	if c.redisClient != nil {
+		ctx, cancel := context.WithTimeout(c.ctx, 2*time.Second)
+		verified, err := c.redisClient.GetClient().Exists(ctx, key).Result()
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to check verification cache: %w", err)
+		}
+		if verified > 0 {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+	if err := c.verifier.Verify(ctx, response, remoteIP); err != nil {
+		return err
+	}
+
+	if c.redisClient == nil {
+		return nil
+	}
+	// The device passed, so a failure to remember that only costs the device
+	// another challenge on retry
+	if err := c.redisClient.GetClient().Set(ctx, key, 1, c.window).Err(); err != nil {
+		slog.Warn("failed to cache human verification", "event_id", eventID, "device_id", deviceID, "error", err)
+	}
+	return nil
+}
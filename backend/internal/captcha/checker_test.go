@@ -0,0 +1,99 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gatekeep/internal/config"
+	redisclient "gatekeep/internal/redis"
+)
+
+// countingVerifier accepts the response "pass" and counts calls
+type countingVerifier struct {
+	calls int
+}
+
+func (v *countingVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	v.calls++
+	if response != "pass" {
+		return ErrFailed
+	}
+	return nil
+}
+
+func setupTestRedis(t *testing.T) (*redisclient.Client, func()) {
+	cfg := &config.Config{
+		Port:          8080,
+		RedisAddr:     "localhost:6379",
+		RedisPassword: "",
+		TokenSecret:   "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+		AdminAPIKey:   "admin-key",
+		LogLevel:      "info",
+		MetricsPort:   9090,
+	}
+
+	redisClient, err := redisclient.NewClient(cfg)
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+		return nil, nil
+	}
+
+	cleanup := func() {
+		ctx := context.Background()
+		client := redisClient.GetClient()
+		iter := client.Scan(ctx, 0, "captcha:*", 100).Iterator()
+		for iter.Next(ctx) {
+			client.Del(ctx, iter.Val())
+		}
+	}
+
+	return redisClient, cleanup
+}
+
+func TestChecker_WithoutRedis(t *testing.T) {
+	verifier := &countingVerifier{}
+	checker := NewChecker(nil, verifier, 0)
+
+	if err := checker.Check("event-1", "device-1", "bot", ""); !errors.Is(err, ErrFailed) {
+		t.Errorf("Expected ErrFailed, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := checker.Check("event-1", "device-1", "pass", ""); err != nil {
+			t.Errorf("Check() failed: %v", err)
+		}
+	}
+	if verifier.calls != 3 {
+		t.Errorf("Expected every check to be verified, got %d calls", verifier.calls)
+	}
+}
+
+func TestChecker_CachesVerifiedDevices(t *testing.T) {
+	redisClient, cleanup := setupTestRedis(t)
+	if redisClient == nil {
+		return
+	}
+	defer cleanup()
+
+	verifier := &countingVerifier{}
+	checker := NewChecker(redisClient, verifier, 0)
+
+	if err := checker.Check("event-1", "device-1", "pass", ""); err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	// Retries without a response pass within the window
+	if err := checker.Check("event-1", "device-1", "", ""); err != nil {
+		t.Errorf("Expected cached verification, got %v", err)
+	}
+	if verifier.calls != 1 {
+		t.Errorf("Expected 1 verification, got %d", verifier.calls)
+	}
+
+	// The cache is per event and device
+	if err := checker.Check("event-2", "device-1", "", ""); !errors.Is(err, ErrFailed) {
+		t.Errorf("Expected ErrFailed for another event, got %v", err)
+	}
+	if err := checker.Check("event-1", "device-2", "", ""); !errors.Is(err, ErrFailed) {
+		t.Errorf("Expected ErrFailed for another device, got %v", err)
+	}
+}
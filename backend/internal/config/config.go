@@ -2,13 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// MaxTokenIssuerLength bounds TOKEN_ISSUER, which every admission token carries
+const MaxTokenIssuerLength = 128
+
 // Config holds all configuration for the application
 type Config struct {
 	Port          int
@@ -18,6 +23,47 @@ type Config struct {
 	AdminAPIKey   string
 	LogLevel      string
 	MetricsPort   int
+	// AdminAPIKeys are named admin keys, keyed by name. Audit entries record
+	// the name of the key a request used; ADMIN_API_KEY is named "admin".
+	AdminAPIKeys map[string]string
+	// RevocationListKey is the read-only credential for GET /revocations.
+	// Empty serves the signed revocation lists without authentication.
+	RevocationListKey string
+	// AdminOnMetricsPort serves the admin API on the metrics listener only,
+	// keeping it off the public port
+	AdminOnMetricsPort bool
+	// WebhookAllowPrivateNetworks permits webhook URLs resolving to loopback,
+	// private and link-local addresses
+	WebhookAllowPrivateNetworks bool
+	// TokenIssuer is the "iss" claim of admission tokens
+	TokenIssuer string
+	// TokenLeeway is the clock skew tolerated when verifying token times
+	TokenLeeway time.Duration
+	// CaptchaVerifyURL is the siteverify endpoint of the human-verification
+	// provider. Empty disables human verification.
+	CaptchaVerifyURL string
+	CaptchaSecret    string
+	// CaptchaCacheWindow is how long a verified device is not challenged again
+	CaptchaCacheWindow time.Duration
+	// AttestationRootsFile is a PEM file of the roots trusted to sign device
+	// attestations. Empty disables attestation checks.
+	AttestationRootsFile string
+	// AttestationAllow and AttestationDeprioritize override the integrity
+	// labels mapped to each verdict. Empty keeps the default policy.
+	AttestationAllow        []string
+	AttestationDeprioritize []string
+	// TrustedProxies lists the networks whose forwarding headers are honored
+	// when resolving the client IP. Empty means only the TCP peer is used.
+	TrustedProxies []*net.IPNet
+	// CloudflareProxies lists the Cloudflare edge networks. CF-Connecting-IP
+	// is only honored from them; they are trusted like TrustedProxies.
+	CloudflareProxies []*net.IPNet
+	// EventStreamMaxLen is roughly how many lifecycle events the Redis stream
+	// retains; 0 disables publishing
+	EventStreamMaxLen int64
+	// AuditLogFile is a file audit entries are appended to as JSON lines, in
+	// addition to the Redis stream. Empty disables the file.
+	AuditLogFile string
 }
 
 // Load loads configuration from environment variables and .env file
@@ -52,12 +98,33 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("TOKEN_SECRET is required")
 	}
 
-	// Load AdminAPIKey (required)
+	// Load TokenIssuer (default: "gatekeep")
+	cfg.TokenIssuer = getEnv("TOKEN_ISSUER", "gatekeep")
+
+	// Load TokenLeeway (default: 30 seconds)
+	leewayStr := getEnv("TOKEN_LEEWAY_SECONDS", "30")
+	leeway, err := strconv.Atoi(leewayStr)
+	if err != nil || leeway < 0 {
+		return nil, fmt.Errorf("invalid TOKEN_LEEWAY_SECONDS value: %s", leewayStr)
+	}
+	cfg.TokenLeeway = time.Duration(leeway) * time.Second
+
+	// Load AdminAPIKeys (optional)
+	adminAPIKeys, err := ParseAdminAPIKeys(getEnv("ADMIN_API_KEYS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.AdminAPIKeys = adminAPIKeys
+
+	// Load AdminAPIKey (required unless named keys are set)
 	cfg.AdminAPIKey = getEnv("ADMIN_API_KEY", "")
-	if cfg.AdminAPIKey == "" {
-		return nil, fmt.Errorf("ADMIN_API_KEY is required")
+	if cfg.AdminAPIKey == "" && len(cfg.AdminAPIKeys) == 0 {
+		return nil, fmt.Errorf("ADMIN_API_KEY is required unless ADMIN_API_KEYS is set")
 	}
 
+	// Load RevocationListKey (optional)
+	cfg.RevocationListKey = getEnv("REVOCATION_LIST_KEY", "")
+
 	// Load LogLevel (default: "info")
 	cfg.LogLevel = strings.ToLower(getEnv("LOG_LEVEL", "info"))
 	validLogLevels := map[string]bool{
@@ -78,9 +145,136 @@ func Load() (*Config, error) {
 	}
 	cfg.MetricsPort = metricsPort
 
+	// Load AdminOnMetricsPort (default: false)
+	adminOnMetricsStr := getEnv("ADMIN_ON_METRICS_PORT", "false")
+	adminOnMetrics, err := strconv.ParseBool(adminOnMetricsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ADMIN_ON_METRICS_PORT value: %s", adminOnMetricsStr)
+	}
+	cfg.AdminOnMetricsPort = adminOnMetrics
+
+	// Load WebhookAllowPrivateNetworks (default: false)
+	webhookPrivateStr := getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false")
+	webhookPrivate, err := strconv.ParseBool(webhookPrivateStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_ALLOW_PRIVATE_NETWORKS value: %s", webhookPrivateStr)
+	}
+	cfg.WebhookAllowPrivateNetworks = webhookPrivate
+
+	// Load human verification settings (optional)
+	cfg.CaptchaVerifyURL = getEnv("CAPTCHA_VERIFY_URL", "")
+	cfg.CaptchaSecret = getEnv("CAPTCHA_SECRET", "")
+	if cfg.CaptchaVerifyURL != "" && cfg.CaptchaSecret == "" {
+		return nil, fmt.Errorf("CAPTCHA_SECRET is required when CAPTCHA_VERIFY_URL is set")
+	}
+	cacheStr := getEnv("CAPTCHA_CACHE_SECONDS", "600")
+	cacheSeconds, err := strconv.Atoi(cacheStr)
+	if err != nil || cacheSeconds < 1 {
+		return nil, fmt.Errorf("invalid CAPTCHA_CACHE_SECONDS value: %s", cacheStr)
+	}
+	cfg.CaptchaCacheWindow = time.Duration(cacheSeconds) * time.Second
+
+	// Load device attestation settings (optional)
+	cfg.AttestationRootsFile = getEnv("ATTESTATION_ROOTS_FILE", "")
+	cfg.AttestationAllow = getEnvList("ATTESTATION_ALLOW_VERDICTS")
+	cfg.AttestationDeprioritize = getEnvList("ATTESTATION_DEPRIORITIZE_VERDICTS")
+
+	// Load lifecycle event stream settings (default: 100000 events)
+	maxLenStr := getEnv("EVENT_STREAM_MAXLEN", "100000")
+	maxLen, err := strconv.ParseInt(maxLenStr, 10, 64)
+	if err != nil || maxLen < 0 {
+		return nil, fmt.Errorf("invalid EVENT_STREAM_MAXLEN value: %s", maxLenStr)
+	}
+	cfg.EventStreamMaxLen = maxLen
+
+	// Load audit log file (optional)
+	cfg.AuditLogFile = getEnv("AUDIT_LOG_FILE", "")
+
+	// Load TrustedProxies (optional, comma-separated IPs or CIDRs)
+	trustedProxies, err := ParseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	// Load CloudflareProxies (optional, comma-separated IPs or CIDRs)
+	cloudflareProxies, err := ParseTrustedProxies(getEnv("CLOUDFLARE_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CLOUDFLARE_PROXIES: %w", err)
+	}
+	cfg.CloudflareProxies = cloudflareProxies
+
 	return cfg, nil
 }
 
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges. Bare addresses are treated as single-host networks.
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network: %s", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %s", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ParseAdminAPIKeys parses a comma-separated list of name:key pairs. Names
+// identify the key holder in the audit log.
+func ParseAdminAPIKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, key, ok := strings.Cut(entry, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !ok || name == "" || key == "" {
+			return nil, fmt.Errorf("invalid ADMIN_API_KEYS entry: must be name:key")
+		}
+		if _, exists := keys[name]; exists {
+			return nil, fmt.Errorf("duplicate ADMIN_API_KEYS name: %s", name)
+		}
+		keys[name] = key
+	}
+	return keys, nil
+}
+
+// AdminCredentials returns every admin key by name, including ADMIN_API_KEY
+// as "admin"
+func (c *Config) AdminCredentials() map[string]string {
+	credentials := make(map[string]string, len(c.AdminAPIKeys)+1)
+	for name, key := range c.AdminAPIKeys {
+		credentials[name] = key
+	}
+	if c.AdminAPIKey != "" {
+		credentials["admin"] = c.AdminAPIKey
+	}
+	return credentials
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -89,6 +283,18 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList gets a comma-separated environment variable as a list, skipping
+// empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Validate performs additional validation on the configuration
 func (c *Config) Validate() error {
 	if c.Port < 1 || c.Port > 65535 {
@@ -107,5 +313,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("TOKEN_SECRET must be at least 32 characters long for security")
 	}
 
+	// The issuer is part of every admission token, which has a size limit
+	if len(c.TokenIssuer) > MaxTokenIssuerLength {
+		return fmt.Errorf("TOKEN_ISSUER must be at most %d bytes", MaxTokenIssuerLength)
+	}
+
+	if c.RevocationListKey != "" && c.RevocationListKey == c.AdminAPIKey {
+		return fmt.Errorf("REVOCATION_LIST_KEY must differ from ADMIN_API_KEY")
+	}
+
+	if _, exists := c.AdminAPIKeys["admin"]; exists && c.AdminAPIKey != "" {
+		return fmt.Errorf("ADMIN_API_KEYS cannot name a key \"admin\" when ADMIN_API_KEY is set")
+	}
+	seen := make(map[string]bool, len(c.AdminAPIKeys)+1)
+	for _, key := range c.AdminCredentials() {
+		if seen[key] {
+			return fmt.Errorf("admin keys must be distinct")
+		}
+		seen[key] = true
+		if key == c.RevocationListKey {
+			return fmt.Errorf("REVOCATION_LIST_KEY must differ from the admin keys")
+		}
+	}
+
 	return nil
 }
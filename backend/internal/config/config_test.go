@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad_ValidConfig(t *testing.T) {
@@ -104,6 +105,30 @@ func TestLoad_DefaultValues(t *testing.T) {
 	if cfg.MetricsPort != 9090 {
 		t.Errorf("Expected default MetricsPort 9090, got %d", cfg.MetricsPort)
 	}
+
+	if cfg.AdminOnMetricsPort {
+		t.Error("Expected the admin API on the public port by default")
+	}
+
+	if cfg.TokenIssuer != "gatekeep" {
+		t.Errorf("Expected default TokenIssuer 'gatekeep', got '%s'", cfg.TokenIssuer)
+	}
+
+	if cfg.TokenLeeway != 30*time.Second {
+		t.Errorf("Expected default TokenLeeway 30s, got %v", cfg.TokenLeeway)
+	}
+
+	if cfg.CaptchaVerifyURL != "" {
+		t.Errorf("Expected human verification disabled by default, got '%s'", cfg.CaptchaVerifyURL)
+	}
+
+	if cfg.CaptchaCacheWindow != 10*time.Minute {
+		t.Errorf("Expected default CaptchaCacheWindow 10m, got %v", cfg.CaptchaCacheWindow)
+	}
+
+	if cfg.AttestationRootsFile != "" || cfg.AttestationAllow != nil {
+		t.Errorf("Expected device attestation disabled by default, got '%s'", cfg.AttestationRootsFile)
+	}
 }
 
 func TestLoad_MissingRequiredFields(t *testing.T) {
@@ -130,7 +155,7 @@ func TestLoad_MissingRequiredFields(t *testing.T) {
 				"REDIS_ADDR":   "localhost:6379",
 				"TOKEN_SECRET": "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
 			},
-			wantErr: "ADMIN_API_KEY is required",
+			wantErr: "ADMIN_API_KEY is required unless ADMIN_API_KEYS is set",
 		},
 	}
 
@@ -187,6 +212,16 @@ func TestLoad_InvalidConfiguration(t *testing.T) {
 			},
 			wantErr: "invalid METRICS_PORT value",
 		},
+		{
+			name: "invalid ADMIN_ON_METRICS_PORT",
+			envVars: map[string]string{
+				"REDIS_ADDR":            "localhost:6379",
+				"TOKEN_SECRET":          "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				"ADMIN_API_KEY":         "admin-key-123",
+				"ADMIN_ON_METRICS_PORT": "sometimes",
+			},
+			wantErr: "invalid ADMIN_ON_METRICS_PORT value",
+		},
 		{
 			name: "invalid LOG_LEVEL",
 			envVars: map[string]string{
@@ -198,12 +233,43 @@ func TestLoad_InvalidConfiguration(t *testing.T) {
 			},
 			wantErr: "invalid LOG_LEVEL",
 		},
+		{
+			name: "negative TOKEN_LEEWAY_SECONDS",
+			envVars: map[string]string{
+				"REDIS_ADDR":           "localhost:6379",
+				"TOKEN_SECRET":         "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				"ADMIN_API_KEY":        "admin-key-123",
+				"TOKEN_LEEWAY_SECONDS": "-5",
+			},
+			wantErr: "invalid TOKEN_LEEWAY_SECONDS value",
+		},
+		{
+			name: "CAPTCHA_VERIFY_URL without secret",
+			envVars: map[string]string{
+				"REDIS_ADDR":         "localhost:6379",
+				"TOKEN_SECRET":       "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				"ADMIN_API_KEY":      "admin-key-123",
+				"CAPTCHA_VERIFY_URL": "https://hcaptcha.com/siteverify",
+			},
+			wantErr: "CAPTCHA_SECRET is required",
+		},
+		{
+			name: "invalid CAPTCHA_CACHE_SECONDS",
+			envVars: map[string]string{
+				"REDIS_ADDR":            "localhost:6379",
+				"TOKEN_SECRET":          "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				"ADMIN_API_KEY":         "admin-key-123",
+				"CAPTCHA_CACHE_SECONDS": "0",
+			},
+			wantErr: "invalid CAPTCHA_CACHE_SECONDS value",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Clean up environment
 			os.Clearenv()
+			defer os.Clearenv()
 
 			// Set test environment variables
 			for k, v := range tt.envVars {
@@ -281,6 +347,58 @@ func TestValidate_InvalidValues(t *testing.T) {
 			},
 			wantErr: "PORT and METRICS_PORT cannot be the same",
 		},
+		{
+			name: "TOKEN_ISSUER too long",
+			cfg: &Config{
+				Port:        8080,
+				RedisAddr:   "localhost:6379",
+				TokenSecret: "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				TokenIssuer: strings.Repeat("i", MaxTokenIssuerLength+1),
+				AdminAPIKey: "admin-key",
+				LogLevel:    "info",
+				MetricsPort: 9090,
+			},
+			wantErr: "TOKEN_ISSUER must be at most",
+		},
+		{
+			name: "REVOCATION_LIST_KEY same as ADMIN_API_KEY",
+			cfg: &Config{
+				Port:              8080,
+				RedisAddr:         "localhost:6379",
+				TokenSecret:       "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				AdminAPIKey:       "admin-key",
+				RevocationListKey: "admin-key",
+				LogLevel:          "info",
+				MetricsPort:       9090,
+			},
+			wantErr: "REVOCATION_LIST_KEY must differ from ADMIN_API_KEY",
+		},
+		{
+			name: "ADMIN_API_KEYS reuses ADMIN_API_KEY",
+			cfg: &Config{
+				Port:         8080,
+				RedisAddr:    "localhost:6379",
+				TokenSecret:  "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				AdminAPIKey:  "admin-key",
+				AdminAPIKeys: map[string]string{"alice": "admin-key"},
+				LogLevel:     "info",
+				MetricsPort:  9090,
+			},
+			wantErr: "admin keys must be distinct",
+		},
+		{
+			name: "ADMIN_API_KEYS names a key admin",
+			cfg: &Config{
+				Port:         8080,
+				RedisAddr:    "localhost:6379",
+				TokenSecret:  "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+				AdminAPIKey:  "admin-key",
+				AdminAPIKeys: map[string]string{"admin": "other-key"},
+				LogLevel:     "info",
+				MetricsPort:  9090,
+			},
+			wantErr: `cannot name a key "admin"`,
+		},
 		{
 			name: "TOKEN_SECRET too short",
 			cfg: &Config{
@@ -402,3 +520,189 @@ func TestLoad_EnvVarTakesPrecedence(t *testing.T) {
 		t.Errorf("Expected Port 9999 from environment variable, got %d", cfg.Port)
 	}
 }
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10 ,2001:db8::/32,::1")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() failed: %v", err)
+	}
+
+	expected := []string{"10.0.0.0/8", "192.168.1.10/32", "2001:db8::/32", "::1/128"}
+	if len(networks) != len(expected) {
+		t.Fatalf("Expected %d networks, got %d", len(expected), len(networks))
+	}
+	for i, network := range networks {
+		if network.String() != expected[i] {
+			t.Errorf("Network #%d: expected %s, got %s", i, expected[i], network.String())
+		}
+	}
+
+	networks, err = ParseTrustedProxies("")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies(\"\") failed: %v", err)
+	}
+	if len(networks) != 0 {
+		t.Errorf("Expected no networks for empty value, got %d", len(networks))
+	}
+}
+
+func TestParseTrustedProxies_Invalid(t *testing.T) {
+	for _, value := range []string{"not-an-ip", "10.0.0.0/33", "10.0.0.1,bogus/8"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ParseTrustedProxies(value)
+			if err == nil {
+				t.Fatalf("ParseTrustedProxies(%q) expected error, got nil", value)
+			}
+			if !strings.Contains(err.Error(), "invalid network") {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	os.Setenv("REDIS_ADDR", "localhost:6379")
+	os.Setenv("TOKEN_SECRET", "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	os.Setenv("ADMIN_API_KEY", "admin-key-123")
+	os.Setenv("TRUSTED_PROXIES", "173.245.48.0/20,10.1.2.3")
+	os.Setenv("CLOUDFLARE_PROXIES", "103.21.244.0/22")
+
+	defer func() {
+		os.Unsetenv("REDIS_ADDR")
+		os.Unsetenv("TOKEN_SECRET")
+		os.Unsetenv("ADMIN_API_KEY")
+		os.Unsetenv("TRUSTED_PROXIES")
+		os.Unsetenv("CLOUDFLARE_PROXIES")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if len(cfg.TrustedProxies) != 2 {
+		t.Fatalf("Expected 2 trusted proxies, got %d", len(cfg.TrustedProxies))
+	}
+	if len(cfg.CloudflareProxies) != 1 {
+		t.Fatalf("Expected 1 Cloudflare network, got %d", len(cfg.CloudflareProxies))
+	}
+
+	os.Setenv("CLOUDFLARE_PROXIES", "garbage")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "CLOUDFLARE_PROXIES") {
+		t.Errorf("Load() expected error for invalid CLOUDFLARE_PROXIES, got %v", err)
+	}
+
+	os.Setenv("CLOUDFLARE_PROXIES", "")
+	os.Setenv("TRUSTED_PROXIES", "garbage")
+	if _, err := Load(); err == nil {
+		t.Error("Load() expected error for invalid TRUSTED_PROXIES, got nil")
+	}
+}
+
+func TestLoad_AdminAPIKeys(t *testing.T) {
+	os.Setenv("REDIS_ADDR", "localhost:6379")
+	os.Setenv("TOKEN_SECRET", "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	os.Setenv("ADMIN_API_KEYS", "alice:key-alice, bob:key:bob")
+
+	defer func() {
+		os.Unsetenv("REDIS_ADDR")
+		os.Unsetenv("TOKEN_SECRET")
+		os.Unsetenv("ADMIN_API_KEYS")
+	}()
+
+	// Named keys replace ADMIN_API_KEY
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	credentials := cfg.AdminCredentials()
+	if len(credentials) != 2 || credentials["alice"] != "key-alice" || credentials["bob"] != "key:bob" {
+		t.Errorf("Unexpected admin credentials: %v", credentials)
+	}
+
+	for _, value := range []string{"alice", "alice:", ":key", "alice:a,alice:b"} {
+		os.Setenv("ADMIN_API_KEYS", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() expected error for ADMIN_API_KEYS %q, got nil", value)
+		}
+	}
+}
+
+func TestLoad_AttestationVerdicts(t *testing.T) {
+	os.Setenv("REDIS_ADDR", "localhost:6379")
+	os.Setenv("TOKEN_SECRET", "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	os.Setenv("ADMIN_API_KEY", "admin-key-123")
+	os.Setenv("ATTESTATION_ROOTS_FILE", "/etc/gatekeep/attestation-roots.pem")
+	os.Setenv("ATTESTATION_ALLOW_VERDICTS", "MEETS_STRONG_INTEGRITY, ,MEETS_DEVICE_INTEGRITY")
+
+	defer func() {
+		os.Unsetenv("REDIS_ADDR")
+		os.Unsetenv("TOKEN_SECRET")
+		os.Unsetenv("ADMIN_API_KEY")
+		os.Unsetenv("ATTESTATION_ROOTS_FILE")
+		os.Unsetenv("ATTESTATION_ALLOW_VERDICTS")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.AttestationRootsFile != "/etc/gatekeep/attestation-roots.pem" {
+		t.Errorf("Expected AttestationRootsFile to be set, got '%s'", cfg.AttestationRootsFile)
+	}
+	expected := []string{"MEETS_STRONG_INTEGRITY", "MEETS_DEVICE_INTEGRITY"}
+	if len(cfg.AttestationAllow) != len(expected) {
+		t.Fatalf("Expected %d allowed verdicts, got %v", len(expected), cfg.AttestationAllow)
+	}
+	for i, verdict := range cfg.AttestationAllow {
+		if verdict != expected[i] {
+			t.Errorf("Verdict #%d: expected %s, got %s", i, expected[i], verdict)
+		}
+	}
+	if cfg.AttestationDeprioritize != nil {
+		t.Errorf("Expected no deprioritized verdicts, got %v", cfg.AttestationDeprioritize)
+	}
+}
+
+func TestLoad_EventStreamMaxLen(t *testing.T) {
+	os.Setenv("REDIS_ADDR", "localhost:6379")
+	os.Setenv("TOKEN_SECRET", "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	os.Setenv("ADMIN_API_KEY", "admin-key-123")
+
+	defer func() {
+		os.Unsetenv("REDIS_ADDR")
+		os.Unsetenv("TOKEN_SECRET")
+		os.Unsetenv("ADMIN_API_KEY")
+		os.Unsetenv("EVENT_STREAM_MAXLEN")
+	}()
+
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 100000, false},
+		{"5000", 5000, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"many", 0, true},
+	}
+
+	for _, tt := range tests {
+		os.Setenv("EVENT_STREAM_MAXLEN", tt.value)
+		cfg, err := Load()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("EVENT_STREAM_MAXLEN=%q: expected error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("EVENT_STREAM_MAXLEN=%q: Load() failed: %v", tt.value, err)
+		}
+		if cfg.EventStreamMaxLen != tt.want {
+			t.Errorf("EVENT_STREAM_MAXLEN=%q: expected %d, got %d", tt.value, tt.want, cfg.EventStreamMaxLen)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package lifecycle publishes queue entry lifecycle events for analytics and
+// data pipelines.
+//
+// Delivery is at-least-once only from the stream on: consumer groups keep
+// each appended event pending until it is acknowledged. Events can be lost
+// before that, when Emit cannot append them (they are logged and dropped, so
+// the queue never waits on the stream), and after it, when MAXLEN trimming
+// evicts events a lagging consumer group has not read yet.
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published over a queue entry's lifecycle
+const (
+	Joined        = "joined"
+	HeartbeatLost = "heartbeat_lost"
+	Admitted      = "admitted"
+	TokenIssued   = "token_issued"
+	Revoked       = "revoked"
+	Left          = "left"
+)
+
+// publishTimeout bounds how long Emit waits for a publisher
+const publishTimeout = 2 * time.Second
+
+// Event is a queue lifecycle event. Events carry token hashes, never tokens.
+type Event struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	EventID    string            `json:"event_id"`
+	QueueID    string            `json:"queue_id,omitempty"`
+	DeviceID   string            `json:"device_id,omitempty"`
+	UserID     string            `json:"user_id,omitempty"`
+	TokenHash  string            `json:"token_hash,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// Publisher publishes lifecycle events
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Emit fills in the event's id and time and publishes it. Publishing never
+// fails the caller: errors are logged and the event is dropped. A nil
+// publisher ignores events.
+func Emit(publisher Publisher, event Event) {
+	if publisher == nil {
+		return
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	if err := publisher.Publish(ctx, event); err != nil {
+		slog.Error("failed to publish lifecycle event", "type", event.Type, "event_id", event.EventID, "queue_id", event.QueueID, "error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordingPublisher records published events and fails while err is set
+type recordingPublisher struct {
+	mu     sync.Mutex
+	err    error
+	events []Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestEmit(t *testing.T) {
+	// A nil publisher ignores events
+	Emit(nil, Event{Type: Joined, EventID: "event-1"})
+
+	publisher := &recordingPublisher{}
+	Emit(publisher, Event{Type: Joined, EventID: "event-1", QueueID: "q-1"})
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(publisher.events))
+	}
+	event := publisher.events[0]
+	if event.ID == "" || event.OccurredAt.IsZero() {
+		t.Errorf("Expected id and time to be filled in, got %+v", event)
+	}
+
+	// Publisher errors do not reach the caller
+	publisher.err = errors.New("redis down")
+	Emit(publisher, Event{Type: Left, EventID: "event-1"})
+	if len(publisher.events) != 1 {
+		t.Errorf("Expected the failed event not to be recorded, got %d events", len(publisher.events))
+	}
+}
@@ -0,0 +1,196 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	redisclient "gatekeep/internal/redis"
+)
+
+const (
+	// StreamKey is the Redis stream lifecycle events are appended to
+	StreamKey = "lifecycle:events"
+	// DefaultMaxLen is roughly how many events the stream retains. Older
+	// events are trimmed whether or not every consumer group has read them.
+	DefaultMaxLen = 100000
+
+	// DefaultMinIdle is how long a delivered event may stay unacknowledged
+	// before another consumer of the group claims it
+	DefaultMinIdle = 30 * time.Second
+	defaultBatch   = 100
+	defaultBlock   = 5 * time.Second
+)
+
+// StreamPublisher appends events to a Redis stream, trimmed to about maxLen
+// entries
+type StreamPublisher struct {
+	redisClient *redisclient.Client
+	maxLen      int64
+}
+
+// NewStreamPublisher creates a publisher for StreamKey
+func NewStreamPublisher(redisClient *redisclient.Client, maxLen int64) *StreamPublisher {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+	return &StreamPublisher{redisClient: redisClient, maxLen: maxLen}
+}
+
+// Publish appends the event to the stream
+func (p *StreamPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	err = p.redisClient.GetClient().XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":     event.Type,
+			"event_id": event.EventID,
+			"data":     data,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	return nil
+}
+
+// Handler processes a delivered event. Returning an error leaves the event
+// pending, so it is delivered again.
+type Handler func(ctx context.Context, event Event) error
+
+// Consumer reads the stream as a member of a consumer group. Each event is
+// delivered to one consumer of the group and acknowledged once its handler
+// succeeds; events left unacknowledged for MinIdle, e.g. by a crashed
+// consumer, are claimed and redelivered, so delivery is at least once.
+type Consumer struct {
+	redisClient *redisclient.Client
+	group       string
+	name        string
+
+	// MinIdle is how long an unacknowledged event waits before it is claimed
+	MinIdle time.Duration
+	// Batch is the most events read per call
+	Batch int64
+	// Block is how long a read waits for new events
+	Block time.Duration
+}
+
+// NewConsumer creates a consumer named name in the group
+func NewConsumer(redisClient *redisclient.Client, group, name string) *Consumer {
+	return &Consumer{
+		redisClient: redisClient,
+		group:       group,
+		name:        name,
+		MinIdle:     DefaultMinIdle,
+		Batch:       defaultBatch,
+		Block:       defaultBlock,
+	}
+}
+
+// EnsureGroup creates the consumer group if it does not exist. A new group
+// starts at the oldest event the stream retains.
+func (c *Consumer) EnsureGroup(ctx context.Context) error {
+	err := c.redisClient.GetClient().XGroupCreateMkStream(ctx, StreamKey, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	return nil
+}
+
+// Run creates the group and handles events until ctx is done
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	if err := c.EnsureGroup(ctx); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		if _, err := c.Poll(ctx, handler); err != nil && ctx.Err() == nil {
+			slog.Error("lifecycle consumer failed", "group", c.group, "consumer", c.name, "error", err)
+			time.Sleep(time.Second)
+		}
+	}
+	return nil
+}
+
+// Poll claims events other consumers left unacknowledged, then reads new
+// events, handles them and acknowledges the ones handled. It returns how many
+// were acknowledged.
+func (c *Consumer) Poll(ctx context.Context, handler Handler) (int, error) {
+	client := c.redisClient.GetClient()
+
+	claimed, _, err := client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   StreamKey,
+		Group:    c.group,
+		Consumer: c.name,
+		MinIdle:  c.MinIdle,
+		Start:    "0-0",
+		Count:    c.Batch,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+	acked, err := c.handle(ctx, claimed, handler)
+	if err != nil || len(claimed) > 0 {
+		return acked, err
+	}
+
+	streams, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  []string{StreamKey, ">"},
+		Count:    c.Batch,
+		Block:    c.Block,
+	}).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	for _, stream := range streams {
+		n, err := c.handle(ctx, stream.Messages, handler)
+		acked += n
+		if err != nil {
+			return acked, err
+		}
+	}
+	return acked, nil
+}
+
+// handle runs the handler on each message and acknowledges the successes.
+// Messages that cannot be decoded are acknowledged and dropped.
+func (c *Consumer) handle(ctx context.Context, messages []redis.XMessage, handler Handler) (int, error) {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		var event Event
+		data, _ := message.Values["data"].(string)
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			slog.Warn("dropping malformed lifecycle event", "message_id", message.ID, "error", err)
+			ids = append(ids, message.ID)
+			continue
+		}
+		if err := handler(ctx, event); err != nil {
+			continue
+		}
+		ids = append(ids, message.ID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if err := c.redisClient.GetClient().XAck(ctx, StreamKey, c.group, ids...).Err(); err != nil {
+		return 0, fmt.Errorf("failed to acknowledge events: %w", err)
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,107 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gatekeep/internal/config"
+	redisclient "gatekeep/internal/redis"
+)
+
+func setupTestPublisher(t *testing.T) (*StreamPublisher, func()) {
+	cfg := &config.Config{
+		Port:          8080,
+		RedisAddr:     "localhost:6379",
+		RedisPassword: "",
+		TokenSecret:   "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+		AdminAPIKey:   "admin-key",
+		LogLevel:      "info",
+		MetricsPort:   9090,
+	}
+
+	redisClient, err := redisclient.NewClient(cfg)
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+		return nil, nil
+	}
+
+	cleanup := func() {
+		redisClient.GetClient().Del(context.Background(), StreamKey)
+	}
+	cleanup()
+
+	return NewStreamPublisher(redisClient, 1000), cleanup
+}
+
+// newTestConsumer creates a consumer that does not block waiting for events
+func newTestConsumer(publisher *StreamPublisher, group, name string) *Consumer {
+	consumer := NewConsumer(publisher.redisClient, group, name)
+	consumer.Block = 10 * time.Millisecond
+	return consumer
+}
+
+func TestConsumer_AtLeastOnce(t *testing.T) {
+	publisher, cleanup := setupTestPublisher(t)
+	if publisher == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	first := newTestConsumer(publisher, "analytics", "consumer-1")
+	if err := first.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() failed: %v", err)
+	}
+	if err := first.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() failed for an existing group: %v", err)
+	}
+
+	Emit(publisher, Event{Type: Joined, EventID: "event-1", QueueID: "q-1"})
+	Emit(publisher, Event{Type: Admitted, EventID: "event-1", QueueID: "q-1"})
+
+	// The first consumer crashes while handling the events
+	acked, err := first.Poll(ctx, func(ctx context.Context, event Event) error {
+		return errors.New("crashed")
+	})
+	if err != nil {
+		t.Fatalf("Poll() failed: %v", err)
+	}
+	if acked != 0 {
+		t.Errorf("Expected no acknowledged events, got %d", acked)
+	}
+
+	// Another consumer of the group claims and handles them
+	second := newTestConsumer(publisher, "analytics", "consumer-2")
+	second.MinIdle = 0
+	var handled []Event
+	acked, err = second.Poll(ctx, func(ctx context.Context, event Event) error {
+		handled = append(handled, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Poll() failed: %v", err)
+	}
+	if acked != 2 || len(handled) != 2 {
+		t.Fatalf("Expected 2 redelivered events, got %d", len(handled))
+	}
+	if handled[0].Type != Joined || handled[1].Type != Admitted {
+		t.Errorf("Expected events in order, got %s, %s", handled[0].Type, handled[1].Type)
+	}
+
+	// Acknowledged events are not delivered again
+	acked, _ = second.Poll(ctx, func(ctx context.Context, event Event) error { return nil })
+	if acked != 0 {
+		t.Errorf("Expected no events after acknowledgement, got %d", acked)
+	}
+
+	// Other groups receive every event independently
+	other := newTestConsumer(publisher, "billing", "consumer-1")
+	if err := other.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup() failed: %v", err)
+	}
+	if acked, _ := other.Poll(ctx, func(ctx context.Context, event Event) error { return nil }); acked != 2 {
+		t.Errorf("Expected 2 events for another group, got %d", acked)
+	}
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ParseLevel parses a LOG_LEVEL value: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %s", level)
+}
+
+// New creates a logger writing JSON records at or above level to w. Records
+// carry the attributes annotated on their context, and secrets are redacted.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Setup makes a JSON logger writing to stderr at the named level the default,
+// both for slog and for the standard log package
+func Setup(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(New(os.Stderr, parsed))
+	return nil
+}
+
+// contextKey keys the annotations stored in a context
+type contextKey struct{}
+
+// annotations are the attributes added to a context's records. They are
+// shared by every context derived from the one that created them, so
+// attributes annotated deep in a call are seen by callers up the stack, e.g.
+// the request log.
+type annotations struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// NewContext returns a context that collects annotations
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &annotations{})
+}
+
+// Annotate adds attributes to every record logged with ctx. Contexts not
+// created by NewContext are left unchanged.
+func Annotate(ctx context.Context, attrs ...slog.Attr) {
+	a, ok := ctx.Value(contextKey{}).(*annotations)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attrs = append(a.attrs, attrs...)
+}
+
+// Attrs returns the attributes annotated on ctx
+func Attrs(ctx context.Context) []slog.Attr {
+	a, ok := ctx.Value(contextKey{}).(*annotations)
+	if !ok {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]slog.Attr(nil), a.attrs...)
+}
+
+// contextHandler adds a context's annotations to its records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		record.AddAttrs(Attrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
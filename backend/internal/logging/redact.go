@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in log records
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted
+var sensitiveKeys = map[string]bool{
+	"token":         true,
+	"secret":        true,
+	"password":      true,
+	"authorization": true,
+	"api_key":       true,
+	"x-api-key":     true,
+	"cookie":        true,
+}
+
+// sensitiveSuffixes redact keys such as "refresh_token" or "captcha_secret"
+var sensitiveSuffixes = []string{"_token", "_secret", "_password"}
+
+// secretPattern matches secrets embedded in free text: admission tokens
+// (base64url header.payload.signature), webhook signing secrets and bearer
+// credentials
+var secretPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*|whsec_[A-Za-z0-9]+|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{16,}`)
+
+// IsSensitiveKey reports whether values logged under key are redacted.
+// Token hashes identify tokens without granting access, so they are kept.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "token_hash" {
+		return false
+	}
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces the secrets embedded in s
+func Redact(s string) string {
+	return secretPattern.ReplaceAllString(s, Redacted)
+}
+
+// redactAttr is the ReplaceAttr hook of the JSON handler
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
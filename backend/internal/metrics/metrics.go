@@ -80,4 +80,39 @@ var (
 		},
 		[]string{"event_id"},
 	)
+
+	// QueueEvictions tracks entries leaving the queue without being admitted
+	QueueEvictions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gatekeep_queue_evictions_total",
+			Help: "Total number of queue entries evicted without admission",
+		},
+		[]string{"event_id", "reason"}, // "expired", "banned", "removed" or "left"
+	)
+
+	// QueueRejections tracks rejected queue joins
+	QueueRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gatekeep_queue_rejections_total",
+			Help: "Total number of rejected queue joins",
+		},
+		[]string{"event_id", "reason"}, // the API error code
+	)
+
+	// TokenVerifications tracks admission token verifications
+	TokenVerifications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gatekeep_token_verifications_total",
+			Help: "Total number of admission token verifications",
+		},
+		[]string{"result"},
+	)
+)
+
+// Eviction reasons
+const (
+	EvictionExpired = "expired"
+	EvictionBanned  = "banned"
+	EvictionRemoved = "removed"
+	EvictionLeft    = "left"
 )
@@ -0,0 +1,269 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultPageLimit is the page size used when none is requested
+	DefaultPageLimit = 50
+	// MaxPageLimit is the largest page size admin listings return
+	MaxPageLimit = 500
+)
+
+// EventSummary summarizes an event's queue for operators
+type EventSummary struct {
+	EventID     string         `json:"event_id"`
+	Enabled     bool           `json:"enabled"`
+	MaxSize     int            `json:"max_size"`
+	ReleaseRate int            `json:"release_rate"`
+	Waiting     map[string]int `json:"waiting"` // entries per bucket
+	Admitted    int            `json:"admitted"`
+}
+
+// EntryInfo is a queue entry with its live position and status
+type EntryInfo struct {
+	QueueEntry
+	Status string `json:"status"`
+}
+
+// Page describes a slice of a larger listing
+type Page struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+	Total  int `json:"total"`
+}
+
+// NormalizePage clamps offset and limit to valid values
+func NormalizePage(offset, limit int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return offset, limit
+}
+
+// ListEvents returns a page of known events, sorted by event ID
+func (m *Manager) ListEvents(offset, limit int) ([]*EventSummary, Page, error) {
+	offset, limit = NormalizePage(offset, limit)
+
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	eventIDs, err := m.redisClient.GetClient().SMembers(ctx, QueueEventIndexKey()).Result()
+	if err != nil {
+		return nil, Page{}, fmt.Errorf("failed to list events: %w", err)
+	}
+	sort.Strings(eventIDs)
+
+	page := Page{Offset: offset, Limit: limit, Total: len(eventIDs)}
+	if offset >= len(eventIDs) {
+		return []*EventSummary{}, page, nil
+	}
+	eventIDs = eventIDs[offset:min(offset+limit, len(eventIDs))]
+
+	// Fetch counts for the whole page in one round trip
+	pipe := m.redisClient.GetClient().Pipeline()
+	type counts struct{ high, normal, deprioritized, review, shadow, admitted *redis.IntCmd }
+	cmds := make([]counts, len(eventIDs))
+	for i, eventID := range eventIDs {
+		cmds[i] = counts{
+			high:          pipe.ZCard(ctx, QueueSortedSetKey(eventID)),
+			normal:        pipe.LLen(ctx, QueueListKey(eventID)),
+			deprioritized: pipe.LLen(ctx, QueueDeprioritizedKey(eventID)),
+			review:        pipe.LLen(ctx, QueueReviewKey(eventID)),
+			shadow:        pipe.LLen(ctx, QueueShadowKey(eventID)),
+			admitted:      pipe.SCard(ctx, QueueAdmittedKey(eventID)),
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, Page{}, fmt.Errorf("failed to count queue entries: %w", err)
+	}
+
+	summaries := make([]*EventSummary, 0, len(eventIDs))
+	for i, eventID := range eventIDs {
+		config, err := m.GetEventConfig(eventID)
+		if err != nil {
+			return nil, Page{}, fmt.Errorf("failed to get event config: %w", err)
+		}
+		summaries = append(summaries, &EventSummary{
+			EventID:     eventID,
+			Enabled:     config.Enabled,
+			MaxSize:     config.MaxSize,
+			ReleaseRate: config.ReleaseRate,
+			Waiting: map[string]int{
+				"high":              int(cmds[i].high.Val()),
+				"normal":            int(cmds[i].normal.Val()),
+				DeprioritizedBucket: int(cmds[i].deprioritized.Val()),
+				ReviewBucket:        int(cmds[i].review.Val()),
+				ShadowBucket:        int(cmds[i].shadow.Val()),
+			},
+			Admitted: int(cmds[i].admitted.Val()),
+		})
+	}
+
+	return summaries, page, nil
+}
+
+// ListEntries returns a page of waiting entries in an event's bucket, in
+// release order. "high" lists the priority queue, "deprioritized" the
+// entries held back by attestation and "review" and "shadow" the entries
+// held by risk scoring; any other bucket lists the FIFO queue shared by all
+// non-priority buckets.
+func (m *Manager) ListEntries(eventID, bucket string, offset, limit int) ([]*QueueEntry, Page, error) {
+	if eventID == "" {
+		return nil, Page{}, fmt.Errorf("event_id is required")
+	}
+	offset, limit = NormalizePage(offset, limit)
+
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	client := m.redisClient.GetClient()
+	start, stop := int64(offset), int64(offset+limit-1)
+
+	var queueIDs []string
+	var total int64
+	var err error
+	if bucket == "high" {
+		key := QueueSortedSetKey(eventID)
+		if total, err = client.ZCard(ctx, key).Result(); err == nil {
+			queueIDs, err = client.ZRange(ctx, key, start, stop).Result()
+		}
+	} else {
+		key := bucketListKey(eventID, bucket)
+		if total, err = client.LLen(ctx, key).Result(); err == nil {
+			queueIDs, err = client.LRange(ctx, key, start, stop).Result()
+		}
+	}
+	if err != nil {
+		return nil, Page{}, fmt.Errorf("failed to list queue entries: %w", err)
+	}
+
+	page := Page{Offset: offset, Limit: limit, Total: int(total)}
+	entries, err := m.loadEntries(ctx, queueIDs)
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	// Positions are implied by the page offset
+	listed := make([]*QueueEntry, 0, len(entries))
+	for i, queueID := range queueIDs {
+		entry, ok := entries[queueID]
+		if !ok {
+			// Entry data expired while still queued
+			continue
+		}
+		entry.Position = offset + i + 1
+		listed = append(listed, entry)
+	}
+
+	return listed, page, nil
+}
+
+// FindEntries looks up queue entries by queue_id, or by device_id or user_id
+// within an event. Exactly one identifier is used, in that order.
+func (m *Manager) FindEntries(eventID, queueID, deviceID, userID string) ([]*EntryInfo, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	client := m.redisClient.GetClient()
+
+	var queueIDs []string
+	switch {
+	case queueID != "":
+		queueIDs = []string{queueID}
+	case eventID == "":
+		return nil, fmt.Errorf("event_id is required to look up by device_id or user_id")
+	case deviceID != "":
+		id, err := client.Get(ctx, QueueDeviceEventKey(deviceID, eventID)).Result()
+		if err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to look up device: %w", err)
+		}
+		if id != "" {
+			queueIDs = []string{id}
+		}
+	case userID != "":
+		ids, err := client.SMembers(ctx, QueueUserEventKey(userID, eventID)).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up user: %w", err)
+		}
+		sort.Strings(ids)
+		queueIDs = ids
+	default:
+		return nil, fmt.Errorf("one of queue_id, device_id or user_id is required")
+	}
+
+	entries, err := m.loadEntries(ctx, queueIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Statuses are computed read-only so that looking an entry up neither
+	// extends its TTL nor changes what is being inspected
+	statuses, err := m.GetQueueStatuses(queueIDs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*EntryInfo, 0, len(queueIDs))
+	for _, id := range queueIDs {
+		entry, ok := entries[id]
+		if !ok {
+			continue
+		}
+		status, ok := statuses[id]
+		if !ok {
+			continue
+		}
+		entry.Position = status.Position
+		infos = append(infos, &EntryInfo{QueueEntry: *entry, Status: status.Status})
+	}
+
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("%w: no matching entries", ErrNotFound)
+	}
+	return infos, nil
+}
+
+// loadEntries fetches and deserializes entries in one round trip. Missing
+// entries are omitted from the result.
+func (m *Manager) loadEntries(ctx context.Context, queueIDs []string) (map[string]*QueueEntry, error) {
+	entries := make(map[string]*QueueEntry, len(queueIDs))
+	if len(queueIDs) == 0 {
+		return entries, nil
+	}
+
+	keys := make([]string, len(queueIDs))
+	for i, queueID := range queueIDs {
+		keys[i] = QueueEntryKey(queueID)
+	}
+
+	values, err := m.redisClient.GetClient().MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queue entries: %w", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		entry, err := DeserializeQueueEntry(data)
+		if err != nil {
+			continue
+		}
+		entries[queueIDs[i]] = entry
+	}
+	return entries, nil
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizePage(t *testing.T) {
+	tests := []struct {
+		name          string
+		offset, limit int
+		wantOffset    int
+		wantLimit     int
+	}{
+		{"defaults", 0, 0, 0, DefaultPageLimit},
+		{"negative offset", -5, 10, 0, 10},
+		{"within range", 20, 100, 20, 100},
+		{"limit capped", 0, MaxPageLimit + 1, 0, MaxPageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, limit := NormalizePage(tt.offset, tt.limit)
+			if offset != tt.wantOffset || limit != tt.wantLimit {
+				t.Errorf("NormalizePage(%d, %d) = (%d, %d), expected (%d, %d)",
+					tt.offset, tt.limit, offset, limit, tt.wantOffset, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-admin-list"
+	var queueIDs []string
+	for _, deviceID := range []string{"admin-device-1", "admin-device-2", "admin-device-3"} {
+		entry, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: deviceID})
+		if err != nil {
+			t.Fatalf("JoinQueue() failed: %v", err)
+		}
+		queueIDs = append(queueIDs, entry.QueueID)
+	}
+
+	entries, page, err := manager.ListEntries(eventID, "normal", 1, 2)
+	if err != nil {
+		t.Fatalf("ListEntries() failed: %v", err)
+	}
+	if page.Total < 3 {
+		t.Errorf("Expected total >= 3, got %d", page.Total)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Position != 2 || entries[1].Position != 3 {
+		t.Errorf("Expected positions 2 and 3, got %d and %d", entries[0].Position, entries[1].Position)
+	}
+	if entries[0].QueueID != queueIDs[1] {
+		t.Errorf("Expected queue_id %s at position 2, got %s", queueIDs[1], entries[0].QueueID)
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-admin-events"
+	if _, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: "admin-events-device"}); err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	events, _, err := manager.ListEvents(0, MaxPageLimit)
+	if err != nil {
+		t.Fatalf("ListEvents() failed: %v", err)
+	}
+
+	for _, event := range events {
+		if event.EventID == eventID {
+			if event.Waiting["normal"] < 1 {
+				t.Errorf("Expected at least 1 waiting entry, got %d", event.Waiting["normal"])
+			}
+			return
+		}
+	}
+	t.Errorf("Event %s not listed", eventID)
+}
+
+func TestFindEntries(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-admin-find"
+	entry, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:  eventID,
+		DeviceID: "admin-find-device",
+		UserID:   "admin-find-user",
+	})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	// Shorten the TTL to detect lookups that rewrite the entry
+	entryKey := QueueEntryKey(entry.QueueID)
+	manager.redisClient.GetClient().Expire(manager.ctx, entryKey, time.Minute)
+
+	tests := []struct {
+		name                      string
+		queueID, deviceID, userID string
+	}{
+		{"by queue_id", entry.QueueID, "", ""},
+		{"by device_id", "", "admin-find-device", ""},
+		{"by user_id", "", "", "admin-find-user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos, err := manager.FindEntries(eventID, tt.queueID, tt.deviceID, tt.userID)
+			if err != nil {
+				t.Fatalf("FindEntries() failed: %v", err)
+			}
+			if len(infos) != 1 || infos[0].QueueID != entry.QueueID {
+				t.Fatalf("Expected entry %s, got %+v", entry.QueueID, infos)
+			}
+			if infos[0].Status != "waiting" {
+				t.Errorf("Expected status 'waiting', got %s", infos[0].Status)
+			}
+		})
+	}
+
+	if ttl := manager.redisClient.GetClient().TTL(manager.ctx, entryKey).Val(); ttl > time.Minute {
+		t.Errorf("Expected lookups to leave the entry TTL unchanged, got %v", ttl)
+	}
+
+	_, err = manager.FindEntries(eventID, "", "unknown-device", "")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
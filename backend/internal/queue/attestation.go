@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gatekeep/internal/attestation"
+)
+
+// SetAttestationVerifier sets the verifier checking device attestations on join
+func (m *Manager) SetAttestationVerifier(verifier attestation.Verifier) {
+	m.attestationVerifier = verifier
+}
+
+// checkAttestation checks the device attestation of a join. Joins without one
+// are denied if the event requires it, and deprioritized if the event prefers
+// it and a verifier is configured, so that leaving the attestation out does
+// not beat sending a weak one. Invalid attestations are denied.
+func (m *Manager) checkAttestation(config *EventConfig, req JoinQueueRequest) (attestation.Verdict, error) {
+	if req.Attestation == "" {
+		if config.AttestationRequired {
+			return attestation.Deny, fmt.Errorf("%w: attestation is required", ErrAttestationFailed)
+		}
+		if config.AttestationPreferred && m.attestationVerifier != nil {
+			return attestation.Deprioritize, nil
+		}
+		return "", nil
+	}
+	if m.attestationVerifier == nil {
+		if config.AttestationRequired {
+			return attestation.Deny, fmt.Errorf("%w: no verifier is configured", attestation.ErrUnavailable)
+		}
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	verdict, reason, err := m.attestationVerifier.Verify(ctx, attestation.Request{
+		EventID:  req.EventID,
+		DeviceID: req.DeviceID,
+		Token:    req.Attestation,
+	})
+	if errors.Is(err, attestation.ErrInvalid) {
+		return attestation.Deny, fmt.Errorf("%w: %v", ErrAttestationFailed, err)
+	}
+	if err != nil {
+		return attestation.Deny, err
+	}
+	if verdict == attestation.Deny {
+		return attestation.Deny, fmt.Errorf("%w: %s", ErrAttestationFailed, reason)
+	}
+	return verdict, nil
+}
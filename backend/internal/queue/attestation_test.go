@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gatekeep/internal/attestation"
+)
+
+// fakeAttestationVerifier returns a verdict keyed by attestation token
+type fakeAttestationVerifier struct{}
+
+func (fakeAttestationVerifier) Verify(ctx context.Context, req attestation.Request) (attestation.Verdict, string, error) {
+	switch req.Token {
+	case "allow":
+		return attestation.Allow, "MEETS_DEVICE_INTEGRITY", nil
+	case "deprioritize":
+		return attestation.Deprioritize, "MEETS_BASIC_INTEGRITY", nil
+	case "deny":
+		return attestation.Deny, "no integrity verdicts", nil
+	case "down":
+		return attestation.Deny, "", fmt.Errorf("%w: provider timeout", attestation.ErrUnavailable)
+	}
+	return attestation.Deny, "", fmt.Errorf("%w: forged", attestation.ErrInvalid)
+}
+
+func TestCheckAttestation(t *testing.T) {
+	withVerifier := NewManager(nil)
+	withVerifier.SetAttestationVerifier(fakeAttestationVerifier{})
+	withoutVerifier := NewManager(nil)
+
+	optional := &EventConfig{EventID: "event-1"}
+	preferred := &EventConfig{EventID: "event-1", AttestationPreferred: true}
+	required := &EventConfig{EventID: "event-1", AttestationRequired: true}
+
+	tests := []struct {
+		name        string
+		manager     *Manager
+		config      *EventConfig
+		token       string
+		wantVerdict attestation.Verdict
+		wantErr     error
+	}{
+		{"optional and missing", withVerifier, optional, "", "", nil},
+		{"preferred and missing", withVerifier, preferred, "", attestation.Deprioritize, nil},
+		{"no verifier, missing", withoutVerifier, preferred, "", "", nil},
+		{"required and missing", withVerifier, required, "", attestation.Deny, ErrAttestationFailed},
+		{"allowed", withVerifier, required, "allow", attestation.Allow, nil},
+		{"deprioritized", withVerifier, optional, "deprioritize", attestation.Deprioritize, nil},
+		{"denied", withVerifier, optional, "deny", attestation.Deny, ErrAttestationFailed},
+		{"forged", withVerifier, optional, "forged", attestation.Deny, ErrAttestationFailed},
+		{"verifier down", withVerifier, optional, "down", attestation.Deny, attestation.ErrUnavailable},
+		{"no verifier, optional", withoutVerifier, optional, "allow", "", nil},
+		{"no verifier, required", withoutVerifier, required, "allow", attestation.Deny, attestation.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := tt.manager.checkAttestation(tt.config, JoinQueueRequest{
+				EventID:     "event-1",
+				DeviceID:    "device-1",
+				Attestation: tt.token,
+			})
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("checkAttestation() failed: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if verdict != tt.wantVerdict {
+				t.Errorf("Expected verdict %q, got %q", tt.wantVerdict, verdict)
+			}
+		})
+	}
+}
+
+func TestJoinQueue_DeprioritizedAttestation(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+	manager.SetAttestationVerifier(fakeAttestationVerifier{})
+
+	eventID := "test-event-attestation"
+
+	// A deprioritized device queues behind devices that join after it
+	suspect, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:        eventID,
+		DeviceID:       "device-suspect",
+		PriorityBucket: "high",
+		Attestation:    "deprioritize",
+	})
+	if err != nil {
+		t.Fatalf("Deprioritized JoinQueue() failed: %v", err)
+	}
+	if suspect.PriorityBucket != DeprioritizedBucket {
+		t.Errorf("Expected bucket %s, got %s", DeprioritizedBucket, suspect.PriorityBucket)
+	}
+	if suspect.Attestation != string(attestation.Deprioritize) {
+		t.Errorf("Expected attestation %s, got %s", attestation.Deprioritize, suspect.Attestation)
+	}
+
+	trusted, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:     eventID,
+		DeviceID:    "device-trusted",
+		Attestation: "allow",
+	})
+	if err != nil {
+		t.Fatalf("Allowed JoinQueue() failed: %v", err)
+	}
+	if trusted.Position != 1 {
+		t.Errorf("Expected trusted device at position 1, got %d", trusted.Position)
+	}
+
+	status, err := manager.GetQueueStatus(suspect.QueueID)
+	if err != nil {
+		t.Fatalf("GetQueueStatus() failed: %v", err)
+	}
+	if status.Position != 2 {
+		t.Errorf("Expected deprioritized device at position 2, got %d", status.Position)
+	}
+
+	// A denied device does not join at all
+	if _, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:     eventID,
+		DeviceID:    "device-denied",
+		Attestation: "deny",
+	}); !errors.Is(err, ErrAttestationFailed) {
+		t.Errorf("Expected ErrAttestationFailed, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// JoinRateWindow is the window over which an event's join rate is measured
+const JoinRateWindow = 10 * time.Second
+
+// maxChallengeDifficulty mirrors token.MaxChallengeDifficulty
+const maxChallengeDifficulty = 32
+
+// Difficulty returns the proof-of-work difficulty for joining at the given
+// rate, or 0 if the event does not require proof-of-work. Above the target
+// join rate, each doubling of the rate adds one bit, which doubles the work
+// per join.
+func (c *EventConfig) Difficulty(joinRate float64) int {
+	difficulty := c.ChallengeDifficulty
+	if difficulty <= 0 {
+		return 0
+	}
+
+	ceiling := c.ChallengeMaxDifficulty
+	if ceiling <= 0 || ceiling > maxChallengeDifficulty {
+		ceiling = maxChallengeDifficulty
+	}
+	if c.ChallengeTargetJoinRate > 0 && c.ChallengeMaxDifficulty > 0 && joinRate > float64(c.ChallengeTargetJoinRate) {
+		difficulty += int(math.Ceil(math.Log2(joinRate / float64(c.ChallengeTargetJoinRate))))
+	}
+	return min(difficulty, ceiling)
+}
+
+// JoinRate returns an event's joins per second over the last complete
+// JoinRateWindow
+func (m *Manager) JoinRate(eventID string) (float64, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	windowSeconds := int64(JoinRateWindow / time.Second)
+	previous := time.Now().Unix()/windowSeconds - 1
+	count, err := m.redisClient.GetClient().Get(ctx, QueueJoinRateKey(eventID, previous)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get join rate: %w", err)
+	}
+	return float64(count) / float64(windowSeconds), nil
+}
+
+// ChallengeDifficulty returns the current proof-of-work difficulty for
+// joining an event, or 0 if none is required
+func (m *Manager) ChallengeDifficulty(eventID string) (int, error) {
+	config, err := m.GetEventConfig(eventID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get event config: %w", err)
+	}
+	if config.ChallengeDifficulty <= 0 {
+		return 0, nil
+	}
+
+	joinRate, err := m.JoinRate(eventID)
+	if err != nil {
+		return 0, err
+	}
+	return config.Difficulty(joinRate), nil
+}
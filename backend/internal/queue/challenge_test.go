@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEventConfig_Difficulty(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   EventConfig
+		joinRate float64
+		want     int
+	}{
+		{"disabled", EventConfig{}, 1000, 0},
+		{"fixed", EventConfig{ChallengeDifficulty: 16}, 1000, 16},
+		{"below target", EventConfig{ChallengeDifficulty: 16, ChallengeTargetJoinRate: 100, ChallengeMaxDifficulty: 24}, 50, 16},
+		{"at target", EventConfig{ChallengeDifficulty: 16, ChallengeTargetJoinRate: 100, ChallengeMaxDifficulty: 24}, 100, 16},
+		{"double target", EventConfig{ChallengeDifficulty: 16, ChallengeTargetJoinRate: 100, ChallengeMaxDifficulty: 24}, 200, 17},
+		{"five times target", EventConfig{ChallengeDifficulty: 16, ChallengeTargetJoinRate: 100, ChallengeMaxDifficulty: 24}, 500, 19},
+		{"capped", EventConfig{ChallengeDifficulty: 16, ChallengeTargetJoinRate: 1, ChallengeMaxDifficulty: 20}, 1e6, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Difficulty(tt.joinRate); got != tt.want {
+				t.Errorf("Expected difficulty %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChallengeDifficulty_RisesWithJoinRate(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-pow"
+	if err := manager.SetEventConfig(&EventConfig{
+		EventID:                 eventID,
+		Enabled:                 true,
+		MaxSize:                 100,
+		ChallengeDifficulty:     8,
+		ChallengeTargetJoinRate: 1,
+		ChallengeMaxDifficulty:  12,
+	}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	difficulty, err := manager.ChallengeDifficulty(eventID)
+	if err != nil {
+		t.Fatalf("ChallengeDifficulty() failed: %v", err)
+	}
+	if difficulty != 8 {
+		t.Errorf("Expected base difficulty 8, got %d", difficulty)
+	}
+
+	// 40 joins in the previous window is 4 per second, four times the target
+	windowSeconds := int64(JoinRateWindow / time.Second)
+	previous := time.Now().Unix()/windowSeconds - 1
+	manager.redisClient.GetClient().Set(context.Background(), QueueJoinRateKey(eventID, previous), 40, time.Minute)
+
+	difficulty, err = manager.ChallengeDifficulty(eventID)
+	if err != nil {
+		t.Fatalf("ChallengeDifficulty() failed: %v", err)
+	}
+	if difficulty != 10 {
+		t.Errorf("Expected difficulty 10, got %d", difficulty)
+	}
+}
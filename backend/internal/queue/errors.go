@@ -0,0 +1,55 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors returned by queue operations. They are wrapped with
+// additional context, so callers should match them with errors.Is.
+var (
+	// ErrNotFound is returned when a queue entry does not exist or has expired
+	ErrNotFound = errors.New("queue entry not found")
+	// ErrEventDisabled is returned when joining the queue of a disabled event
+	ErrEventDisabled = errors.New("event queue is disabled")
+	// ErrQueueFull is returned when the event queue has reached its max size
+	ErrQueueFull = errors.New("queue is full")
+	// ErrRateLimited is returned when a device exceeds the join rate limit
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrBanned is returned when a banned device, user or IP joins an event
+	ErrBanned = errors.New("banned from event")
+	// ErrAttestationFailed is returned when a join's device attestation is
+	// missing, invalid or denied
+	ErrAttestationFailed = errors.New("device attestation failed")
+	// ErrInvalidMetadata is returned when join metadata exceeds the key or
+	// size limits
+	ErrInvalidMetadata = errors.New("invalid metadata")
+	// ErrInvalidIdentifier is returned when an id of a join is missing or
+	// too long
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	// ErrAlreadyAdmitted is returned when moderating an entry that has
+	// already been admitted
+	ErrAlreadyAdmitted = errors.New("queue entry already admitted")
+	// ErrInvalidBucket is returned when a join requests a priority bucket the
+	// event does not offer to clients
+	ErrInvalidBucket = errors.New("invalid priority bucket")
+)
+
+// RateLimitError is returned when a device exceeds the join rate limit.
+// It matches ErrRateLimited and carries the time until the window resets.
+type RateLimitError struct {
+	Limit      int
+	Window     time.Duration
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: maximum %d joins per %v", ErrRateLimited, e.Limit, e.Window)
+}
+
+// Is reports whether target is ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
@@ -21,7 +21,7 @@ func (m *Manager) SendHeartbeat(queueID string) (*QueueStatus, error) {
 	entryKey := QueueEntryKey(queueID)
 	entryData, err := m.redisClient.GetClient().Get(ctx, entryKey).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("queue entry not found: %s", queueID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, queueID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve queue entry: %w", err)
@@ -47,11 +47,13 @@ func (m *Manager) SendHeartbeat(queueID string) (*QueueStatus, error) {
 		// User is admitted, return status with token indicator
 		return &QueueStatus{
 			QueueID:              queueID,
+			EventID:              entry.EventID,
 			Position:             0,
 			EstimatedWaitSeconds: 0,
 			Status:               "admitted",
 			EnqueuedAt:           entry.EnqueuedAt,
 			LastHeartbeat:        entry.LastHeartbeat,
+			AdmissionToken:       m.getAdmissionToken(ctx, queueID, true),
 		}, nil
 	}
 
@@ -83,6 +85,7 @@ func (m *Manager) SendHeartbeat(queueID string) (*QueueStatus, error) {
 
 	return &QueueStatus{
 		QueueID:              queueID,
+		EventID:              entry.EventID,
 		Position:             position,
 		EstimatedWaitSeconds: estimatedWait,
 		Status:               "waiting",
@@ -92,6 +95,34 @@ func (m *Manager) SendHeartbeat(queueID string) (*QueueStatus, error) {
 	}, nil
 }
 
+// TouchEntries records a heartbeat for entries without recomputing their
+// status, e.g. for clients holding a status stream open. Entries removed in
+// the meantime are not recreated.
+func (m *Manager) TouchEntries(queueIDs []string) error {
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	entries, err := m.loadEntries(ctx, queueIDs)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	now := time.Now()
+	pipe := m.redisClient.GetClient().Pipeline()
+	for queueID, entry := range entries {
+		entry.LastHeartbeat = now
+		entryData, err := SerializeQueueEntry(entry)
+		if err != nil {
+			continue
+		}
+		pipe.SetXX(ctx, QueueEntryKey(queueID), entryData, QueueEntryTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to update queue entries: %w", err)
+	}
+	return nil
+}
+
 // CleanupAbandonedSessions removes queue entries that haven't sent a heartbeat
 func (m *Manager) CleanupAbandonedSessions() error {
 	// This is a simplified cleanup - in production, you'd want to scan all queue entries
@@ -118,7 +149,7 @@ func (m *Manager) MarkAsAdmitted(queueID string) error {
 	entryKey := QueueEntryKey(queueID)
 	entryData, err := m.redisClient.GetClient().Get(ctx, entryKey).Result()
 	if err == redis.Nil {
-		return fmt.Errorf("queue entry not found: %s", queueID)
+		return fmt.Errorf("%w: %s", ErrNotFound, queueID)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to retrieve queue entry: %w", err)
@@ -140,7 +171,7 @@ func (m *Manager) MarkAsAdmitted(queueID string) error {
 		zsetKey := QueueSortedSetKey(entry.EventID)
 		_ = m.redisClient.GetClient().ZRem(ctx, zsetKey, queueID).Err()
 	} else {
-		listKey := QueueListKey(entry.EventID)
+		listKey := bucketListKey(entry.EventID, entry.PriorityBucket)
 		_ = m.redisClient.GetClient().LRem(ctx, listKey, 1, queueID).Err()
 	}
 
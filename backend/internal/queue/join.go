@@ -3,10 +3,17 @@ package queue
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+
+	"gatekeep/internal/attestation"
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/metrics"
+	"gatekeep/internal/webhook"
 )
 
 const (
@@ -16,6 +23,9 @@ const (
 	MaxJoinsPerWindow = 5
 	// QueueEntryTTL is the TTL for queue entries (30 minutes)
 	QueueEntryTTL = 30 * time.Minute
+	// DeprioritizedBucket holds entries whose device attestation was weak;
+	// they are released only once the FIFO queue is empty
+	DeprioritizedBucket = "deprioritized"
 )
 
 // JoinQueueRequest is defined in models.go for interface compatibility
@@ -23,16 +33,22 @@ const (
 // JoinQueue adds a user to the queue
 func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 	// Validate request
-	if req.EventID == "" {
-		return nil, fmt.Errorf("event_id is required")
+	if err := ValidateIdentifier("event_id", req.EventID); err != nil {
+		return nil, err
 	}
-	if req.DeviceID == "" {
-		return nil, fmt.Errorf("device_id is required")
+	if err := ValidateIdentifier("device_id", req.DeviceID); err != nil {
+		return nil, err
+	}
+	if err := ValidateMetadata(req.Metadata); err != nil {
+		return nil, err
 	}
 	// Use device_id as user_id if user_id is not provided
 	if req.UserID == "" {
 		req.UserID = req.DeviceID
 	}
+	if err := ValidateIdentifier("user_id", req.UserID); err != nil {
+		return nil, err
+	}
 	if req.PriorityBucket == "" {
 		req.PriorityBucket = "normal"
 	}
@@ -44,7 +60,12 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 	}
 
 	if !config.Enabled {
-		return nil, fmt.Errorf("queue for event %s is disabled", req.EventID)
+		return nil, fmt.Errorf("%w: %s", ErrEventDisabled, req.EventID)
+	}
+
+	// Check bans before counting the attempt against the rate limit
+	if err := m.checkBanned(req.EventID, req.DeviceID, req.UserID, req.ClientIP); err != nil {
+		return nil, err
 	}
 
 	// Check rate limiting
@@ -52,6 +73,24 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 		return nil, err
 	}
 
+	// Check device attestation; deprioritized devices wait behind everyone
+	attestationVerdict, err := m.checkAttestation(config, req)
+	if err != nil {
+		return nil, err
+	}
+	if attestationVerdict == attestation.Deprioritize {
+		req.PriorityBucket = DeprioritizedBucket
+	}
+
+	// Score the join; risky entries are held for review or shadow-banned
+	assessment, err := m.assessRisk(req)
+	if err != nil {
+		return nil, err
+	}
+	if bucket := config.RiskBucket(assessment.Score); bucket != "" {
+		req.PriorityBucket = bucket
+	}
+
 	// Check for existing queue entry (idempotency)
 	deviceEventKey := QueueDeviceEventKey(req.DeviceID, req.EventID)
 	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
@@ -66,7 +105,7 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 			entry, err := DeserializeQueueEntry(entryData)
 			if err == nil {
 				// Update position
-				entry.Position = m.calculatePosition(req.EventID, existingQueueID, req.PriorityBucket)
+				entry.Position = m.calculatePosition(req.EventID, existingQueueID, entry.PriorityBucket)
 				return entry, nil
 			}
 		}
@@ -80,7 +119,7 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 	}
 
 	if queueSize >= config.MaxSize {
-		return nil, fmt.Errorf("queue is full (max size: %d)", config.MaxSize)
+		return nil, fmt.Errorf("%w (max size: %d)", ErrQueueFull, config.MaxSize)
 	}
 
 	// Generate queue_id
@@ -97,6 +136,12 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 		EnqueuedAt:     now,
 		LastHeartbeat:  now,
 		PriorityBucket: req.PriorityBucket,
+		ClientIP:       req.ClientIP,
+		KeyThumbprint:  req.KeyThumbprint,
+		Attestation:    string(attestationVerdict),
+		RiskScore:      assessment.Score,
+		RiskReasons:    assessment.Reasons,
+		Metadata:       req.Metadata,
 	}
 
 	// Serialize entry
@@ -113,7 +158,8 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 	pipe.Set(ctx, entryKey, entryData, QueueEntryTTL)
 
 	// Add to queue (LIST for FIFO, ZSET for priority)
-	if req.PriorityBucket == "high" {
+	switch req.PriorityBucket {
+	case "high":
 		// Use sorted set for priority queues
 		zsetKey := QueueSortedSetKey(req.EventID)
 		score := float64(now.Unix()) // Use timestamp as score for FIFO within priority
@@ -121,15 +167,26 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 			Score:  score,
 			Member: queueID,
 		})
-	} else {
-		// Use list for normal/low priority
-		listKey := QueueListKey(req.EventID)
-		pipe.RPush(ctx, listKey, queueID)
+	default:
+		// Use list for normal/low priority; held buckets have lists of
+		// their own behind the FIFO queue
+		pipe.RPush(ctx, bucketListKey(req.EventID, req.PriorityBucket), queueID)
 	}
 
 	// Store device+event mapping for idempotency
 	pipe.Set(ctx, deviceEventKey, queueID, QueueEntryTTL)
 
+	// Index the entry by user and the event for admin lookups
+	userEventKey := QueueUserEventKey(req.UserID, req.EventID)
+	pipe.SAdd(ctx, userEventKey, queueID)
+	pipe.Expire(ctx, userEventKey, QueueEntryTTL)
+	pipe.SAdd(ctx, QueueEventIndexKey(), req.EventID)
+
+	// Count the join towards the event's join rate
+	joinRateKey := QueueJoinRateKey(req.EventID, now.Unix()/int64(JoinRateWindow/time.Second))
+	pipe.Incr(ctx, joinRateKey)
+	pipe.Expire(ctx, joinRateKey, 3*JoinRateWindow)
+
 	// Execute transaction
 	_, err = pipe.Exec(ctx)
 	if err != nil {
@@ -144,6 +201,43 @@ func (m *Manager) JoinQueue(req JoinQueueRequest) (*QueueEntry, error) {
 	entryData, _ = SerializeQueueEntry(entry)
 	m.redisClient.GetClient().Set(ctx, entryKey, entryData, QueueEntryTTL)
 
+	// Entries held back by attestation or risk scoring are worth noticing
+	level := slog.LevelDebug
+	if isHeldBucket(entry.PriorityBucket) {
+		level = slog.LevelInfo
+	}
+	metrics.QueueJoins.WithLabelValues(entry.EventID, entry.PriorityBucket).Inc()
+	m.recordQueueLength(ctx, entry.EventID)
+	slog.Log(ctx, level, "queue joined",
+		"event_id", entry.EventID,
+		"queue_id", entry.QueueID,
+		"priority_bucket", entry.PriorityBucket,
+		"position", entry.Position,
+		"risk_score", entry.RiskScore,
+	)
+
+	lifecycle.Emit(m.lifecycle, lifecycle.Event{
+		Type:     lifecycle.Joined,
+		EventID:  entry.EventID,
+		QueueID:  entry.QueueID,
+		DeviceID: entry.DeviceID,
+		UserID:   entry.UserID,
+		Metadata: entry.Metadata,
+		Details: map[string]string{
+			"priority_bucket": entry.PriorityBucket,
+			"risk_score":      strconv.Itoa(entry.RiskScore),
+		},
+	})
+	m.webhooks.Emit(webhook.Event{
+		Type:     webhook.EventJoined,
+		EventID:  entry.EventID,
+		QueueID:  entry.QueueID,
+		DeviceID: entry.DeviceID,
+		UserID:   entry.UserID,
+		Metadata: entry.Metadata,
+		Details:  map[string]string{"priority_bucket": entry.PriorityBucket},
+	})
+
 	return entry, nil
 }
 
@@ -163,7 +257,15 @@ func (m *Manager) checkRateLimit(deviceID, eventID string) error {
 	}
 
 	if count >= MaxJoinsPerWindow {
-		return fmt.Errorf("rate limit exceeded: maximum %d joins per %v", MaxJoinsPerWindow, RateLimitWindow)
+		retryAfter, err := m.redisClient.GetClient().TTL(ctx, key).Result()
+		if err != nil || retryAfter < 0 {
+			retryAfter = RateLimitWindow
+		}
+		return &RateLimitError{
+			Limit:      MaxJoinsPerWindow,
+			Window:     RateLimitWindow,
+			RetryAfter: retryAfter,
+		}
 	}
 
 	// Increment counter
@@ -189,7 +291,7 @@ func (m *Manager) getQueueSize(eventID, priorityBucket string) (int, error) {
 		return int(count), err
 	}
 
-	listKey := QueueListKey(eventID)
+	listKey := bucketListKey(eventID, priorityBucket)
 	count, err := m.redisClient.GetClient().LLen(ctx, listKey).Result()
 	return int(count), err
 }
@@ -209,6 +311,19 @@ func (m *Manager) calculatePosition(eventID, queueID, priorityBucket string) int
 		return int(rank) + 1 // Convert to 1-based
 	}
 
+	if isHeldBucket(priorityBucket) {
+		// Behind every entry of the FIFO queue
+		ahead, err := m.redisClient.GetClient().LLen(ctx, QueueListKey(eventID)).Result()
+		if err != nil {
+			return -1
+		}
+		index, err := m.redisClient.GetClient().LPos(ctx, bucketListKey(eventID, priorityBucket), queueID, redis.LPosArgs{}).Result()
+		if err != nil {
+			return -1
+		}
+		return int(ahead) + int(index) + 1
+	}
+
 	// For list, find position
 	listKey := QueueListKey(eventID)
 	items, err := m.redisClient.GetClient().LRange(ctx, listKey, 0, -1).Result()
@@ -443,3 +443,68 @@ func TestSetEventConfig(t *testing.T) {
 		t.Errorf("ReleaseRate mismatch: expected %d, got %d", config.ReleaseRate, retrieved.ReleaseRate)
 	}
 }
+
+func TestEventConfig_AllowsBucket(t *testing.T) {
+	config := EventConfig{PriorityBuckets: []string{"presale", "high"}}
+	tests := []struct {
+		bucket string
+		want   bool
+	}{
+		{"normal", true},
+		{"presale", true},
+		{"high", true},
+		{"vip", false},
+		{DeprioritizedBucket, false},
+		{ShadowBucket, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			if got := config.AllowsBucket(tt.bucket); got != tt.want {
+				t.Errorf("Expected AllowsBucket(%q) = %v, got %v", tt.bucket, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidatePriorityBucket(t *testing.T) {
+	tests := []struct {
+		bucket  string
+		wantErr bool
+	}{
+		{"presale", false},
+		{"", true},
+		{ReviewBucket, true},
+		{string(make([]byte, MaxMetadataKeyLength+1)), true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidatePriorityBucket(tt.bucket); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePriorityBucket(%q): expected error %v, got %v", tt.bucket, tt.wantErr, err)
+		}
+	}
+}
+
+func TestEventConfig_RequiresCaptcha(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EventConfig
+		bucket string
+		want   bool
+	}{
+		{"disabled", EventConfig{}, "normal", false},
+		{"every bucket", EventConfig{CaptchaRequired: true}, "high", true},
+		{"listed bucket", EventConfig{CaptchaBuckets: []string{"presale"}}, "presale", true},
+		{"other bucket", EventConfig{CaptchaBuckets: []string{"presale"}}, "general", false},
+		{"bucket in the FIFO queue", EventConfig{CaptchaBuckets: []string{"normal"}}, "normal2", true},
+		{"priority queue", EventConfig{CaptchaBuckets: []string{"normal"}}, "high", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.RequiresCaptcha(tt.bucket); got != tt.want {
+				t.Errorf("Expected RequiresCaptcha(%q) = %v, got %v", tt.bucket, tt.want, got)
+			}
+		})
+	}
+}
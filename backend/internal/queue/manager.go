@@ -3,17 +3,35 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"gatekeep/internal/attestation"
+	"gatekeep/internal/lifecycle"
 	redisclient "gatekeep/internal/redis"
+	"gatekeep/internal/risk"
+	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
 )
 
 // Manager implements QueueManager interface
 type Manager struct {
 	redisClient *redisclient.Client
 	ctx         context.Context
+	// attestationVerifier is optional; without it attestations are ignored
+	// and events requiring one cannot be joined
+	attestationVerifier attestation.Verifier
+	// riskEngine scores joins; nil disables scoring
+	riskEngine *risk.Engine
+	// webhooks receives join and leave events; nil disables them
+	webhooks *webhook.Dispatcher
+	// lifecycle publishes join and leave events; nil disables them
+	lifecycle lifecycle.Publisher
+	// tokenSealer opens the sealed admission tokens returned to clients; nil
+	// returns only token metadata
+	tokenSealer *token.Sealer
 }
 
 // NewManager creates a new queue manager
@@ -21,6 +39,7 @@ func NewManager(redisClient *redisclient.Client) *Manager {
 	return &Manager{
 		redisClient: redisClient,
 		ctx:         context.Background(),
+		riskEngine:  risk.NewEngine(risk.DefaultRules()...),
 	}
 }
 
@@ -30,6 +49,100 @@ type EventConfig struct {
 	Enabled     bool   `json:"enabled"`
 	MaxSize     int    `json:"max_size"`
 	ReleaseRate int    `json:"release_rate"` // users per second
+	// Admission token lifetime; 0 uses the default
+	TokenTTLSeconds int `json:"token_ttl_seconds,omitempty"`
+	// Upper bound on a token's lifetime across refreshes; 0 uses the default
+	MaxTokenLifetimeSeconds int `json:"max_token_lifetime_seconds,omitempty"`
+	// Entitlements embedded in admission tokens, e.g. {"max_tickets": 4}
+	Entitlements map[string]interface{} `json:"entitlements,omitempty"`
+	// Per priority bucket entitlements, overriding the event's key by key
+	BucketEntitlements map[string]map[string]interface{} `json:"bucket_entitlements,omitempty"`
+	// Buckets clients may request on join besides "normal". Clients pick
+	// their own bucket, so each of these grants its entitlements to anyone.
+	PriorityBuckets []string `json:"priority_buckets,omitempty"`
+	// Proof-of-work difficulty in bits required to join; 0 disables it
+	ChallengeDifficulty int `json:"challenge_difficulty,omitempty"`
+	// Joins per second above which the difficulty rises, one bit per
+	// doubling, up to ChallengeMaxDifficulty; 0 keeps it fixed
+	ChallengeTargetJoinRate int `json:"challenge_target_join_rate,omitempty"`
+	ChallengeMaxDifficulty  int `json:"challenge_max_difficulty,omitempty"`
+	// Human verification on join, for every bucket or the listed ones
+	CaptchaRequired bool     `json:"captcha_required,omitempty"`
+	CaptchaBuckets  []string `json:"captcha_buckets,omitempty"`
+	// Reject joins without a device attestation
+	AttestationRequired bool `json:"attestation_required,omitempty"`
+	// Deprioritize joins without a device attestation, like weak ones
+	AttestationPreferred bool `json:"attestation_preferred,omitempty"`
+	// Risk scores (0-100) at or above which joins are held for review or
+	// shadow-banned; 0 disables routing
+	RiskReviewThreshold    int `json:"risk_review_threshold,omitempty"`
+	RiskShadowBanThreshold int `json:"risk_shadow_ban_threshold,omitempty"`
+	// Join metadata keys copied into admission tokens
+	TokenMetadataKeys []string `json:"token_metadata_keys,omitempty"`
+}
+
+// AllowsBucket reports whether clients may request the bucket on join.
+// Buckets the service assigns (deprioritized, review, shadow) never are.
+func (c *EventConfig) AllowsBucket(bucket string) bool {
+	if bucket == "normal" {
+		return true
+	}
+	if isHeldBucket(bucket) {
+		return false
+	}
+	for _, b := range c.PriorityBuckets {
+		if b == bucket {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePriorityBucket checks that a bucket offered to clients is 1-64
+// bytes and not one the service assigns
+func ValidatePriorityBucket(bucket string) error {
+	if bucket == "" || len(bucket) > MaxMetadataKeyLength {
+		return fmt.Errorf("%w: buckets must be 1-%d bytes, got %q", ErrInvalidBucket, MaxMetadataKeyLength, bucket)
+	}
+	if isHeldBucket(bucket) {
+		return fmt.Errorf("%w: %s is assigned by the service", ErrInvalidBucket, bucket)
+	}
+	return nil
+}
+
+// RequiresCaptcha reports whether joining the bucket needs human verification.
+// A listed bucket also covers every bucket waiting in its queue, so listing
+// "normal" covers all buckets sharing the FIFO queue.
+func (c *EventConfig) RequiresCaptcha(bucket string) bool {
+	if c.CaptchaRequired {
+		return true
+	}
+	queued := snapshotBucket(bucket)
+	for _, b := range c.CaptchaBuckets {
+		if b == bucket || b == queued {
+			return true
+		}
+	}
+	return false
+}
+
+// EventConfigured reports whether the event has a stored configuration.
+// GetEventConfig returns defaults for events without one.
+func (m *Manager) EventConfigured(eventID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	n, err := m.redisClient.GetClient().Exists(ctx, QueueEventConfigKey(eventID)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetTokenSealer sets the sealer opening stored admission tokens. It must use
+// the secret the release controller's token generator uses.
+func (m *Manager) SetTokenSealer(sealer *token.Sealer) {
+	m.tokenSealer = sealer
 }
 
 // GetEventConfig retrieves event configuration from Redis
@@ -74,5 +187,9 @@ func (m *Manager) SetEventConfig(config *EventConfig) error {
 		return err
 	}
 
-	return m.redisClient.GetClient().Set(ctx, key, data, 0).Err()
+	pipe := m.redisClient.GetClient().Pipeline()
+	pipe.Set(ctx, key, data, 0)
+	pipe.SAdd(ctx, QueueEventIndexKey(), config.EventID)
+	_, err = pipe.Exec(ctx)
+	return err
 }
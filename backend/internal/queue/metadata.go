@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// MaxMetadataKeys bounds the number of metadata keys on a join
+	MaxMetadataKeys = 16
+	// MaxMetadataKeyLength bounds the length of metadata keys
+	MaxMetadataKeyLength = 64
+	// MaxMetadataValueLength bounds the length of metadata values
+	MaxMetadataValueLength = 256
+	// MaxMetadataSize bounds the total JSON-encoded length of metadata keys
+	// and values. With MaxIdentifierLength and token.MaxEntitlementsSize it
+	// keeps admission tokens under token.MaxTokenLength.
+	MaxMetadataSize = 512
+	// MaxIdentifierLength bounds the JSON-encoded length of the event, device
+	// and user ids of a join, which tokens carry
+	MaxIdentifierLength = 128
+)
+
+// ValidateMetadata checks join metadata, e.g. {"source": "newsletter",
+// "locale": "de-DE"}, against the key and size limits
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataKeys {
+		return fmt.Errorf("%w: at most %d keys, got %d", ErrInvalidMetadata, MaxMetadataKeys, len(metadata))
+	}
+	size := 0
+	for key, value := range metadata {
+		if err := ValidateMetadataKey(key); err != nil {
+			return err
+		}
+		if len(value) > MaxMetadataValueLength {
+			return fmt.Errorf("%w: value of %q longer than %d bytes", ErrInvalidMetadata, key, MaxMetadataValueLength)
+		}
+		size += len(key) + encodedLength(value)
+	}
+	if size > MaxMetadataSize {
+		return fmt.Errorf("%w: keys and values encode to %d bytes, at most %d", ErrInvalidMetadata, size, MaxMetadataSize)
+	}
+	return nil
+}
+
+// ValidateIdentifier checks that an id of a join, e.g. device_id, is set and
+// encodes to at most MaxIdentifierLength bytes
+func ValidateIdentifier(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: %s is required", ErrInvalidIdentifier, name)
+	}
+	if n := encodedLength(value); n > MaxIdentifierLength {
+		return fmt.Errorf("%w: %s encodes to %d bytes, at most %d", ErrInvalidIdentifier, name, n, MaxIdentifierLength)
+	}
+	return nil
+}
+
+// encodedLength returns the length of a string as a JSON string without its
+// quotes, as it takes up in a token
+func encodedLength(value string) int {
+	data, _ := json.Marshal(value)
+	return len(data) - 2
+}
+
+// ValidateMetadataKey checks that a metadata key is 1-64 letters, digits,
+// '_', '-' or '.'
+func ValidateMetadataKey(key string) error {
+	if key == "" || len(key) > MaxMetadataKeyLength {
+		return fmt.Errorf("%w: keys must be 1-%d bytes, got %q", ErrInvalidMetadata, MaxMetadataKeyLength, key)
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("%w: invalid character %q in key %q", ErrInvalidMetadata, c, key)
+		}
+	}
+	return nil
+}
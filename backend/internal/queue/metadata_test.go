@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gatekeep/internal/token"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxMetadataKeys; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "v"
+	}
+	tooLarge := map[string]string{
+		"a": strings.Repeat("x", MaxMetadataValueLength),
+		"b": strings.Repeat("x", MaxMetadataValueLength),
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{"empty", nil, false},
+		{"valid", map[string]string{"source": "newsletter", "locale": "de-DE", "app.version": "4.2.0"}, false},
+		{"too many keys", tooMany, true},
+		{"empty key", map[string]string{"": "v"}, true},
+		{"long key", map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "v"}, true},
+		{"invalid key", map[string]string{"utm source": "v"}, true},
+		{"long value", map[string]string{"source": strings.Repeat("x", MaxMetadataValueLength+1)}, true},
+		{"too large", tooLarge, true},
+		{"too large encoded", map[string]string{"a": strings.Repeat("<", MaxMetadataSize/6+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetadata(tt.metadata)
+			if tt.wantErr && !errors.Is(err, ErrInvalidMetadata) {
+				t.Errorf("Expected ErrInvalidMetadata, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidateMetadata() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", "device-1", false},
+		{"empty", "", true},
+		{"longest", strings.Repeat("d", MaxIdentifierLength), false},
+		{"too long", strings.Repeat("d", MaxIdentifierLength+1), true},
+		{"too long encoded", strings.Repeat("&", MaxIdentifierLength/6+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIdentifier("device_id", tt.value)
+			if tt.wantErr && !errors.Is(err, ErrInvalidIdentifier) {
+				t.Errorf("Expected ErrInvalidIdentifier, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidateIdentifier() failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestJoinLimits_FitInToken checks that a token carrying the largest ids,
+// metadata and entitlements a join and event config allow is not longer than
+// token.MaxTokenLength
+func TestJoinLimits_FitInToken(t *testing.T) {
+	id := strings.Repeat("i", MaxIdentifierLength)
+	metadata := map[string]string{
+		"a": strings.Repeat("v", MaxMetadataValueLength-1),
+		"b": strings.Repeat("v", MaxMetadataValueLength-1),
+	}
+	if err := ValidateMetadata(metadata); err != nil {
+		t.Fatalf("ValidateMetadata() failed: %v", err)
+	}
+	entitlements := token.Entitlements{"e": strings.Repeat("x", token.MaxEntitlementsSize-8)}
+	if err := entitlements.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(token.TokenPayload{
+		Issuer:        strings.Repeat("s", 128),
+		EventID:       id,
+		DeviceID:      id,
+		UserID:        id,
+		QueueID:       "q_" + strings.Repeat("0", 36),
+		IssuedAt:      now,
+		ExpiresAt:     now.Add(token.MaxTokenLifetime),
+		NotBefore:     now,
+		AdmittedAt:    now,
+		Nonce:         strings.Repeat("n", 36),
+		KeyThumbprint: strings.Repeat("t", 43),
+		Entitlements:  entitlements,
+		Metadata:      metadata,
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	header, _ := json.Marshal(token.TokenHeader{Algorithm: token.TokenHeaderAlgorithm, Type: token.TokenType})
+	length := base64.RawURLEncoding.EncodedLen(len(header)) + 1 +
+		base64.RawURLEncoding.EncodedLen(len(payload)) + 1 + base64.RawURLEncoding.EncodedLen(32)
+	if length > token.MaxTokenLength {
+		t.Errorf("Expected the largest token to fit in %d bytes, got %d", token.MaxTokenLength, length)
+	}
+}
+
+func TestJoinQueue_StoresMetadata(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	metadata := map[string]string{"source": "newsletter", "locale": "de-DE"}
+	entry, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:  "test-event-metadata",
+		DeviceID: "device-1",
+		Metadata: metadata,
+	})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	stored, err := manager.GetQueueEntry(entry.QueueID)
+	if err != nil {
+		t.Fatalf("GetQueueEntry() failed: %v", err)
+	}
+	if len(stored.Metadata) != 2 || stored.Metadata["source"] != "newsletter" || stored.Metadata["locale"] != "de-DE" {
+		t.Errorf("Expected metadata %v, got %v", metadata, stored.Metadata)
+	}
+
+	if _, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:  "test-event-metadata",
+		DeviceID: "device-2",
+		Metadata: map[string]string{"bad key": "v"},
+	}); !errors.Is(err, ErrInvalidMetadata) {
+		t.Errorf("Expected ErrInvalidMetadata, got %v", err)
+	}
+}
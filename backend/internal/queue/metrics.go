@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+
+	"gatekeep/internal/metrics"
+)
+
+// recordQueueLength sets the queue length gauge to the number of entries
+// waiting in all of the event's buckets
+func (m *Manager) recordQueueLength(ctx context.Context, eventID string) {
+	pipe := m.redisClient.GetClient().Pipeline()
+	cmds := []interface{ Val() int64 }{
+		pipe.ZCard(ctx, QueueSortedSetKey(eventID)),
+		pipe.LLen(ctx, QueueListKey(eventID)),
+		pipe.LLen(ctx, QueueDeprioritizedKey(eventID)),
+		pipe.LLen(ctx, QueueReviewKey(eventID)),
+		pipe.LLen(ctx, QueueShadowKey(eventID)),
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Debug("failed to count queue entries", "event_id", eventID, "error", err)
+		return
+	}
+
+	var length int64
+	for _, cmd := range cmds {
+		length += cmd.Val()
+	}
+	metrics.QueueLength.WithLabelValues(eventID).Set(float64(length))
+}
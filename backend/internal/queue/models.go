@@ -1,6 +1,8 @@
 package queue
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -16,19 +18,66 @@ type QueueEntry struct {
 	EnqueuedAt     time.Time `json:"enqueued_at"`
 	LastHeartbeat  time.Time `json:"last_heartbeat"`
 	PriorityBucket string    `json:"priority_bucket"`
+	ClientIP       string    `json:"client_ip,omitempty"`
+	// KeyThumbprint is the thumbprint of the device key registered at join;
+	// admission tokens for the entry are bound to it
+	KeyThumbprint string `json:"key_thumbprint,omitempty"`
+	// Attestation is the verdict of the device attestation checked at join
+	Attestation string `json:"attestation,omitempty"`
+	// RiskScore and RiskReasons are the risk assessment made at join
+	RiskScore   int      `json:"risk_score,omitempty"`
+	RiskReasons []string `json:"risk_reasons,omitempty"`
+	// Metadata is what the client reported at join, e.g. campaign source,
+	// locale and app version
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // QueueStatus represents the current status of a queue entry
 type QueueStatus struct {
-	QueueID              string    `json:"queue_id"`
-	Position             int       `json:"position"`
-	EstimatedWaitSeconds int       `json:"estimated_wait_seconds"`
-	Status               string    `json:"status"` // "waiting", "admitted", "expired"
-	EnqueuedAt           time.Time `json:"enqueued_at"`
-	LastHeartbeat        time.Time `json:"last_heartbeat"`
-	TotalInQueue         *int      `json:"total_in_queue,omitempty"` // Total users in queue
+	QueueID              string          `json:"queue_id"`
+	EventID              string          `json:"event_id,omitempty"`
+	Position             int             `json:"position"`
+	EstimatedWaitSeconds int             `json:"estimated_wait_seconds"`
+	Status               string          `json:"status"` // "waiting", "admitted", "expired"
+	EnqueuedAt           time.Time       `json:"enqueued_at"`
+	LastHeartbeat        time.Time       `json:"last_heartbeat"`
+	TotalInQueue         *int            `json:"total_in_queue,omitempty"`  // Total users in queue
+	AdmissionToken       *AdmissionToken `json:"admission_token,omitempty"` // Set once admitted
+	Version              string          `json:"version,omitempty"`         // Changes when status, position bucket or token change
 }
 
+// AdmissionToken is the token issued to a queue entry on release. The bearer
+// token is kept in Redis only sealed with the token secret; Token is set when
+// it is handed to the client, on every status read until it expires.
+type AdmissionToken struct {
+	Token     string `json:"token,omitempty"`
+	TokenHash string `json:"token_hash"`
+	// SealedToken is the stored, encrypted token; it is never returned
+	SealedToken string    `json:"sealed_token,omitempty"`
+	EventID     string    `json:"event_id"`
+	DeviceID    string    `json:"device_id"`
+	UserID      string    `json:"user_id,omitempty"`
+	QueueID     string    `json:"queue_id"`
+	IssuedAt    time.Time `json:"issued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// QueueEvent is published on an event's pub/sub channel whenever queue state
+// changes in a way that affects waiting clients
+type QueueEvent struct {
+	Type    string `json:"type"`
+	QueueID string `json:"queue_id,omitempty"`
+}
+
+const (
+	// QueueEventAdmitted is published when a queue entry is released
+	QueueEventAdmitted = "admitted"
+	// QueueEventRemoved is published when an admin removes a queue entry
+	QueueEventRemoved = "removed"
+	// QueueEventMoved is published when an admin moves a queue entry
+	QueueEventMoved = "moved"
+)
+
 // QueueManager defines the interface for queue operations
 type QueueManager interface {
 	JoinQueue(req JoinQueueRequest) (*QueueEntry, error)
@@ -43,6 +92,14 @@ type JoinQueueRequest struct {
 	DeviceID       string
 	UserID         string
 	PriorityBucket string
+	ClientIP       string
+	KeyThumbprint  string
+	// Attestation is the device attestation token, if any
+	Attestation string
+	// UserAgent is a risk signal reported by the client
+	UserAgent string
+	// Metadata is stored on the entry and scored as a risk signal
+	Metadata map[string]string
 }
 
 // Redis key generation helpers
@@ -57,6 +114,24 @@ func QueueListKey(eventID string) string {
 	return fmt.Sprintf("queue:list:%s", eventID)
 }
 
+// QueueDeprioritizedKey returns the Redis key for the list of deprioritized
+// entries, released after the FIFO queue is empty
+func QueueDeprioritizedKey(eventID string) string {
+	return fmt.Sprintf("queue:deprioritized:%s", eventID)
+}
+
+// QueueReviewKey returns the Redis key for the list of entries held for
+// review; they are only released by an admin
+func QueueReviewKey(eventID string) string {
+	return fmt.Sprintf("queue:review:%s", eventID)
+}
+
+// QueueShadowKey returns the Redis key for the list of shadow-banned entries,
+// which are never released
+func QueueShadowKey(eventID string) string {
+	return fmt.Sprintf("queue:shadow:%s", eventID)
+}
+
 // QueueSortedSetKey returns the Redis key for the priority queue (sorted set)
 func QueueSortedSetKey(eventID string) string {
 	return fmt.Sprintf("queue:zset:%s", eventID)
@@ -77,11 +152,80 @@ func QueueRateLimitKey(deviceID, eventID string) string {
 	return fmt.Sprintf("queue:ratelimit:%s:%s", deviceID, eventID)
 }
 
+// QueueIPJoinsKey returns the Redis key counting joins from an IP
+func QueueIPJoinsKey(eventID, clientIP string) string {
+	return fmt.Sprintf("queue:ipjoins:%s:%s", eventID, clientIP)
+}
+
+// QueueIPDevicesKey returns the Redis key estimating the distinct devices
+// joining from an IP (HyperLogLog)
+func QueueIPDevicesKey(eventID, clientIP string) string {
+	return fmt.Sprintf("queue:ipdevices:%s:%s", eventID, clientIP)
+}
+
 // QueueDeviceEventKey returns the Redis key for device+event lookup (for idempotency)
 func QueueDeviceEventKey(deviceID, eventID string) string {
 	return fmt.Sprintf("queue:device:event:%s:%s", deviceID, eventID)
 }
 
+// QueueUserEventKey returns the Redis key for the set of a user's queue IDs in an event
+func QueueUserEventKey(userID, eventID string) string {
+	return fmt.Sprintf("queue:user:event:%s:%s", userID, eventID)
+}
+
+// QueueEventIndexKey returns the Redis key for the set of known event IDs
+func QueueEventIndexKey() string {
+	return "queue:index:events"
+}
+
+// QueueJoinRateKey returns the Redis key counting an event's joins in a
+// JoinRateWindow
+func QueueJoinRateKey(eventID string, window int64) string {
+	return fmt.Sprintf("queue:joinrate:%s:%d", eventID, window)
+}
+
+// QueueBanKey returns the Redis key for an event's set of banned values of a kind
+func QueueBanKey(eventID string, kind BanKind) string {
+	return fmt.Sprintf("queue:ban:%s:%s", eventID, kind)
+}
+
+// QueueAdmissionTokenKey returns the Redis key for the metadata of the token
+// issued to a queue entry
+func QueueAdmissionTokenKey(queueID string) string {
+	return fmt.Sprintf("queue:admission:%s", queueID)
+}
+
+// QueueEventsChannel returns the Redis pub/sub channel for an event's queue updates
+func QueueEventsChannel(eventID string) string {
+	return fmt.Sprintf("queue:events:%s", eventID)
+}
+
+// PositionBucket coarsens a position so that small movements far back in the
+// queue do not count as a change for long-polling clients
+func PositionBucket(position int) int {
+	switch {
+	case position <= 100:
+		return position
+	case position <= 1000:
+		return position / 10 * 10
+	default:
+		return position / 100 * 100
+	}
+}
+
+// StatusVersion returns an opaque version of the parts of a status that
+// clients wait on: the status, the position bucket and the admission token
+func StatusVersion(status *QueueStatus) string {
+	tokenTag := ""
+	if status.AdmissionToken != nil {
+		tokenTag = status.AdmissionToken.TokenHash
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%s", status.Status, PositionBucket(status.Position), tokenTag)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
 // SerializeQueueEntry serializes a QueueEntry to JSON
 func SerializeQueueEntry(entry *QueueEntry) (string, error) {
 	data, err := json.Marshal(entry)
@@ -324,3 +324,46 @@ func TestRedisKeyGeneration_MultiArg(t *testing.T) {
 		t.Errorf("QueueDeviceEventKey() = %s, want %s", deviceEventKey, expectedDeviceEvent)
 	}
 }
+
+func TestPositionBucket(t *testing.T) {
+	tests := []struct {
+		position int
+		want     int
+	}{
+		{0, 0},
+		{1, 1},
+		{100, 100},
+		{101, 100},
+		{999, 990},
+		{1000, 1000},
+		{1049, 1000},
+		{15234, 15200},
+	}
+
+	for _, tt := range tests {
+		if got := PositionBucket(tt.position); got != tt.want {
+			t.Errorf("PositionBucket(%d) = %d, want %d", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestStatusVersion(t *testing.T) {
+	waiting := &QueueStatus{QueueID: "q1", Position: 1523, Status: "waiting"}
+	sameBucket := &QueueStatus{QueueID: "q1", Position: 1510, Status: "waiting", EstimatedWaitSeconds: 30}
+	nextBucket := &QueueStatus{QueueID: "q1", Position: 1499, Status: "waiting"}
+	admitted := &QueueStatus{QueueID: "q1", Status: "admitted"}
+	withToken := &QueueStatus{QueueID: "q1", Status: "admitted", AdmissionToken: &AdmissionToken{TokenHash: "abc"}}
+
+	if StatusVersion(waiting) != StatusVersion(sameBucket) {
+		t.Error("Versions should match within the same position bucket")
+	}
+	if StatusVersion(waiting) == StatusVersion(nextBucket) {
+		t.Error("Versions should differ across position buckets")
+	}
+	if StatusVersion(admitted) == StatusVersion(withToken) {
+		t.Error("Versions should differ when a token is issued")
+	}
+	if len(StatusVersion(waiting)) != 16 {
+		t.Errorf("Expected 16-character version, got %q", StatusVersion(waiting))
+	}
+}
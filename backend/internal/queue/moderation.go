@@ -0,0 +1,252 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/metrics"
+	"gatekeep/internal/webhook"
+)
+
+// BanKind identifies what a ban applies to
+type BanKind string
+
+const (
+	// BanDevice bans a device_id
+	BanDevice BanKind = "device"
+	// BanUser bans a user_id
+	BanUser BanKind = "user"
+	// BanIP bans a client IP
+	BanIP BanKind = "ip"
+)
+
+// BanKinds lists every supported ban kind
+var BanKinds = []BanKind{BanDevice, BanUser, BanIP}
+
+// ParseBanKind validates a ban kind
+func ParseBanKind(value string) (BanKind, error) {
+	for _, kind := range BanKinds {
+		if string(kind) == value {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("invalid ban kind: %s (expected device, user or ip)", value)
+}
+
+// Ban bans a device, user or IP from an event. Banned clients cannot join and
+// are dropped instead of admitted if already queued.
+func (m *Manager) Ban(eventID string, kind BanKind, value string) error {
+	if eventID == "" {
+		return fmt.Errorf("event_id is required")
+	}
+	if value == "" {
+		return fmt.Errorf("value is required")
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	if err := m.redisClient.GetClient().SAdd(ctx, QueueBanKey(eventID, kind), value).Err(); err != nil {
+		return fmt.Errorf("failed to ban %s: %w", kind, err)
+	}
+	return nil
+}
+
+// Unban lifts a ban. It reports whether the ban existed.
+func (m *Manager) Unban(eventID string, kind BanKind, value string) (bool, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	removed, err := m.redisClient.GetClient().SRem(ctx, QueueBanKey(eventID, kind), value).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to unban %s: %w", kind, err)
+	}
+	return removed > 0, nil
+}
+
+// ListBans returns an event's banned values by kind
+func (m *Manager) ListBans(eventID string) (map[BanKind][]string, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	pipe := m.redisClient.GetClient().Pipeline()
+	cmds := make(map[BanKind]*redis.StringSliceCmd, len(BanKinds))
+	for _, kind := range BanKinds {
+		cmds[kind] = pipe.SMembers(ctx, QueueBanKey(eventID, kind))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+
+	bans := make(map[BanKind][]string, len(BanKinds))
+	for kind, cmd := range cmds {
+		bans[kind] = cmd.Val()
+	}
+	return bans, nil
+}
+
+// checkBanned returns ErrBanned if any of the identifiers is banned from the event
+func (m *Manager) checkBanned(eventID, deviceID, userID, clientIP string) error {
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	values := map[BanKind]string{BanDevice: deviceID, BanUser: userID, BanIP: clientIP}
+
+	pipe := m.redisClient.GetClient().Pipeline()
+	cmds := make(map[BanKind]*redis.BoolCmd, len(values))
+	for kind, value := range values {
+		if value != "" {
+			cmds[kind] = pipe.SIsMember(ctx, QueueBanKey(eventID, kind), value)
+		}
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to check bans: %w", err)
+	}
+
+	for kind, cmd := range cmds {
+		if cmd.Val() {
+			return fmt.Errorf("%w: %s %s", ErrBanned, kind, values[kind])
+		}
+	}
+	return nil
+}
+
+// RemoveEntry removes a queue entry and everything indexed by it, and
+// returns the removed entry
+func (m *Manager) RemoveEntry(queueID string) (*QueueEntry, error) {
+	return m.removeEntry(queueID, metrics.EvictionRemoved)
+}
+
+// LeaveQueue removes a client's own queue entry
+func (m *Manager) LeaveQueue(queueID string) error {
+	_, err := m.removeEntry(queueID, metrics.EvictionLeft)
+	return err
+}
+
+// removeEntry removes a queue entry and sends a "left" webhook with the reason
+func (m *Manager) removeEntry(queueID, reason string) (*QueueEntry, error) {
+	entry, err := m.GetQueueEntry(queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	client := m.redisClient.GetClient()
+	pipe := client.TxPipeline()
+	pipe.Del(ctx, QueueEntryKey(queueID), QueueAdmissionTokenKey(queueID))
+	pipe.LRem(ctx, QueueListKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueDeprioritizedKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueReviewKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueShadowKey(entry.EventID), 0, queueID)
+	pipe.ZRem(ctx, QueueSortedSetKey(entry.EventID), queueID)
+	pipe.SRem(ctx, QueueAdmittedKey(entry.EventID), queueID)
+	pipe.SRem(ctx, QueueUserEventKey(entry.UserID, entry.EventID), queueID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to remove queue entry: %w", err)
+	}
+
+	// Only drop the device mapping if it still points at this entry
+	deviceEventKey := QueueDeviceEventKey(entry.DeviceID, entry.EventID)
+	if current, err := client.Get(ctx, deviceEventKey).Result(); err == nil && current == queueID {
+		client.Del(ctx, deviceEventKey)
+	}
+
+	m.publishQueueEvent(ctx, entry.EventID, QueueEvent{Type: QueueEventRemoved, QueueID: queueID})
+	slog.Info("queue entry removed", "event_id", entry.EventID, "queue_id", queueID, "reason", reason)
+	metrics.QueueEvictions.WithLabelValues(entry.EventID, reason).Inc()
+	m.recordQueueLength(ctx, entry.EventID)
+	lifecycle.Emit(m.lifecycle, lifecycle.Event{
+		Type:     lifecycle.Left,
+		EventID:  entry.EventID,
+		QueueID:  queueID,
+		DeviceID: entry.DeviceID,
+		UserID:   entry.UserID,
+		Details:  map[string]string{"reason": reason},
+	})
+	m.webhooks.Emit(webhook.Event{
+		Type:     webhook.EventLeft,
+		EventID:  entry.EventID,
+		QueueID:  queueID,
+		DeviceID: entry.DeviceID,
+		UserID:   entry.UserID,
+		Metadata: entry.Metadata,
+		Details:  map[string]string{"reason": reason},
+	})
+	return entry, nil
+}
+
+// MoveToFront moves a waiting entry ahead of everyone in the event's queue.
+// The entry is placed at the head of the priority queue, so it is released
+// before all other entries, and its bucket becomes "high".
+func (m *Manager) MoveToFront(queueID string) (*QueueEntry, error) {
+	entry, err := m.GetQueueEntry(queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	client := m.redisClient.GetClient()
+
+	admitted, err := client.SIsMember(ctx, QueueAdmittedKey(entry.EventID), queueID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admission: %w", err)
+	}
+	if admitted {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyAdmitted, queueID)
+	}
+
+	// Score below the current head keeps FIFO order for everyone else
+	zsetKey := QueueSortedSetKey(entry.EventID)
+	score := float64(time.Now().Unix())
+	head, err := client.ZRangeWithScores(ctx, zsetKey, 0, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read priority queue: %w", err)
+	}
+	if len(head) > 0 && head[0].Member != queueID && head[0].Score <= score {
+		score = head[0].Score - 1
+	}
+
+	entry.PriorityBucket = "high"
+	entryData, err := SerializeQueueEntry(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize entry: %w", err)
+	}
+
+	pipe := client.TxPipeline()
+	pipe.LRem(ctx, QueueListKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueDeprioritizedKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueReviewKey(entry.EventID), 0, queueID)
+	pipe.LRem(ctx, QueueShadowKey(entry.EventID), 0, queueID)
+	pipe.ZAdd(ctx, zsetKey, redis.Z{Score: score, Member: queueID})
+	pipe.Set(ctx, QueueEntryKey(queueID), entryData, redis.KeepTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to move queue entry: %w", err)
+	}
+
+	entry.Position = m.calculatePosition(entry.EventID, queueID, entry.PriorityBucket)
+	m.publishQueueEvent(ctx, entry.EventID, QueueEvent{Type: QueueEventMoved, QueueID: queueID})
+	return entry, nil
+}
+
+// publishQueueEvent notifies watchers of a queue change. Failures are
+// ignored; watchers fall back to periodic refreshes.
+func (m *Manager) publishQueueEvent(ctx context.Context, eventID string, event QueueEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	m.redisClient.GetClient().Publish(ctx, QueueEventsChannel(eventID), data)
+}
@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBanKind(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    BanKind
+		wantErr bool
+	}{
+		{"device", BanDevice, false},
+		{"user", BanUser, false},
+		{"ip", BanIP, false},
+		{"", "", true},
+		{"email", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			kind, err := ParseBanKind(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBanKind(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if kind != tt.want {
+				t.Errorf("ParseBanKind(%q) = %q, expected %q", tt.value, kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinQueue_Banned(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-banned"
+	if err := manager.Ban(eventID, BanIP, "203.0.113.7"); err != nil {
+		t.Fatalf("Ban() failed: %v", err)
+	}
+
+	_, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: "ban-device", ClientIP: "203.0.113.7"})
+	if !errors.Is(err, ErrBanned) {
+		t.Fatalf("Expected ErrBanned, got %v", err)
+	}
+
+	removed, err := manager.Unban(eventID, BanIP, "203.0.113.7")
+	if err != nil || !removed {
+		t.Fatalf("Unban() = %v, %v", removed, err)
+	}
+	if _, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: "ban-device", ClientIP: "203.0.113.7"}); err != nil {
+		t.Errorf("JoinQueue() after unban failed: %v", err)
+	}
+}
+
+func TestRemoveEntry(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	entry, err := manager.JoinQueue(JoinQueueRequest{EventID: "test-event-remove", DeviceID: "remove-device"})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	if _, err := manager.RemoveEntry(entry.QueueID); err != nil {
+		t.Fatalf("RemoveEntry() failed: %v", err)
+	}
+	if _, err := manager.GetQueueStatus(entry.QueueID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after removal, got %v", err)
+	}
+	if _, err := manager.RemoveEntry(entry.QueueID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound removing twice, got %v", err)
+	}
+}
+
+func TestMoveToFront(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-front"
+	if _, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: "front-high", PriorityBucket: "high"}); err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+	last, err := manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: "front-normal"})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	moved, err := manager.MoveToFront(last.QueueID)
+	if err != nil {
+		t.Fatalf("MoveToFront() failed: %v", err)
+	}
+	if moved.Position != 1 {
+		t.Errorf("Expected position 1, got %d", moved.Position)
+	}
+	if moved.PriorityBucket != "high" {
+		t.Errorf("Expected bucket 'high', got %s", moved.PriorityBucket)
+	}
+
+	if err := manager.MarkAsAdmitted(last.QueueID); err != nil {
+		t.Fatalf("MarkAsAdmitted() failed: %v", err)
+	}
+	if _, err := manager.MoveToFront(last.QueueID); !errors.Is(err, ErrAlreadyAdmitted) {
+		t.Errorf("Expected ErrAlreadyAdmitted, got %v", err)
+	}
+}
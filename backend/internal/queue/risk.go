@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gatekeep/internal/risk"
+)
+
+const (
+	// ReviewBucket holds high-risk entries until an admin releases or removes
+	// them
+	ReviewBucket = "review"
+	// ShadowBucket holds shadow-banned entries; they see a position but are
+	// never released
+	ShadowBucket = "shadow"
+)
+
+// SetRiskEngine sets the engine scoring joins; nil disables scoring
+func (m *Manager) SetRiskEngine(engine *risk.Engine) {
+	m.riskEngine = engine
+}
+
+// RiskBucket returns the bucket a join with the score is routed to, or ""
+// to keep the requested one
+func (c *EventConfig) RiskBucket(score int) string {
+	if c.RiskShadowBanThreshold > 0 && score >= c.RiskShadowBanThreshold {
+		return ShadowBucket
+	}
+	if c.RiskReviewThreshold > 0 && score >= c.RiskReviewThreshold {
+		return ReviewBucket
+	}
+	return ""
+}
+
+// assessRisk records the join against its client IP and scores it
+func (m *Manager) assessRisk(req JoinQueueRequest) (risk.Assessment, error) {
+	if m.riskEngine == nil {
+		return risk.Assessment{}, nil
+	}
+
+	signals := risk.Signals{
+		EventID:   req.EventID,
+		DeviceID:  req.DeviceID,
+		ClientIP:  req.ClientIP,
+		UserAgent: req.UserAgent,
+		Metadata:  req.Metadata,
+	}
+	if req.ClientIP != "" {
+		ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+		defer cancel()
+
+		joinsKey := QueueIPJoinsKey(req.EventID, req.ClientIP)
+		devicesKey := QueueIPDevicesKey(req.EventID, req.ClientIP)
+		pipe := m.redisClient.GetClient().Pipeline()
+		joins := pipe.Incr(ctx, joinsKey)
+		pipe.Expire(ctx, joinsKey, RateLimitWindow)
+		pipe.PFAdd(ctx, devicesKey, req.DeviceID)
+		pipe.Expire(ctx, devicesKey, RateLimitWindow)
+		devices := pipe.PFCount(ctx, devicesKey)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return risk.Assessment{}, fmt.Errorf("failed to record risk signals: %w", err)
+		}
+		signals.IPJoins = int(joins.Val())
+		signals.IPDevices = int(devices.Val())
+	}
+
+	return m.riskEngine.Score(signals), nil
+}
+
+// bucketListKey returns the list holding entries of a bucket other than
+// "high"
+func bucketListKey(eventID, bucket string) string {
+	switch bucket {
+	case DeprioritizedBucket:
+		return QueueDeprioritizedKey(eventID)
+	case ReviewBucket:
+		return QueueReviewKey(eventID)
+	case ShadowBucket:
+		return QueueShadowKey(eventID)
+	}
+	return QueueListKey(eventID)
+}
+
+// isHeldBucket reports whether entries of the bucket wait in a list of their
+// own behind the FIFO queue
+func isHeldBucket(bucket string) bool {
+	return bucket == DeprioritizedBucket || bucket == ReviewBucket || bucket == ShadowBucket
+}
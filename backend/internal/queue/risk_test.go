@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"testing"
+
+	"gatekeep/internal/risk"
+)
+
+func TestEventConfig_RiskBucket(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EventConfig
+		score  int
+		want   string
+	}{
+		{"disabled", EventConfig{}, 100, ""},
+		{"below review", EventConfig{RiskReviewThreshold: 50, RiskShadowBanThreshold: 90}, 49, ""},
+		{"review", EventConfig{RiskReviewThreshold: 50, RiskShadowBanThreshold: 90}, 50, ReviewBucket},
+		{"shadow ban", EventConfig{RiskReviewThreshold: 50, RiskShadowBanThreshold: 90}, 90, ShadowBucket},
+		{"shadow ban only", EventConfig{RiskShadowBanThreshold: 70}, 60, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.RiskBucket(tt.score); got != tt.want {
+				t.Errorf("Expected bucket %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinQueue_RiskRouting(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "test-event-risk"
+	if err := manager.SetEventConfig(&EventConfig{
+		EventID:                eventID,
+		Enabled:                true,
+		MaxSize:                100,
+		RiskReviewThreshold:    40,
+		RiskShadowBanThreshold: 80,
+	}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	browser := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+	tests := []struct {
+		name       string
+		req        JoinQueueRequest
+		wantBucket string
+		wantScore  int
+	}{
+		{"browser", JoinQueueRequest{DeviceID: "device-browser", ClientIP: "203.0.113.1", UserAgent: browser}, "normal", 0},
+		{"library", JoinQueueRequest{DeviceID: "device-curl", ClientIP: "203.0.113.2", UserAgent: "curl/8.7.1"}, ReviewBucket, 40},
+		{"webdriver library", JoinQueueRequest{DeviceID: "device-bot", ClientIP: "203.0.113.3", UserAgent: "curl/8.7.1",
+			Metadata: map[string]string{"webdriver": "true"}}, ShadowBucket, 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.EventID = eventID
+			entry, err := manager.JoinQueue(tt.req)
+			if err != nil {
+				t.Fatalf("JoinQueue() failed: %v", err)
+			}
+			if entry.PriorityBucket != tt.wantBucket {
+				t.Errorf("Expected bucket %s, got %s", tt.wantBucket, entry.PriorityBucket)
+			}
+			if entry.RiskScore != tt.wantScore {
+				t.Errorf("Expected risk score %d, got %d (%v)", tt.wantScore, entry.RiskScore, entry.RiskReasons)
+			}
+			// Held entries still see a position behind the FIFO queue
+			if entry.Position < 1 {
+				t.Errorf("Expected a position, got %d", entry.Position)
+			}
+		})
+	}
+}
+
+func TestJoinQueue_DeviceChurn(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+	manager.SetRiskEngine(risk.NewEngine(risk.DeviceChurn{Limit: 2, Points: 60}))
+
+	eventID := "test-event-churn"
+	if err := manager.SetEventConfig(&EventConfig{EventID: eventID, Enabled: true, MaxSize: 100, RiskReviewThreshold: 50}); err != nil {
+		t.Fatalf("SetEventConfig() failed: %v", err)
+	}
+
+	var entry *QueueEntry
+	for _, deviceID := range []string{"device-a", "device-b", "device-c"} {
+		var err error
+		entry, err = manager.JoinQueue(JoinQueueRequest{EventID: eventID, DeviceID: deviceID, ClientIP: "198.51.100.9"})
+		if err != nil {
+			t.Fatalf("JoinQueue(%s) failed: %v", deviceID, err)
+		}
+	}
+	if entry.PriorityBucket != ReviewBucket {
+		t.Errorf("Expected third device from the IP held for review, got %s (score %d)", entry.PriorityBucket, entry.RiskScore)
+	}
+
+	// Approving the entry moves it to the front
+	moved, err := manager.MoveToFront(entry.QueueID)
+	if err != nil {
+		t.Fatalf("MoveToFront() failed: %v", err)
+	}
+	if moved.Position != 1 {
+		t.Errorf("Expected approved entry at position 1, got %d", moved.Position)
+	}
+}
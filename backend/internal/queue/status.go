@@ -2,7 +2,9 @@ package queue
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -21,7 +23,7 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 	entryKey := QueueEntryKey(queueID)
 	entryData, err := m.redisClient.GetClient().Get(ctx, entryKey).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("queue entry not found: %s", queueID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, queueID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve queue entry: %w", err)
@@ -37,6 +39,7 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 	if time.Since(entry.LastHeartbeat) > QueueEntryTTL {
 		return &QueueStatus{
 			QueueID:              queueID,
+			EventID:              entry.EventID,
 			Position:             0,
 			EstimatedWaitSeconds: 0,
 			Status:               "expired",
@@ -55,11 +58,13 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 	if isAdmitted {
 		return &QueueStatus{
 			QueueID:              queueID,
+			EventID:              entry.EventID,
 			Position:             0,
 			EstimatedWaitSeconds: 0,
 			Status:               "admitted",
 			EnqueuedAt:           entry.EnqueuedAt,
 			LastHeartbeat:        entry.LastHeartbeat,
+			AdmissionToken:       m.getAdmissionToken(ctx, queueID, true),
 		}, nil
 	}
 
@@ -69,6 +74,7 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 		// Entry not found in queue, might have been removed
 		return &QueueStatus{
 			QueueID:              queueID,
+			EventID:              entry.EventID,
 			Position:             0,
 			EstimatedWaitSeconds: 0,
 			Status:               "expired",
@@ -95,6 +101,7 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 
 	return &QueueStatus{
 		QueueID:              queueID,
+		EventID:              entry.EventID,
 		Position:             position,
 		EstimatedWaitSeconds: estimatedWait,
 		Status:               "waiting",
@@ -104,6 +111,51 @@ func (m *Manager) GetQueueStatus(queueID string) (*QueueStatus, error) {
 	}, nil
 }
 
+// GetQueueEntry retrieves a queue entry by its queue ID
+func (m *Manager) GetQueueEntry(queueID string) (*QueueEntry, error) {
+	if queueID == "" {
+		return nil, fmt.Errorf("queue_id is required")
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 2*time.Second)
+	defer cancel()
+
+	entryData, err := m.redisClient.GetClient().Get(ctx, QueueEntryKey(queueID)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, queueID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve queue entry: %w", err)
+	}
+
+	return DeserializeQueueEntry(entryData)
+}
+
+// getAdmissionToken returns the metadata of the token issued to an admitted
+// entry, or nil if none is stored (e.g. the token has expired). With
+// withToken, the bearer token is unsealed and included, so a client that
+// missed a response can read it again until it expires.
+func (m *Manager) getAdmissionToken(ctx context.Context, queueID string, withToken bool) *AdmissionToken {
+	data, err := m.redisClient.GetClient().Get(ctx, QueueAdmissionTokenKey(queueID)).Result()
+	if err != nil {
+		return nil
+	}
+
+	var admissionToken AdmissionToken
+	if err := json.Unmarshal([]byte(data), &admissionToken); err != nil {
+		return nil
+	}
+	if withToken && m.tokenSealer != nil && admissionToken.SealedToken != "" {
+		tokenString, err := m.tokenSealer.Open(admissionToken.SealedToken, queueID)
+		if err != nil {
+			slog.Warn("failed to unseal admission token", "queue_id", queueID, "error", err)
+		}
+		admissionToken.Token = tokenString
+	}
+	admissionToken.SealedToken = ""
+	return &admissionToken
+}
+
 // calculateEstimatedWait calculates the estimated wait time in seconds
 func (m *Manager) calculateEstimatedWait(eventID string, position int, priorityBucket string) int {
 	// Get event configuration for release rate
@@ -114,7 +166,11 @@ func (m *Manager) calculateEstimatedWait(eventID string, position int, priorityB
 			ReleaseRate: 10,
 		}
 	}
+	return estimateWait(config, position, priorityBucket)
+}
 
+// estimateWait estimates the wait in seconds at position under config
+func estimateWait(config *EventConfig, position int, priorityBucket string) int {
 	// Calculate based on position and release rate
 	// If release rate is 10 users/second, position 50 means ~5 seconds wait
 	if config.ReleaseRate <= 0 {
@@ -133,3 +189,155 @@ func (m *Manager) calculateEstimatedWait(eventID string, position int, priorityB
 
 	return estimatedSeconds
 }
+
+// GetQueueStatuses computes the statuses of several entries without
+// modifying them. Each event's queue is read once for all of its entries,
+// so refreshing every watcher of an event costs a single scan. Missing
+// entries are omitted from the result. With withToken, admission tokens are
+// included; only pass it for the clients themselves.
+func (m *Manager) GetQueueStatuses(queueIDs []string, withToken bool) (map[string]*QueueStatus, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 5*time.Second)
+	defer cancel()
+
+	entries, err := m.loadEntries(ctx, queueIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check admission of every entry in one round trip
+	pipe := m.redisClient.GetClient().Pipeline()
+	admitted := make(map[string]*redis.BoolCmd, len(entries))
+	for queueID, entry := range entries {
+		admitted[queueID] = pipe.SIsMember(ctx, QueueAdmittedKey(entry.EventID), queueID)
+	}
+	if len(admitted) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to check admission status: %w", err)
+		}
+	}
+
+	snapshots := make(map[string]*positionSnapshot)
+	configs := make(map[string]*EventConfig)
+	statuses := make(map[string]*QueueStatus, len(entries))
+	for queueID, entry := range entries {
+		status := &QueueStatus{
+			QueueID:       queueID,
+			EventID:       entry.EventID,
+			Status:        "expired",
+			EnqueuedAt:    entry.EnqueuedAt,
+			LastHeartbeat: entry.LastHeartbeat,
+		}
+		statuses[queueID] = status
+
+		if time.Since(entry.LastHeartbeat) > QueueEntryTTL {
+			continue
+		}
+		if admitted[queueID].Val() {
+			status.Status = "admitted"
+			status.AdmissionToken = m.getAdmissionToken(ctx, queueID, withToken)
+			continue
+		}
+
+		snapshot, ok := snapshots[entry.EventID]
+		if !ok {
+			snapshot, err = m.loadPositions(ctx, entry.EventID)
+			if err != nil {
+				return nil, err
+			}
+			snapshots[entry.EventID] = snapshot
+		}
+		position := snapshot.position(queueID, entry.PriorityBucket)
+		if position < 0 {
+			// Entry not found in queue, might have been removed
+			continue
+		}
+
+		config, ok := configs[entry.EventID]
+		if !ok {
+			if config, err = m.GetEventConfig(entry.EventID); err != nil {
+				config = &EventConfig{ReleaseRate: 10}
+			}
+			configs[entry.EventID] = config
+		}
+
+		totalInQueue := snapshot.size(entry.PriorityBucket)
+		status.Status = "waiting"
+		status.Position = position
+		status.EstimatedWaitSeconds = estimateWait(config, position, entry.PriorityBucket)
+		status.TotalInQueue = &totalInQueue
+	}
+
+	return statuses, nil
+}
+
+// positionSnapshot is the order of an event's buckets at one point in time
+type positionSnapshot struct {
+	order map[string][]string       // bucket -> queue IDs in release order
+	index map[string]map[string]int // bucket -> queue ID -> 0-based index
+}
+
+// snapshotBuckets are the buckets with a queue of their own; every other
+// bucket waits in the FIFO queue
+var snapshotBuckets = []string{"high", "normal", DeprioritizedBucket, ReviewBucket, ShadowBucket}
+
+// loadPositions reads the order of every bucket of an event in one round trip
+func (m *Manager) loadPositions(ctx context.Context, eventID string) (*positionSnapshot, error) {
+	pipe := m.redisClient.GetClient().Pipeline()
+	cmds := make(map[string]*redis.StringSliceCmd, len(snapshotBuckets))
+	for _, bucket := range snapshotBuckets {
+		if bucket == "high" {
+			cmds[bucket] = pipe.ZRange(ctx, QueueSortedSetKey(eventID), 0, -1)
+		} else {
+			cmds[bucket] = pipe.LRange(ctx, bucketListKey(eventID, bucket), 0, -1)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read queue positions: %w", err)
+	}
+
+	snapshot := &positionSnapshot{
+		order: make(map[string][]string, len(cmds)),
+		index: make(map[string]map[string]int, len(cmds)),
+	}
+	for bucket, cmd := range cmds {
+		queueIDs := cmd.Val()
+		index := make(map[string]int, len(queueIDs))
+		for i, queueID := range queueIDs {
+			if _, ok := index[queueID]; !ok {
+				index[queueID] = i
+			}
+		}
+		snapshot.order[bucket] = queueIDs
+		snapshot.index[bucket] = index
+	}
+	return snapshot, nil
+}
+
+// snapshotBucket maps a priority bucket to the queue it waits in
+func snapshotBucket(priorityBucket string) string {
+	if priorityBucket == "high" || isHeldBucket(priorityBucket) {
+		return priorityBucket
+	}
+	return "normal"
+}
+
+// position returns the 1-based position of an entry, computed like
+// calculatePosition, or -1 if it is not queued
+func (s *positionSnapshot) position(queueID, priorityBucket string) int {
+	bucket := snapshotBucket(priorityBucket)
+	index, ok := s.index[bucket][queueID]
+	if !ok {
+		return -1
+	}
+	if isHeldBucket(bucket) {
+		// Behind every entry of the FIFO queue
+		return len(s.order["normal"]) + index + 1
+	}
+	return index + 1
+}
+
+// size returns the number of entries waiting in a bucket's queue, like
+// getQueueSize
+func (s *positionSnapshot) size(priorityBucket string) int {
+	return len(s.order[snapshotBucket(priorityBucket)])
+}
@@ -2,6 +2,9 @@ package queue
 
 import (
 	"testing"
+	"time"
+
+	"gatekeep/internal/token"
 )
 
 func TestGetQueueStatus_WaitingUser(t *testing.T) {
@@ -201,3 +204,139 @@ func TestCalculateEstimatedWait(t *testing.T) {
 		t.Errorf("EstimatedWaitSeconds should be >= 0, got %d", status.EstimatedWaitSeconds)
 	}
 }
+
+func TestPositionSnapshot(t *testing.T) {
+	snapshot := &positionSnapshot{order: map[string][]string{}, index: map[string]map[string]int{}}
+	for bucket, queueIDs := range map[string][]string{
+		"high":              {"h1", "h2"},
+		"normal":            {"n1", "n2", "n3"},
+		DeprioritizedBucket: {"d1"},
+	} {
+		snapshot.order[bucket] = queueIDs
+		snapshot.index[bucket] = map[string]int{}
+		for i, queueID := range queueIDs {
+			snapshot.index[bucket][queueID] = i
+		}
+	}
+
+	tests := []struct {
+		queueID, bucket string
+		position, size  int
+	}{
+		{"h2", "high", 2, 2},
+		{"n3", "normal", 3, 3},
+		{"n1", "low", 1, 3},
+		{"d1", DeprioritizedBucket, 4, 1},
+		{"n1", "high", -1, 2},
+	}
+
+	for _, tt := range tests {
+		if got := snapshot.position(tt.queueID, tt.bucket); got != tt.position {
+			t.Errorf("Expected %s in %s at position %d, got %d", tt.queueID, tt.bucket, tt.position, got)
+		}
+		if got := snapshot.size(tt.bucket); got != tt.size {
+			t.Errorf("Expected %s size %d, got %d", tt.bucket, tt.size, got)
+		}
+	}
+}
+
+func TestGetQueueStatuses_ReadOnly(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	var queueIDs []string
+	for _, device := range []string{"device-statuses-1", "device-statuses-2"} {
+		entry, err := manager.JoinQueue(JoinQueueRequest{
+			EventID:        "test-event-statuses",
+			DeviceID:       device,
+			UserID:         "user-" + device,
+			PriorityBucket: "normal",
+		})
+		if err != nil {
+			t.Fatalf("JoinQueue() failed: %v", err)
+		}
+		queueIDs = append(queueIDs, entry.QueueID)
+	}
+
+	key := QueueEntryKey(queueIDs[1])
+	before, _ := manager.redisClient.GetClient().Get(manager.ctx, key).Result()
+
+	statuses, err := manager.GetQueueStatuses(append(queueIDs, "non-existent-queue-id"), false)
+	if err != nil {
+		t.Fatalf("GetQueueStatuses() failed: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("Expected 2 statuses, got %d", len(statuses))
+	}
+	for i, queueID := range queueIDs {
+		status := statuses[queueID]
+		if status.Status != "waiting" || status.Position != i+1 {
+			t.Errorf("Expected waiting at position %d, got %s at %d", i+1, status.Status, status.Position)
+		}
+		if status.TotalInQueue == nil || *status.TotalInQueue != 2 {
+			t.Errorf("Expected total in queue 2, got %v", status.TotalInQueue)
+		}
+	}
+
+	after, _ := manager.redisClient.GetClient().Get(manager.ctx, key).Result()
+	if before != after {
+		t.Error("Expected GetQueueStatuses() not to modify entries")
+	}
+}
+
+func TestGetQueueStatus_TokenReturnedUntilExpiry(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	entry, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:  "test-event-pickup",
+		DeviceID: "device-pickup",
+	})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+	if err := manager.MarkAsAdmitted(entry.QueueID); err != nil {
+		t.Fatalf("MarkAsAdmitted() failed: %v", err)
+	}
+
+	// Store an issued token the way the release controller does
+	sealer := token.NewSealer("test-secret")
+	manager.SetTokenSealer(sealer)
+	sealed, err := sealer.Seal("raw-token", entry.QueueID)
+	if err != nil {
+		t.Fatalf("Seal() failed: %v", err)
+	}
+	client := manager.redisClient.GetClient()
+	client.Set(manager.ctx, QueueAdmissionTokenKey(entry.QueueID), `{"token_hash":"hash-1","sealed_token":"`+sealed+`","queue_id":"`+entry.QueueID+`"}`, time.Minute)
+
+	// Admin lookups do not see the token
+	statuses, err := manager.GetQueueStatuses([]string{entry.QueueID}, false)
+	if err != nil {
+		t.Fatalf("GetQueueStatuses() failed: %v", err)
+	}
+	if admission := statuses[entry.QueueID].AdmissionToken; admission == nil || admission.Token != "" || admission.SealedToken != "" {
+		t.Errorf("Expected token metadata without the token, got %+v", admission)
+	}
+
+	first, err := manager.GetQueueStatus(entry.QueueID)
+	if err != nil {
+		t.Fatalf("GetQueueStatus() failed: %v", err)
+	}
+	if first.AdmissionToken == nil || first.AdmissionToken.Token != "raw-token" {
+		t.Fatalf("Expected the token on first read, got %+v", first.AdmissionToken)
+	}
+
+	second, err := manager.GetQueueStatus(entry.QueueID)
+	if err != nil {
+		t.Fatalf("GetQueueStatus() failed: %v", err)
+	}
+	if second.AdmissionToken == nil || second.AdmissionToken.Token != "raw-token" || second.AdmissionToken.SealedToken != "" {
+		t.Errorf("Expected the token again on later reads, got %+v", second.AdmissionToken)
+	}
+}
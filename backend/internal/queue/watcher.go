@@ -0,0 +1,305 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// WatchRefreshInterval is how often every watched entry is refreshed. These
+	// refreshes double as heartbeats, so an open stream keeps the entry alive.
+	WatchRefreshInterval = 15 * time.Second
+	// watchCoalesceInterval bounds how often an event's watchers are refreshed
+	// after position-changing notifications
+	watchCoalesceInterval = 1 * time.Second
+)
+
+// Watcher pushes status changes for watched queue entries. A single Watcher
+// per instance subscribes to all queue event channels in Redis, so releases
+// performed on any instance reach clients connected to this one.
+type Watcher struct {
+	manager *Manager
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{} // event_id -> subscriptions
+	dirty  map[string]bool                       // events awaiting a refresh
+	closed bool
+}
+
+// Subscription receives status updates for a single queue entry
+type Subscription struct {
+	QueueID string
+	EventID string
+
+	watcher *Watcher
+	updates chan *QueueStatus
+	last    *QueueStatus
+	mu      sync.Mutex
+	closed  bool
+}
+
+// NewWatcher creates a new status watcher
+func NewWatcher(manager *Manager) *Watcher {
+	ctx, cancel := context.WithCancel(manager.ctx)
+	return &Watcher{
+		manager: manager,
+		ctx:     ctx,
+		cancel:  cancel,
+		subs:    make(map[string]map[*Subscription]struct{}),
+		dirty:   make(map[string]bool),
+	}
+}
+
+// Start subscribes to queue events and starts the refresh loop
+func (w *Watcher) Start() {
+	pubsub := w.manager.redisClient.GetClient().PSubscribe(w.ctx, QueueEventsChannel("*"))
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer pubsub.Close()
+		w.run(pubsub.Channel())
+	}()
+}
+
+// Stop stops the watcher and closes all subscriptions
+func (w *Watcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	w.closed = true
+	subs := w.subs
+	w.subs = make(map[string]map[*Subscription]struct{})
+	w.mu.Unlock()
+
+	for _, eventSubs := range subs {
+		for sub := range eventSubs {
+			sub.close()
+		}
+	}
+}
+
+// Watch starts watching a queue entry. The current status is delivered
+// immediately; later updates are delivered only when the status changes.
+func (w *Watcher) Watch(queueID string) (*Subscription, error) {
+	entry, err := w.manager.GetQueueEntry(queueID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{
+		QueueID: queueID,
+		EventID: entry.EventID,
+		watcher: w,
+		updates: make(chan *QueueStatus, 1),
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil, errors.New("watcher is stopped")
+	}
+	if w.subs[entry.EventID] == nil {
+		w.subs[entry.EventID] = make(map[*Subscription]struct{})
+	}
+	w.subs[entry.EventID][sub] = struct{}{}
+	w.mu.Unlock()
+
+	// Opening a stream counts as a heartbeat
+	w.refresh([]*Subscription{sub}, true)
+	return sub, nil
+}
+
+// Updates returns the channel on which status changes are delivered. Only
+// the latest status is kept if the reader falls behind. The channel is
+// closed when the subscription or watcher is closed.
+func (s *Subscription) Updates() <-chan *QueueStatus {
+	return s.updates
+}
+
+// Close stops delivering updates for this subscription
+func (s *Subscription) Close() {
+	s.watcher.mu.Lock()
+	if eventSubs, ok := s.watcher.subs[s.EventID]; ok {
+		delete(eventSubs, s)
+		if len(eventSubs) == 0 {
+			delete(s.watcher.subs, s.EventID)
+		}
+	}
+	s.watcher.mu.Unlock()
+
+	s.close()
+}
+
+// close closes the updates channel exactly once
+func (s *Subscription) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.updates)
+	}
+}
+
+// deliver sends status if it differs from the last delivered one, replacing
+// any undelivered update
+func (s *Subscription) deliver(status *QueueStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || !statusChanged(s.last, status) {
+		return
+	}
+	s.last = status
+
+	select {
+	case <-s.updates:
+	default:
+	}
+	s.updates <- status
+}
+
+// run dispatches pub/sub notifications and periodic refreshes
+func (w *Watcher) run(messages <-chan *redis.Message) {
+	coalesce := time.NewTicker(watchCoalesceInterval)
+	defer coalesce.Stop()
+	refreshAll := time.NewTicker(WatchRefreshInterval)
+	defer refreshAll.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			w.handleMessage(msg.Channel, msg.Payload)
+		case <-coalesce.C:
+			w.mu.Lock()
+			dirty := w.dirty
+			w.dirty = make(map[string]bool)
+			w.mu.Unlock()
+			for eventID := range dirty {
+				w.refreshEvent(eventID, false)
+			}
+		case <-refreshAll.C:
+			for _, eventID := range w.eventIDs() {
+				w.refreshEvent(eventID, true)
+			}
+		}
+	}
+}
+
+// handleMessage processes a notification published on a queue event channel
+func (w *Watcher) handleMessage(channel, payload string) {
+	eventID := strings.TrimPrefix(channel, QueueEventsChannel(""))
+
+	var event QueueEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	eventSubs := w.subs[eventID]
+	var admitted []*Subscription
+	for sub := range eventSubs {
+		if event.Type == QueueEventAdmitted && sub.QueueID == event.QueueID {
+			admitted = append(admitted, sub)
+		}
+	}
+	if len(eventSubs) > 0 {
+		// Everyone behind a released entry moved up
+		w.dirty[eventID] = true
+	}
+	w.mu.Unlock()
+
+	// Deliver tokens without waiting for the coalesced refresh
+	if len(admitted) > 0 {
+		w.refresh(admitted, false)
+	}
+}
+
+// eventIDs returns the events that currently have watchers
+func (w *Watcher) eventIDs() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	eventIDs := make([]string, 0, len(w.subs))
+	for eventID := range w.subs {
+		eventIDs = append(eventIDs, eventID)
+	}
+	return eventIDs
+}
+
+// refreshEvent refreshes every subscription of an event
+func (w *Watcher) refreshEvent(eventID string, heartbeat bool) {
+	w.mu.Lock()
+	subs := make([]*Subscription, 0, len(w.subs[eventID]))
+	for sub := range w.subs[eventID] {
+		subs = append(subs, sub)
+	}
+	w.mu.Unlock()
+
+	if len(subs) > 0 {
+		w.refresh(subs, heartbeat)
+	}
+}
+
+// refresh recomputes the statuses of subscriptions. Statuses are computed
+// read-only with one queue scan per event; with heartbeat set, the entries'
+// heartbeats are recorded first so an open stream keeps them alive.
+func (w *Watcher) refresh(subs []*Subscription, heartbeat bool) {
+	queueIDs := make([]string, len(subs))
+	for i, sub := range subs {
+		queueIDs[i] = sub.QueueID
+	}
+
+	if heartbeat {
+		if err := w.manager.TouchEntries(queueIDs); err != nil {
+			slog.Warn("failed to record stream heartbeats", "error", err)
+		}
+	}
+
+	statuses, err := w.manager.GetQueueStatuses(queueIDs, true)
+	if err != nil {
+		// Transient failure; the next refresh will retry
+		return
+	}
+	for _, sub := range subs {
+		status, ok := statuses[sub.QueueID]
+		if !ok {
+			status = &QueueStatus{QueueID: sub.QueueID, Status: "expired"}
+		}
+		sub.deliver(status)
+	}
+}
+
+// statusChanged reports whether next differs from prev in a way clients care about
+func statusChanged(prev, next *QueueStatus) bool {
+	if prev == nil {
+		return true
+	}
+	if prev.Status != next.Status ||
+		prev.Position != next.Position ||
+		prev.EstimatedWaitSeconds != next.EstimatedWaitSeconds {
+		return true
+	}
+	if (prev.AdmissionToken == nil) != (next.AdmissionToken == nil) {
+		return true
+	}
+	if (prev.TotalInQueue == nil) != (next.TotalInQueue == nil) {
+		return true
+	}
+	return prev.TotalInQueue != nil && *prev.TotalInQueue != *next.TotalInQueue
+}
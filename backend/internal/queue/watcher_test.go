@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatusChanged(t *testing.T) {
+	total := 10
+	fewer := 9
+	base := &QueueStatus{QueueID: "q1", Position: 5, EstimatedWaitSeconds: 2, Status: "waiting", TotalInQueue: &total}
+
+	tests := []struct {
+		name string
+		prev *QueueStatus
+		next *QueueStatus
+		want bool
+	}{
+		{"first status", nil, base, true},
+		{"identical", base, &QueueStatus{QueueID: "q1", Position: 5, EstimatedWaitSeconds: 2, Status: "waiting", TotalInQueue: &total}, false},
+		{"heartbeat only", base, &QueueStatus{QueueID: "q1", Position: 5, EstimatedWaitSeconds: 2, Status: "waiting", TotalInQueue: &total, LastHeartbeat: time.Now()}, false},
+		{"position moved", base, &QueueStatus{QueueID: "q1", Position: 4, EstimatedWaitSeconds: 2, Status: "waiting", TotalInQueue: &total}, true},
+		{"queue shrank", base, &QueueStatus{QueueID: "q1", Position: 5, EstimatedWaitSeconds: 2, Status: "waiting", TotalInQueue: &fewer}, true},
+		{"admitted", base, &QueueStatus{QueueID: "q1", Status: "admitted", AdmissionToken: &AdmissionToken{TokenHash: "t"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusChanged(tt.prev, tt.next); got != tt.want {
+				t.Errorf("statusChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcher_DeliversAdmission(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	watcher := NewWatcher(manager)
+	watcher.Start()
+	defer watcher.Stop()
+
+	entry, err := manager.JoinQueue(JoinQueueRequest{
+		EventID:        "test-event-watch",
+		DeviceID:       "device-watch",
+		UserID:         "user-watch",
+		PriorityBucket: "normal",
+	})
+	if err != nil {
+		t.Fatalf("JoinQueue() failed: %v", err)
+	}
+
+	sub, err := watcher.Watch(entry.QueueID)
+	if err != nil {
+		t.Fatalf("Watch() failed: %v", err)
+	}
+	defer sub.Close()
+
+	select {
+	case status := <-sub.Updates():
+		if status.Status != "waiting" {
+			t.Fatalf("Initial status should be 'waiting', got %s", status.Status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for initial status")
+	}
+
+	if err := manager.MarkAsAdmitted(entry.QueueID); err != nil {
+		t.Fatalf("MarkAsAdmitted() failed: %v", err)
+	}
+	manager.redisClient.GetClient().Publish(manager.ctx, QueueEventsChannel("test-event-watch"),
+		`{"type":"admitted","queue_id":"`+entry.QueueID+`"}`)
+
+	select {
+	case status := <-sub.Updates():
+		if status.Status != "admitted" {
+			t.Errorf("Status should be 'admitted', got %s", status.Status)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for admission update")
+	}
+}
+
+func TestWatcher_NotFound(t *testing.T) {
+	manager, cleanup := setupTestManager(t)
+	if manager == nil {
+		return
+	}
+	defer cleanup()
+
+	watcher := NewWatcher(manager)
+	defer watcher.Stop()
+
+	if _, err := watcher.Watch("non-existent-queue-id"); err == nil {
+		t.Error("Watch() expected error for non-existent queue_id, got nil")
+	}
+}
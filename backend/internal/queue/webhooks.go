@@ -0,0 +1,18 @@
+package queue
+
+import (
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/webhook"
+)
+
+// SetWebhookDispatcher sets the dispatcher receiving join and leave events;
+// nil disables them
+func (m *Manager) SetWebhookDispatcher(dispatcher *webhook.Dispatcher) {
+	m.webhooks = dispatcher
+}
+
+// SetLifecyclePublisher sets the publisher of join and leave events; nil
+// disables them
+func (m *Manager) SetLifecyclePublisher(publisher lifecycle.Publisher) {
+	m.lifecycle = publisher
+}
@@ -33,6 +33,8 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		MaxRetries:      3,
 	})
 
+	rdb.AddHook(metricsHook{})
+
 	client := &Client{
 		rdb:    rdb,
 		config: cfg,
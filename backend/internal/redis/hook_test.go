@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"gatekeep/internal/metrics"
+)
+
+func TestMetricsHook(t *testing.T) {
+	ctx := context.Background()
+	hook := metricsHook{}
+
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error { return nil })
+	if err := process(ctx, redis.NewCmd(ctx, "hooktest")); err != nil {
+		t.Fatalf("ProcessHook() failed: %v", err)
+	}
+	pipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error { return nil })
+	if err := pipeline(ctx, []redis.Cmder{redis.NewCmd(ctx, "hooktest")}); err != nil {
+		t.Fatalf("ProcessPipelineHook() failed: %v", err)
+	}
+
+	// Deleting a series reports whether it was observed
+	if !metrics.RedisOperationDuration.DeleteLabelValues("hooktest") {
+		t.Error("Expected the command to be observed")
+	}
+	if !metrics.RedisOperationDuration.DeleteLabelValues("pipeline") {
+		t.Error("Expected the pipeline to be observed")
+	}
+}
@@ -0,0 +1,43 @@
+package release
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// isBanned reports whether the entry's device, user or IP has been banned
+// from its event since it joined
+func (c *Controller) isBanned(ctx context.Context, entry *QueueEntry) (bool, error) {
+	values := map[string]string{"device": entry.DeviceID, "user": entry.UserID, "ip": entry.ClientIP}
+
+	pipe := c.redisClient.GetClient().Pipeline()
+	cmds := make([]*redis.BoolCmd, 0, len(values))
+	for kind, value := range values {
+		if value != "" {
+			cmds = append(cmds, pipe.SIsMember(ctx, fmt.Sprintf("queue:ban:%s:%s", entry.EventID, kind), value))
+		}
+	}
+	if len(cmds) == 0 {
+		return false, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to check bans: %w", err)
+	}
+
+	for _, cmd := range cmds {
+		if cmd.Val() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dropEntry deletes a popped entry that will not be admitted
+func (c *Controller) dropEntry(ctx context.Context, entry *QueueEntry) {
+	pipe := c.redisClient.GetClient().Pipeline()
+	pipe.Del(ctx, fmt.Sprintf("queue:entry:%s", entry.QueueID))
+	pipe.SRem(ctx, fmt.Sprintf("queue:user:event:%s:%s", entry.UserID, entry.EventID), entry.QueueID)
+	_, _ = pipe.Exec(ctx)
+}
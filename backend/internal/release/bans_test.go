@@ -0,0 +1,117 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gatekeep/internal/token"
+)
+
+// enqueueTestEntry stores an entry and appends it to the event's FIFO queue
+func enqueueTestEntry(t *testing.T, c *Controller, entry QueueEntry) {
+	t.Helper()
+	ctx := context.Background()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to marshal entry: %v", err)
+	}
+	client := c.redisClient.GetClient()
+	client.Set(ctx, fmt.Sprintf("queue:entry:%s", entry.QueueID), data, time.Minute)
+	client.RPush(ctx, fmt.Sprintf("queue:list:%s", entry.EventID), entry.QueueID)
+}
+
+func TestReleaseUsers_SkipsBanned(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	eventID := "event-bans"
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-banned", EventID: eventID, DeviceID: "bad-device", UserID: "u1"})
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-ok", EventID: eventID, DeviceID: "good-device", UserID: "u2"})
+	controller.redisClient.GetClient().SAdd(ctx, fmt.Sprintf("queue:ban:%s:device", eventID), "bad-device")
+
+	released, err := controller.ReleaseUsers(eventID, 1)
+	if err != nil {
+		t.Fatalf("ReleaseUsers() failed: %v", err)
+	}
+	if released != 1 {
+		t.Fatalf("Expected 1 released, got %d", released)
+	}
+
+	admitted, _ := controller.redisClient.GetClient().SMembers(ctx, fmt.Sprintf("queue:admitted:%s", eventID)).Result()
+	if len(admitted) != 1 || admitted[0] != "q-ok" {
+		t.Errorf("Expected only q-ok admitted, got %v", admitted)
+	}
+}
+
+func TestAdmitEntry(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "event-admit"
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-first", EventID: eventID, DeviceID: "d1", UserID: "u1"})
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-second", EventID: eventID, DeviceID: "d2", UserID: "u2"})
+
+	// Admission ignores pause
+	controller.Pause()
+
+	tokenString, payload, err := controller.AdmitEntry("q-second")
+	if err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+	if tokenString == "" || payload.QueueID != "q-second" {
+		t.Errorf("Expected token for q-second, got %q (%+v)", tokenString, payload)
+	}
+
+	queued, _ := controller.redisClient.GetClient().LRange(context.Background(), fmt.Sprintf("queue:list:%s", eventID), 0, -1).Result()
+	if len(queued) != 1 || queued[0] != "q-first" {
+		t.Errorf("Expected only q-first left in queue, got %v", queued)
+	}
+
+	if _, _, err := controller.AdmitEntry("q-second"); !errors.Is(err, ErrAlreadyAdmitted) {
+		t.Errorf("Expected ErrAlreadyAdmitted, got %v", err)
+	}
+	if _, _, err := controller.AdmitEntry("q-missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAdmit_Once(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	// A release popping the entry races a manual admission of it
+	entry := &QueueEntry{QueueID: "q-race", EventID: "event-admit-race", DeviceID: "d1", EnqueuedAt: time.Now()}
+	ctx := context.Background()
+	tokenConfig := controller.tokenConfig(ctx, entry.EventID)
+
+	first, _, err := controller.admit(ctx, entry, tokenConfig)
+	if err != nil {
+		t.Fatalf("admit() failed: %v", err)
+	}
+	if _, _, err := controller.admit(ctx, entry, tokenConfig); !errors.Is(err, ErrAlreadyAdmitted) {
+		t.Fatalf("Expected ErrAlreadyAdmitted, got %v", err)
+	}
+
+	stored, err := controller.redisClient.GetClient().Get(ctx, "queue:admission:"+entry.QueueID).Result()
+	if err != nil {
+		t.Fatalf("Failed to read admission token: %v", err)
+	}
+	if !strings.Contains(stored, token.TokenHash(first)) {
+		t.Errorf("Expected the first token to be kept, got %s", stored)
+	}
+}
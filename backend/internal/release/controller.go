@@ -3,14 +3,20 @@ package release
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/metrics"
 	redisclient "gatekeep/internal/redis"
 	"gatekeep/internal/token"
+	"gatekeep/internal/webhook"
 )
 
 // Controller manages the release of users from the queue
@@ -22,6 +28,12 @@ type Controller struct {
 	wg          sync.WaitGroup
 	mu          sync.RWMutex
 
+	// webhooks receives admission and expiry events; nil disables them
+	webhooks *webhook.Dispatcher
+	// lifecycle publishes admission, token and expiry events; nil disables
+	// them
+	lifecycle lifecycle.Publisher
+
 	// Release state
 	paused          bool
 	releaseRate     int // users per second
@@ -64,6 +76,18 @@ func (c *Controller) Stop() {
 	c.wg.Wait()
 }
 
+// SetWebhookDispatcher sets the dispatcher receiving admission and expiry
+// events; nil disables them
+func (c *Controller) SetWebhookDispatcher(dispatcher *webhook.Dispatcher) {
+	c.webhooks = dispatcher
+}
+
+// SetLifecyclePublisher sets the publisher of admission, token and expiry
+// events; nil disables them
+func (c *Controller) SetLifecyclePublisher(publisher lifecycle.Publisher) {
+	c.lifecycle = publisher
+}
+
 // SetReleaseRate sets the release rate (users per second)
 func (c *Controller) SetReleaseRate(rate int) error {
 	if rate < 0 {
@@ -126,13 +150,13 @@ func (c *Controller) ReleaseUsers(eventID string, count int) (int, error) {
 	c.mu.RUnlock()
 
 	if paused {
-		return 0, fmt.Errorf("release is paused")
+		return 0, ErrPaused
 	}
 
 	// Check capacity
 	availableCapacity := maxCapacity - currentCapacity
 	if availableCapacity <= 0 {
-		return 0, fmt.Errorf("max capacity reached")
+		return 0, ErrCapacityReached
 	}
 
 	// Limit count to available capacity
@@ -148,14 +172,23 @@ func (c *Controller) ReleaseUsers(eventID string, count int) (int, error) {
 	released := 0
 	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
 	defer cancel()
+	defer c.recordQueueLength(ctx, eventID)
+	metrics.ReleaseRate.WithLabelValues(eventID).Set(float64(releaseRate))
+
+	tokenConfig := c.tokenConfig(ctx, eventID)
 
-	// Release users from queue
-	for i := 0; i < count; i++ {
+	// Release users from queue; banned entries are dropped without using up count
+	for released < count {
 		// Try high priority first (sorted set)
 		queueID, err := c.popFromPriorityQueue(ctx, eventID)
 		if err == redis.Nil {
 			// No high priority users, try normal queue
 			queueID, err = c.popFromNormalQueue(ctx, eventID)
+			if err == redis.Nil {
+				// Then the entries deprioritized by attestation; entries held
+				// for review or shadow-banned are never popped
+				queueID, err = c.popFromDeprioritizedQueue(ctx, eventID)
+			}
 			if err == redis.Nil {
 				// Queue is empty
 				break
@@ -167,38 +200,186 @@ func (c *Controller) ReleaseUsers(eventID string, count int) (int, error) {
 			return released, fmt.Errorf("failed to pop from priority queue: %w", err)
 		}
 
-		// Get queue entry
+		// Get queue entry; entries that expired while queued are skipped
+		// without using up count
 		entry, err := c.getQueueEntry(ctx, queueID)
+		if err == redis.Nil {
+			slog.Debug("skipping expired queue entry", "event_id", eventID, "queue_id", queueID)
+			metrics.QueueEvictions.WithLabelValues(eventID, metrics.EvictionExpired).Inc()
+			lifecycle.Emit(c.lifecycle, lifecycle.Event{Type: lifecycle.HeartbeatLost, EventID: eventID, QueueID: queueID})
+			c.webhooks.Emit(webhook.Event{Type: webhook.EventExpired, EventID: eventID, QueueID: queueID})
+			continue
+		}
 		if err != nil {
 			return released, fmt.Errorf("failed to get queue entry: %w", err)
 		}
 
-		// Generate admission token
-		admissionToken, err := c.tokenGen.GenerateToken(entry.EventID, entry.DeviceID, entry.UserID, entry.QueueID)
+		// Drop entries banned after they joined
+		banned, err := c.isBanned(ctx, entry)
 		if err != nil {
-			return released, fmt.Errorf("failed to generate token: %w", err)
+			return released, err
 		}
-
-		// Mark as admitted
-		if err := c.markAsAdmitted(ctx, eventID, queueID); err != nil {
-			return released, fmt.Errorf("failed to mark as admitted: %w", err)
+		if banned {
+			slog.Info("dropping banned queue entry", "event_id", eventID, "queue_id", queueID)
+			metrics.QueueEvictions.WithLabelValues(eventID, metrics.EvictionBanned).Inc()
+			c.dropEntry(ctx, entry)
+			continue
 		}
 
-		// Update capacity
-		c.mu.Lock()
-		c.currentCapacity++
-		c.mu.Unlock()
+		if _, _, err := c.admit(ctx, entry, tokenConfig); errors.Is(err, ErrAlreadyAdmitted) {
+			// Admitted manually while it was being popped
+			continue
+		} else if err != nil {
+			return released, err
+		}
 
 		released++
-		_ = admissionToken // Token is generated and stored, can be returned if needed
 	}
 
+	slog.Info("users released", "event_id", eventID, "limit", count, "released", released)
+
 	// Save state after release
-	_ = c.SaveState()
+	c.saveState()
 
 	return released, nil
 }
 
+// AdmitEntry admits a specific queue entry immediately, ahead of the queue.
+// It is an operator override: pause and capacity limits do not apply, but the
+// admission counts against capacity.
+func (c *Controller) AdmitEntry(queueID string) (string, *token.TokenPayload, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	entry, err := c.getQueueEntry(ctx, queueID)
+	if err == redis.Nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrNotFound, queueID)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get queue entry: %w", err)
+	}
+
+	admittedKey := fmt.Sprintf("queue:admitted:%s", entry.EventID)
+	admitted, err := c.redisClient.GetClient().SIsMember(ctx, admittedKey, queueID).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check admission: %w", err)
+	}
+	if admitted {
+		return "", nil, fmt.Errorf("%w: %s", ErrAlreadyAdmitted, queueID)
+	}
+
+	// Take the entry out of whichever queue holds it
+	pipe := c.redisClient.GetClient().Pipeline()
+	pipe.LRem(ctx, fmt.Sprintf("queue:list:%s", entry.EventID), 0, queueID)
+	pipe.LRem(ctx, fmt.Sprintf("queue:deprioritized:%s", entry.EventID), 0, queueID)
+	pipe.LRem(ctx, fmt.Sprintf("queue:review:%s", entry.EventID), 0, queueID)
+	pipe.LRem(ctx, fmt.Sprintf("queue:shadow:%s", entry.EventID), 0, queueID)
+	pipe.ZRem(ctx, fmt.Sprintf("queue:zset:%s", entry.EventID), queueID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", nil, fmt.Errorf("failed to dequeue entry: %w", err)
+	}
+
+	tokenString, payload, err := c.admit(ctx, entry, c.tokenConfig(ctx, entry.EventID))
+	if err != nil {
+		return "", nil, err
+	}
+
+	slog.Info("entry admitted manually", "event_id", entry.EventID, "queue_id", queueID)
+	c.recordQueueLength(ctx, entry.EventID)
+
+	c.saveState()
+	return tokenString, payload, nil
+}
+
+// admit marks an entry already taken off the queue as admitted, issues its
+// token and notifies its watchers. Adding the entry to the admitted set is
+// the guard against admitting it twice: only the caller that adds it issues
+// a token, others get ErrAlreadyAdmitted.
+func (c *Controller) admit(ctx context.Context, entry *QueueEntry, tokenConfig *eventTokenConfig) (string, *token.TokenPayload, error) {
+	added, err := c.markAsAdmitted(ctx, entry.EventID, entry.QueueID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to mark as admitted: %w", err)
+	}
+	if !added {
+		return "", nil, fmt.Errorf("%w: %s", ErrAlreadyAdmitted, entry.QueueID)
+	}
+
+	admissionToken, payload, err := c.issueAdmission(ctx, entry, tokenConfig)
+	if err != nil {
+		// Release the claim so the entry can be admitted again
+		c.redisClient.GetClient().SRem(ctx, fmt.Sprintf("queue:admitted:%s", entry.EventID), entry.QueueID)
+		return "", nil, err
+	}
+	return admissionToken, payload, nil
+}
+
+// issueAdmission issues the token of an entry marked as admitted and notifies
+// its watchers
+func (c *Controller) issueAdmission(ctx context.Context, entry *QueueEntry, tokenConfig *eventTokenConfig) (string, *token.TokenPayload, error) {
+	// Generate admission token
+	admissionToken, payload, err := c.tokenGen.Issue(token.IssueRequest{
+		EventID:       entry.EventID,
+		DeviceID:      entry.DeviceID,
+		UserID:        entry.UserID,
+		QueueID:       entry.QueueID,
+		TTL:           tokenConfig.tokenTTL(),
+		KeyThumbprint: entry.KeyThumbprint,
+		Entitlements:  tokenConfig.entitlements(entry.PriorityBucket),
+		Metadata:      tokenConfig.metadata(entry.Metadata),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Keep the token for status, heartbeat and streams until it expires
+	if err := c.storeAdmissionToken(ctx, entry.QueueID, admissionToken, payload); err != nil {
+		return "", nil, fmt.Errorf("failed to store admission token: %w", err)
+	}
+
+	// Notify connected clients; streams fall back to periodic refresh
+	if err := c.publishAdmitted(ctx, entry.EventID, entry.QueueID); err != nil {
+		slog.Debug("failed to notify watchers of admission", "event_id", entry.EventID, "queue_id", entry.QueueID, "error", err)
+	}
+
+	wait := time.Since(entry.EnqueuedAt)
+	metrics.AdmissionCount.WithLabelValues(entry.EventID).Inc()
+	metrics.WaitTime.WithLabelValues(entry.EventID).Observe(wait.Seconds())
+
+	tokenHash := token.TokenHash(admissionToken)
+	lifecycle.Emit(c.lifecycle, lifecycle.Event{
+		Type:     lifecycle.Admitted,
+		EventID:  entry.EventID,
+		QueueID:  entry.QueueID,
+		DeviceID: entry.DeviceID,
+		UserID:   entry.UserID,
+		Metadata: entry.Metadata,
+		Details: map[string]string{
+			"priority_bucket": entry.PriorityBucket,
+			"wait_seconds":    strconv.Itoa(int(wait.Seconds())),
+		},
+	})
+	c.emitTokenIssued(payload, tokenHash, false)
+
+	expiresAt := payload.ExpiresAt
+	c.webhooks.Emit(webhook.Event{
+		Type:      webhook.EventAdmissionGranted,
+		EventID:   entry.EventID,
+		QueueID:   entry.QueueID,
+		DeviceID:  entry.DeviceID,
+		UserID:    entry.UserID,
+		Metadata:  entry.Metadata,
+		TokenHash: tokenHash,
+		ExpiresAt: &expiresAt,
+	})
+
+	// Update capacity
+	c.mu.Lock()
+	c.currentCapacity++
+	c.mu.Unlock()
+
+	return admissionToken, payload, nil
+}
+
 // popFromPriorityQueue pops a user from the priority queue (sorted set)
 func (c *Controller) popFromPriorityQueue(ctx context.Context, eventID string) (string, error) {
 	zsetKey := fmt.Sprintf("queue:zset:%s", eventID)
@@ -222,6 +403,12 @@ func (c *Controller) popFromNormalQueue(ctx context.Context, eventID string) (st
 	return queueID, nil
 }
 
+// popFromDeprioritizedQueue pops a user from the deprioritized queue (list)
+func (c *Controller) popFromDeprioritizedQueue(ctx context.Context, eventID string) (string, error) {
+	listKey := fmt.Sprintf("queue:deprioritized:%s", eventID)
+	return c.redisClient.GetClient().LPop(ctx, listKey).Result()
+}
+
 // getQueueEntry retrieves a queue entry from Redis
 func (c *Controller) getQueueEntry(ctx context.Context, queueID string) (*QueueEntry, error) {
 	entryKey := fmt.Sprintf("queue:entry:%s", queueID)
@@ -238,10 +425,11 @@ func (c *Controller) getQueueEntry(ctx context.Context, queueID string) (*QueueE
 	return &entry, nil
 }
 
-// markAsAdmitted marks a user as admitted
-func (c *Controller) markAsAdmitted(ctx context.Context, eventID, queueID string) error {
+// markAsAdmitted marks a user as admitted, reporting false if it already was
+func (c *Controller) markAsAdmitted(ctx context.Context, eventID, queueID string) (bool, error) {
 	admittedKey := fmt.Sprintf("queue:admitted:%s", eventID)
-	return c.redisClient.GetClient().SAdd(ctx, admittedKey, queueID).Err()
+	added, err := c.redisClient.GetClient().SAdd(ctx, admittedKey, queueID).Result()
+	return added == 1, err
 }
 
 // releaseScheduler runs the release scheduler goroutine
@@ -274,12 +462,15 @@ func (c *Controller) releaseScheduler() {
 
 // QueueEntry represents a queue entry (imported from queue package structure)
 type QueueEntry struct {
-	QueueID        string    `json:"queue_id"`
-	EventID        string    `json:"event_id"`
-	DeviceID       string    `json:"device_id"`
-	UserID         string    `json:"user_id"`
-	Position       int       `json:"position"`
-	EnqueuedAt     time.Time `json:"enqueued_at"`
-	LastHeartbeat  time.Time `json:"last_heartbeat"`
-	PriorityBucket string    `json:"priority_bucket"`
+	QueueID        string            `json:"queue_id"`
+	EventID        string            `json:"event_id"`
+	DeviceID       string            `json:"device_id"`
+	UserID         string            `json:"user_id"`
+	Position       int               `json:"position"`
+	EnqueuedAt     time.Time         `json:"enqueued_at"`
+	LastHeartbeat  time.Time         `json:"last_heartbeat"`
+	PriorityBucket string            `json:"priority_bucket"`
+	ClientIP       string            `json:"client_ip,omitempty"`
+	KeyThumbprint  string            `json:"key_thumbprint,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
 }
@@ -2,6 +2,7 @@ package release
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -222,6 +223,97 @@ func TestReleaseUsers_RespectsCapacity(t *testing.T) {
 	}
 }
 
+func TestReleaseUsers_DeprioritizedLast(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	eventID := "event-deprioritized"
+
+	// The deprioritized entry joined first but is released after the list
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-suspect", EventID: eventID, DeviceID: "d1", PriorityBucket: "deprioritized"})
+	client := controller.redisClient.GetClient()
+	client.LRem(ctx, fmt.Sprintf("queue:list:%s", eventID), 0, "q-suspect")
+	client.RPush(ctx, fmt.Sprintf("queue:deprioritized:%s", eventID), "q-suspect")
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-trusted", EventID: eventID, DeviceID: "d2"})
+
+	if _, err := controller.ReleaseUsers(eventID, 1); err != nil {
+		t.Fatalf("ReleaseUsers() failed: %v", err)
+	}
+	admitted, _ := client.SMembers(ctx, fmt.Sprintf("queue:admitted:%s", eventID)).Result()
+	if len(admitted) != 1 || admitted[0] != "q-trusted" {
+		t.Fatalf("Expected q-trusted admitted first, got %v", admitted)
+	}
+
+	if _, err := controller.ReleaseUsers(eventID, 1); err != nil {
+		t.Fatalf("ReleaseUsers() failed: %v", err)
+	}
+	if ok, _ := client.SIsMember(ctx, fmt.Sprintf("queue:admitted:%s", eventID), "q-suspect").Result(); !ok {
+		t.Error("Expected q-suspect admitted once the list is empty")
+	}
+}
+
+func TestReleaseUsers_SkipsHeldEntries(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	eventID := "event-held"
+	client := controller.redisClient.GetClient()
+	for _, bucket := range []string{"review", "shadow"} {
+		queueID := "q-" + bucket
+		enqueueTestEntry(t, controller, QueueEntry{QueueID: queueID, EventID: eventID, DeviceID: bucket, PriorityBucket: bucket})
+		client.LRem(ctx, fmt.Sprintf("queue:list:%s", eventID), 0, queueID)
+		client.RPush(ctx, fmt.Sprintf("queue:%s:%s", bucket, eventID), queueID)
+	}
+
+	released, err := controller.ReleaseUsers(eventID, 10)
+	if err != nil {
+		t.Fatalf("ReleaseUsers() failed: %v", err)
+	}
+	if released != 0 {
+		t.Errorf("Expected held entries not to be released, released %d", released)
+	}
+
+	// An admin can still admit an entry under review
+	if _, _, err := controller.AdmitEntry("q-review"); err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+	if n, _ := client.LLen(ctx, fmt.Sprintf("queue:review:%s", eventID)).Result(); n != 0 {
+		t.Errorf("Expected admitted entry removed from review, %d left", n)
+	}
+}
+
+func TestReleaseUsers_SkipsExpiredEntries(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	eventID := "event-expired"
+	client := controller.redisClient.GetClient()
+
+	// The entry's data expired while its id was still queued
+	client.RPush(ctx, fmt.Sprintf("queue:list:%s", eventID), "q-expired")
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-live", EventID: eventID, DeviceID: "device-live"})
+
+	released, err := controller.ReleaseUsers(eventID, 1)
+	if err != nil {
+		t.Fatalf("ReleaseUsers() failed: %v", err)
+	}
+	if released != 1 {
+		t.Errorf("Expected the live entry to be released, released %d", released)
+	}
+}
+
 func TestDecrementCapacity(t *testing.T) {
 	controller, cleanup := setupTestController(t)
 	if controller == nil {
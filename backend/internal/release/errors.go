@@ -0,0 +1,15 @@
+package release
+
+import "errors"
+
+// Sentinel errors returned by the release controller
+var (
+	// ErrPaused is returned when releasing users while releases are paused
+	ErrPaused = errors.New("release is paused")
+	// ErrCapacityReached is returned when no admission capacity is left
+	ErrCapacityReached = errors.New("max capacity reached")
+	// ErrNotFound is returned when admitting a queue entry that does not exist
+	ErrNotFound = errors.New("queue entry not found")
+	// ErrAlreadyAdmitted is returned when admitting an entry a second time
+	ErrAlreadyAdmitted = errors.New("queue entry already admitted")
+)
@@ -0,0 +1,32 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gatekeep/internal/metrics"
+)
+
+// recordQueueLength sets the queue length gauge to the number of entries
+// waiting in all of the event's buckets
+func (c *Controller) recordQueueLength(ctx context.Context, eventID string) {
+	pipe := c.redisClient.GetClient().Pipeline()
+	cmds := []interface{ Val() int64 }{
+		pipe.ZCard(ctx, fmt.Sprintf("queue:zset:%s", eventID)),
+		pipe.LLen(ctx, fmt.Sprintf("queue:list:%s", eventID)),
+		pipe.LLen(ctx, fmt.Sprintf("queue:deprioritized:%s", eventID)),
+		pipe.LLen(ctx, fmt.Sprintf("queue:review:%s", eventID)),
+		pipe.LLen(ctx, fmt.Sprintf("queue:shadow:%s", eventID)),
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		slog.Debug("failed to count queue entries", "event_id", eventID, "error", err)
+		return
+	}
+
+	var length int64
+	for _, cmd := range cmds {
+		length += cmd.Val()
+	}
+	metrics.QueueLength.WithLabelValues(eventID).Set(float64(length))
+}
@@ -0,0 +1,70 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gatekeep/internal/token"
+)
+
+// admissionToken mirrors queue.AdmissionToken as stored in Redis. The
+// bearer token is only stored sealed.
+type admissionToken struct {
+	TokenHash   string    `json:"token_hash"`
+	SealedToken string    `json:"sealed_token"`
+	EventID     string    `json:"event_id"`
+	DeviceID    string    `json:"device_id"`
+	UserID      string    `json:"user_id,omitempty"`
+	QueueID     string    `json:"queue_id"`
+	IssuedAt    time.Time `json:"issued_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// queueEvent mirrors queue.QueueEvent as published on the event channel
+type queueEvent struct {
+	Type    string `json:"type"`
+	QueueID string `json:"queue_id,omitempty"`
+}
+
+// storeAdmissionToken stores an issued token, sealed, with its metadata under
+// the queue entry. Status reads return it until it expires, so a client that
+// missed a response or a stream update can read it again.
+func (c *Controller) storeAdmissionToken(ctx context.Context, queueID, tokenString string, payload *token.TokenPayload) error {
+	sealed, err := c.tokenGen.Seal(tokenString, queueID)
+	if err != nil {
+		return fmt.Errorf("failed to seal admission token: %w", err)
+	}
+	data, err := json.Marshal(admissionToken{
+		TokenHash:   token.TokenHash(tokenString),
+		SealedToken: sealed,
+		EventID:     payload.EventID,
+		DeviceID:    payload.DeviceID,
+		UserID:      payload.UserID,
+		QueueID:     payload.QueueID,
+		IssuedAt:    payload.IssuedAt,
+		ExpiresAt:   payload.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal admission token: %w", err)
+	}
+
+	ttl := time.Until(payload.ExpiresAt)
+	if ttl <= 0 {
+		ttl = token.DefaultTokenTTL
+	}
+
+	return c.redisClient.GetClient().Set(ctx, fmt.Sprintf("queue:admission:%s", queueID), data, ttl).Err()
+}
+
+// publishAdmitted announces a release on the event's queue channel
+func (c *Controller) publishAdmitted(ctx context.Context, eventID, queueID string) error {
+	data, err := json.Marshal(queueEvent{Type: "admitted", QueueID: queueID})
+	if err != nil {
+		return err
+	}
+
+	channel := fmt.Sprintf("queue:events:%s", eventID)
+	return c.redisClient.GetClient().Publish(ctx, channel, data).Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -29,6 +30,14 @@ func (c *Controller) SaveState() error {
 	return c.redisClient.GetClient().Set(ctx, ReleaseStateKey, stateJSON, 0).Err()
 }
 
+// saveState saves the release state, logging failures: the in-memory state
+// stays authoritative and is saved again by the next change
+func (c *Controller) saveState() {
+	if err := c.SaveState(); err != nil {
+		slog.Warn("failed to save release state", "error", err)
+	}
+}
+
 // LoadState loads the release state from Redis
 func (c *Controller) LoadState() error {
 	ctx, cancel := context.WithTimeout(c.ctx, 2*time.Second)
@@ -0,0 +1,130 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"gatekeep/internal/lifecycle"
+	"gatekeep/internal/token"
+)
+
+// eventTokenConfig mirrors the token settings of queue.EventConfig. Zero
+// values mean the token package defaults apply.
+type eventTokenConfig struct {
+	TokenTTLSeconds         int                           `json:"token_ttl_seconds"`
+	MaxTokenLifetimeSeconds int                           `json:"max_token_lifetime_seconds"`
+	Entitlements            token.Entitlements            `json:"entitlements"`
+	BucketEntitlements      map[string]token.Entitlements `json:"bucket_entitlements"`
+	PriorityBuckets         []string                      `json:"priority_buckets"`
+	TokenMetadataKeys       []string                      `json:"token_metadata_keys"`
+}
+
+// assignedBuckets mirrors the queue buckets entries are placed in by the
+// service rather than by the client
+var assignedBuckets = []string{"normal", "deprioritized", "review", "shadow"}
+
+// tokenTTL returns the lifetime of issued tokens
+func (tc *eventTokenConfig) tokenTTL() time.Duration {
+	return time.Duration(tc.TokenTTLSeconds) * time.Second
+}
+
+// maxTokenLifetime returns the bound on a token's lifetime across refreshes
+func (tc *eventTokenConfig) maxTokenLifetime() time.Duration {
+	return time.Duration(tc.MaxTokenLifetimeSeconds) * time.Second
+}
+
+// entitlements returns the event's entitlements with the bucket's overrides.
+// Overrides apply only to buckets the event offers to clients or the service
+// assigns, so entries with any other client-sent bucket get the event's.
+func (tc *eventTokenConfig) entitlements(bucket string) token.Entitlements {
+	var overrides token.Entitlements
+	if slices.Contains(tc.PriorityBuckets, bucket) || slices.Contains(assignedBuckets, bucket) {
+		overrides = tc.BucketEntitlements[bucket]
+	}
+	return token.MergeEntitlements(tc.Entitlements, overrides)
+}
+
+// metadata returns the allow-listed keys of an entry's metadata, or nil if
+// there are none
+func (tc *eventTokenConfig) metadata(metadata map[string]string) map[string]string {
+	var allowed map[string]string
+	for _, key := range tc.TokenMetadataKeys {
+		value, ok := metadata[key]
+		if !ok {
+			continue
+		}
+		if allowed == nil {
+			allowed = make(map[string]string, len(tc.TokenMetadataKeys))
+		}
+		allowed[key] = value
+	}
+	return allowed
+}
+
+// tokenConfig returns an event's token settings. A missing or unreadable
+// config yields the defaults.
+func (c *Controller) tokenConfig(ctx context.Context, eventID string) *eventTokenConfig {
+	config := &eventTokenConfig{}
+	data, err := c.redisClient.GetClient().Get(ctx, fmt.Sprintf("queue:config:%s", eventID)).Result()
+	if err != nil {
+		return config
+	}
+	if err := json.Unmarshal([]byte(data), config); err != nil {
+		return &eventTokenConfig{}
+	}
+	return config
+}
+
+// RefreshAdmission issues a replacement for a verified admission token using
+// the event's token settings, keeping its entitlements. Only the caller gets
+// the new token; status reads report its metadata.
+//
+// claim retires the old token. It is called once the refresh is known to be
+// allowed and before the new token is issued, and must fail for a token that
+// was already claimed, so concurrent refreshes issue at most one replacement.
+func (c *Controller) RefreshAdmission(old *token.TokenPayload, claim func() error) (string, *token.TokenPayload, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	config := c.tokenConfig(ctx, old.EventID)
+	if err := token.CheckRefreshable(old, config.maxTokenLifetime()); err != nil {
+		return "", nil, err
+	}
+	if err := claim(); err != nil {
+		return "", nil, err
+	}
+
+	tokenString, payload, err := c.tokenGen.Refresh(old, config.tokenTTL(), config.maxTokenLifetime())
+	if err != nil {
+		return "", nil, err
+	}
+
+	if old.QueueID != "" {
+		if err := c.storeAdmissionToken(ctx, old.QueueID, tokenString, payload); err != nil {
+			return "", nil, fmt.Errorf("failed to store admission token: %w", err)
+		}
+	}
+
+	c.emitTokenIssued(payload, token.TokenHash(tokenString), true)
+	return tokenString, payload, nil
+}
+
+// emitTokenIssued publishes a token_issued event for an admission token
+func (c *Controller) emitTokenIssued(payload *token.TokenPayload, tokenHash string, refresh bool) {
+	lifecycle.Emit(c.lifecycle, lifecycle.Event{
+		Type:      lifecycle.TokenIssued,
+		EventID:   payload.EventID,
+		QueueID:   payload.QueueID,
+		DeviceID:  payload.DeviceID,
+		UserID:    payload.UserID,
+		TokenHash: tokenHash,
+		Details: map[string]string{
+			"expires_at": payload.ExpiresAt.UTC().Format(time.RFC3339),
+			"refresh":    strconv.FormatBool(refresh),
+		},
+	})
+}
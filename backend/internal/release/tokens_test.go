@@ -0,0 +1,173 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gatekeep/internal/token"
+)
+
+func TestAdmitEntry_UsesEventTokenTTL(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "event-ttl"
+	controller.redisClient.GetClient().Set(context.Background(), fmt.Sprintf("queue:config:%s", eventID),
+		`{"event_id":"event-ttl","enabled":true,"token_ttl_seconds":600}`, 0)
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-ttl", EventID: eventID, DeviceID: "d1"})
+
+	_, payload, err := controller.AdmitEntry("q-ttl")
+	if err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+	if ttl := payload.ExpiresAt.Sub(payload.IssuedAt); ttl != 10*time.Minute {
+		t.Errorf("Expected 10m TTL, got %v", ttl)
+	}
+}
+
+func TestAdmitEntry_EmbedsBucketEntitlements(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "event-entitlements"
+	controller.redisClient.GetClient().Set(context.Background(), fmt.Sprintf("queue:config:%s", eventID),
+		`{"event_id":"event-entitlements","enabled":true,"entitlements":{"max_tickets":4,"sections":["A"]},`+
+			`"bucket_entitlements":{"vip":{"max_tickets":8}},"priority_buckets":["vip"]}`, 0)
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-vip", EventID: eventID, DeviceID: "d1", PriorityBucket: "vip"})
+
+	_, payload, err := controller.AdmitEntry("q-vip")
+	if err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+	if payload.Entitlements["max_tickets"] != float64(8) {
+		t.Errorf("Expected bucket max_tickets 8, got %v", payload.Entitlements["max_tickets"])
+	}
+	if _, ok := payload.Entitlements["sections"]; !ok {
+		t.Errorf("Expected event sections to be kept, got %v", payload.Entitlements)
+	}
+}
+
+func TestEventTokenConfig_Entitlements(t *testing.T) {
+	config := &eventTokenConfig{
+		Entitlements: token.Entitlements{"max_tickets": 4},
+		BucketEntitlements: map[string]token.Entitlements{
+			"vip":           {"max_tickets": 8},
+			"partner":       {"max_tickets": 6},
+			"deprioritized": {"max_tickets": 1},
+		},
+		PriorityBuckets: []string{"partner"},
+	}
+
+	tests := []struct {
+		bucket string
+		want   int
+	}{
+		{"partner", 6},
+		{"deprioritized", 1},
+		{"vip", 4},
+		{"normal", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bucket, func(t *testing.T) {
+			if got := config.entitlements(tt.bucket)["max_tickets"]; got != tt.want {
+				t.Errorf("Expected max_tickets %d, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAdmitEntry_EmbedsAllowListedMetadata(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "event-metadata"
+	controller.redisClient.GetClient().Set(context.Background(), fmt.Sprintf("queue:config:%s", eventID),
+		`{"event_id":"event-metadata","enabled":true,"token_metadata_keys":["source","tier"]}`, 0)
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-metadata", EventID: eventID, DeviceID: "d1",
+		Metadata: map[string]string{"source": "newsletter", "locale": "de-DE"}})
+
+	_, payload, err := controller.AdmitEntry("q-metadata")
+	if err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+	if len(payload.Metadata) != 1 || payload.Metadata["source"] != "newsletter" {
+		t.Errorf("Expected only source in token metadata, got %v", payload.Metadata)
+	}
+
+	// Refreshed tokens keep their metadata
+	_, refreshed, err := controller.RefreshAdmission(payload, func() error { return nil })
+	if err != nil {
+		t.Fatalf("RefreshAdmission() failed: %v", err)
+	}
+	if refreshed.Metadata["source"] != "newsletter" {
+		t.Errorf("Expected metadata kept across refresh, got %v", refreshed.Metadata)
+	}
+}
+
+func TestRefreshAdmission(t *testing.T) {
+	controller, cleanup := setupTestController(t)
+	if controller == nil {
+		return
+	}
+	defer cleanup()
+
+	eventID := "event-refresh"
+	enqueueTestEntry(t, controller, QueueEntry{QueueID: "q-refresh", EventID: eventID, DeviceID: "d1"})
+
+	oldToken, old, err := controller.AdmitEntry("q-refresh")
+	if err != nil {
+		t.Fatalf("AdmitEntry() failed: %v", err)
+	}
+
+	// A failed claim issues nothing
+	if _, _, err := controller.RefreshAdmission(old, func() error { return token.ErrTokenRevoked }); !errors.Is(err, token.ErrTokenRevoked) {
+		t.Fatalf("Expected the claim error, got %v", err)
+	}
+
+	newToken, payload, err := controller.RefreshAdmission(old, func() error { return nil })
+	if err != nil {
+		t.Fatalf("RefreshAdmission() failed: %v", err)
+	}
+	if newToken == oldToken {
+		t.Error("Expected a new token")
+	}
+	if !payload.AdmittedAt.Equal(old.AdmittedAt) {
+		t.Errorf("AdmittedAt mismatch: expected %v, got %v", old.AdmittedAt, payload.AdmittedAt)
+	}
+
+	// Status reports the refreshed token; neither token is stored in plain text
+	client := controller.redisClient.GetClient()
+	stored, err := client.Get(context.Background(), "queue:admission:q-refresh").Result()
+	if err != nil {
+		t.Fatalf("Failed to read stored admission token: %v", err)
+	}
+	if !strings.Contains(stored, token.TokenHash(newToken)) {
+		t.Error("Expected stored admission token to be the refreshed one")
+	}
+	if strings.Contains(stored, newToken) || strings.Contains(stored, oldToken) {
+		t.Error("Expected no raw token in the stored metadata")
+	}
+	var metadata admissionToken
+	if err := json.Unmarshal([]byte(stored), &metadata); err != nil {
+		t.Fatalf("Failed to decode stored admission token: %v", err)
+	}
+	sealer := token.NewSealer("this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	if opened, err := sealer.Open(metadata.SealedToken, "q-refresh"); err != nil || opened != newToken {
+		t.Errorf("Expected the refreshed token sealed at rest, got %q, %v", opened, err)
+	}
+}
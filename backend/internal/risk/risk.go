@@ -0,0 +1,168 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxScore is the highest risk score; rule scores are summed and capped at it
+const MaxScore = 100
+
+// Signals is what is known about a join when it is scored
+type Signals struct {
+	EventID   string
+	DeviceID  string
+	ClientIP  string
+	UserAgent string
+	Metadata  map[string]string
+	// IPJoins is the number of joins from the client IP within the rate limit
+	// window, including this one
+	IPJoins int
+	// IPDevices is the number of distinct devices that joined from the client
+	// IP within the rate limit window
+	IPDevices int
+}
+
+// Rule scores one aspect of a join. Score returns 0 when the rule does not
+// apply, otherwise the points it adds and a reason.
+type Rule interface {
+	Name() string
+	Score(signals Signals) (int, string)
+}
+
+// Assessment is the risk score of a join and the reasons behind it
+type Assessment struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Engine scores joins with a set of rules
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine creates an engine applying the given rules
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// DefaultRules returns the rules used when none are configured
+func DefaultRules() []Rule {
+	return []Rule{
+		IPVelocity{Limit: 20, Points: 30},
+		DeviceChurn{Limit: 3, Points: 40},
+		UserAgent{Points: 40},
+		MetadataMatch{Key: "webdriver", Values: []string{"true"}, Points: 50},
+	}
+}
+
+// Score applies every rule to the signals
+func (e *Engine) Score(signals Signals) Assessment {
+	var assessment Assessment
+	for _, rule := range e.rules {
+		points, reason := rule.Score(signals)
+		if points <= 0 {
+			continue
+		}
+		assessment.Score += points
+		assessment.Reasons = append(assessment.Reasons, rule.Name()+": "+reason)
+	}
+	if assessment.Score > MaxScore {
+		assessment.Score = MaxScore
+	}
+	return assessment
+}
+
+// IPVelocity scores clients joining more than Limit times per window from the
+// same IP
+type IPVelocity struct {
+	Limit  int
+	Points int
+}
+
+// Name returns the rule name
+func (r IPVelocity) Name() string { return "ip_velocity" }
+
+// Score scores the join
+func (r IPVelocity) Score(signals Signals) (int, string) {
+	if signals.IPJoins <= r.Limit {
+		return 0, ""
+	}
+	return r.Points, fmt.Sprintf("%d joins from %s", signals.IPJoins, signals.ClientIP)
+}
+
+// DeviceChurn scores IPs cycling through more than Limit device ids per
+// window, a sign of device ids being regenerated to dodge per-device limits
+type DeviceChurn struct {
+	Limit  int
+	Points int
+}
+
+// Name returns the rule name
+func (r DeviceChurn) Name() string { return "device_churn" }
+
+// Score scores the join
+func (r DeviceChurn) Score(signals Signals) (int, string) {
+	if signals.IPDevices <= r.Limit {
+		return 0, ""
+	}
+	return r.Points, fmt.Sprintf("%d devices from %s", signals.IPDevices, signals.ClientIP)
+}
+
+// automationAgents are user-agent fragments of HTTP libraries and headless
+// browsers, matched case-insensitively. The HTTP stacks of mobile apps
+// (OkHttp, Java's URLConnection on Android) are left out, since native
+// clients send their default user agent.
+var automationAgents = []string{
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"node-fetch", "axios/", "headlesschrome", "phantomjs", "selenium", "puppeteer",
+	"playwright",
+}
+
+// UserAgent scores joins without a user agent or with the user agent of an
+// HTTP library or headless browser
+type UserAgent struct {
+	Points int
+}
+
+// Name returns the rule name
+func (r UserAgent) Name() string { return "user_agent" }
+
+// Score scores the join
+func (r UserAgent) Score(signals Signals) (int, string) {
+	if signals.UserAgent == "" {
+		return r.Points, "missing user agent"
+	}
+	agent := strings.ToLower(signals.UserAgent)
+	for _, fragment := range automationAgents {
+		if strings.Contains(agent, fragment) {
+			return r.Points, "automation user agent " + strings.TrimSuffix(fragment, "/")
+		}
+	}
+	return 0, ""
+}
+
+// MetadataMatch scores joins whose metadata Key has one of Values, e.g. a
+// client SDK reporting navigator.webdriver
+type MetadataMatch struct {
+	Key    string
+	Values []string
+	Points int
+}
+
+// Name returns the rule name
+func (r MetadataMatch) Name() string { return "metadata" }
+
+// Score scores the join
+func (r MetadataMatch) Score(signals Signals) (int, string) {
+	value, ok := signals.Metadata[r.Key]
+	if !ok {
+		return 0, ""
+	}
+	for _, match := range r.Values {
+		if value == match {
+			return r.Points, r.Key + "=" + value
+		}
+	}
+	return 0, ""
+}
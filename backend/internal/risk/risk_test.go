@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+)
+
+const browserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15"
+
+func TestEngine_DefaultRules(t *testing.T) {
+	engine := NewEngine(DefaultRules()...)
+
+	tests := []struct {
+		name       string
+		signals    Signals
+		wantScore  int
+		wantReason string
+	}{
+		{"browser", Signals{ClientIP: "203.0.113.7", UserAgent: browserAgent, IPJoins: 1, IPDevices: 1}, 0, ""},
+		{"missing user agent", Signals{IPJoins: 1, IPDevices: 1}, 40, "user_agent: missing user agent"},
+		{"library user agent", Signals{UserAgent: "python-requests/2.32.3"}, 40, "user_agent: automation user agent python-requests"},
+		{"android app", Signals{UserAgent: "okhttp/4.12.0", IPJoins: 1, IPDevices: 1}, 0, ""},
+		{"headless browser", Signals{UserAgent: "Mozilla/5.0 HeadlessChrome/126.0.0.0"}, 40, "headlesschrome"},
+		{"busy IP", Signals{ClientIP: "203.0.113.7", UserAgent: browserAgent, IPJoins: 21}, 30, "ip_velocity: 21 joins from 203.0.113.7"},
+		{"device churn", Signals{ClientIP: "203.0.113.7", UserAgent: browserAgent, IPDevices: 4}, 40, "device_churn: 4 devices from 203.0.113.7"},
+		{"webdriver", Signals{UserAgent: browserAgent, Metadata: map[string]string{"webdriver": "true"}}, 50, "metadata: webdriver=true"},
+		{"webdriver false", Signals{UserAgent: browserAgent, Metadata: map[string]string{"webdriver": "false"}}, 0, ""},
+		{"capped", Signals{UserAgent: "curl/8.7.1", IPJoins: 50, IPDevices: 50, Metadata: map[string]string{"webdriver": "true"}}, MaxScore, "device_churn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assessment := engine.Score(tt.signals)
+			if assessment.Score != tt.wantScore {
+				t.Errorf("Expected score %d, got %d (%v)", tt.wantScore, assessment.Score, assessment.Reasons)
+			}
+			reasons := strings.Join(assessment.Reasons, "; ")
+			if tt.wantReason == "" && reasons != "" {
+				t.Errorf("Expected no reasons, got %q", reasons)
+			}
+			if !strings.Contains(reasons, tt.wantReason) {
+				t.Errorf("Expected reasons to contain %q, got %q", tt.wantReason, reasons)
+			}
+		})
+	}
+}
+
+// fixedRule is a custom rule scoring every join
+type fixedRule struct{}
+
+func (fixedRule) Name() string                { return "fixed" }
+func (fixedRule) Score(Signals) (int, string) { return 7, "always" }
+
+func TestEngine_CustomRules(t *testing.T) {
+	assessment := NewEngine(fixedRule{}, fixedRule{}).Score(Signals{})
+	if assessment.Score != 14 {
+		t.Errorf("Expected score 14, got %d", assessment.Score)
+	}
+	if len(assessment.Reasons) != 2 || assessment.Reasons[0] != "fixed: always" {
+		t.Errorf("Unexpected reasons: %v", assessment.Reasons)
+	}
+
+	if assessment := NewEngine().Score(Signals{}); assessment.Score != 0 || assessment.Reasons != nil {
+		t.Errorf("Expected empty engine to score 0, got %+v", assessment)
+	}
+}
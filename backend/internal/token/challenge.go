@@ -0,0 +1,170 @@
+package token
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Proof-of-work challenges make joining a queue cost CPU time. A client gets a
+// signed challenge for its event and device, then searches for a solution
+// such that SHA-256(challenge + "." + solution) starts with at least the
+// challenge's difficulty in zero bits. Each extra bit doubles the expected
+// work; verifying a solution takes a single hash.
+
+const (
+	// ChallengeType is the "typ" header of proof-of-work challenges, which
+	// keeps them from being accepted as admission tokens and vice versa
+	ChallengeType = "gatekeep-pow+jwt"
+	// ChallengeTTL is how long a client has to solve a challenge
+	ChallengeTTL = 2 * time.Minute
+	// MaxChallengeDifficulty bounds the difficulty of challenges, in bits
+	MaxChallengeDifficulty = 32
+	// MaxSolutionLength bounds the size of challenge solutions
+	MaxSolutionLength = 64
+)
+
+// Challenge is the signed content of a proof-of-work challenge
+type Challenge struct {
+	EventID    string    `json:"event_id"`
+	DeviceID   string    `json:"device_id"`
+	Nonce      string    `json:"nonce"`
+	Difficulty int       `json:"difficulty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ChallengeKey returns the Redis key marking a challenge as solved
+func ChallengeKey(nonce string) string {
+	return "token:challenge:" + nonce
+}
+
+// IssueChallenge creates a signed challenge for a device joining an event
+func (v *Verifier) IssueChallenge(eventID, deviceID string, difficulty int) (string, *Challenge, error) {
+	if eventID == "" || deviceID == "" {
+		return "", nil, fmt.Errorf("event_id and device_id are required")
+	}
+	if difficulty < 1 || difficulty > MaxChallengeDifficulty {
+		return "", nil, fmt.Errorf("difficulty must be between 1 and %d, got %d", MaxChallengeDifficulty, difficulty)
+	}
+
+	now := time.Now()
+	challenge := &Challenge{
+		EventID:    eventID,
+		DeviceID:   deviceID,
+		Nonce:      uuid.New().String(),
+		Difficulty: difficulty,
+		IssuedAt:   now,
+		ExpiresAt:  now.Add(ChallengeTTL),
+	}
+
+	headerJSON, err := json.Marshal(TokenHeader{Algorithm: TokenHeaderAlgorithm, Type: ChallengeType})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal header: %w", err)
+	}
+	payloadJSON, err := json.Marshal(challenge)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal challenge: %w", err)
+	}
+
+	signatureInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+	signature := base64.RawURLEncoding.EncodeToString(v.createSignature(signatureInput))
+	return signatureInput + "." + signature, challenge, nil
+}
+
+// VerifyChallenge checks a solution to a challenge issued for the event and
+// device, and reserves the challenge so concurrent joins cannot use it too.
+// Call ReleaseChallenge if the join it guards fails, so a join rejected for
+// another reason can be retried with the same solution. Challenges are single
+// use when the verifier has Redis.
+func (v *Verifier) VerifyChallenge(signed, solution, eventID, deviceID string) (*Challenge, error) {
+	if signed == "" || solution == "" {
+		return nil, fmt.Errorf("%w: challenge and solution are required", ErrInvalidChallenge)
+	}
+	if len(solution) > MaxSolutionLength {
+		return nil, fmt.Errorf("%w: solution longer than %d bytes", ErrInvalidChallenge, MaxSolutionLength)
+	}
+
+	payloadJSON, err := v.decodeJWS(signed, MaxTokenLength, ChallengeType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidChallenge, err)
+	}
+	var challenge Challenge
+	if err := json.Unmarshal(payloadJSON, &challenge); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims", ErrInvalidChallenge)
+	}
+
+	if challenge.EventID != eventID || challenge.DeviceID != deviceID {
+		return nil, fmt.Errorf("%w: issued for another event or device", ErrInvalidChallenge)
+	}
+	if time.Now().After(challenge.ExpiresAt.Add(v.leeway)) {
+		return nil, fmt.Errorf("%w: expired at %s", ErrInvalidChallenge, challenge.ExpiresAt.Format(time.RFC3339))
+	}
+	if challenge.Difficulty < 1 || LeadingZeroBits(signed, solution) < challenge.Difficulty {
+		return nil, fmt.Errorf("%w: solution does not meet difficulty %d", ErrInvalidChallenge, challenge.Difficulty)
+	}
+
+	if v.redisClient == nil {
+		return &challenge, nil
+	}
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+	// The challenge has not expired, so the TTL is positive
+	ttl := time.Until(challenge.ExpiresAt.Add(v.leeway))
+	fresh, err := v.redisClient.GetClient().SetNX(ctx, ChallengeKey(challenge.Nonce), 1, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve challenge: %w", err)
+	}
+	if !fresh {
+		return nil, fmt.Errorf("%w: challenge already used", ErrInvalidChallenge)
+	}
+	return &challenge, nil
+}
+
+// ReleaseChallenge frees a challenge reserved by VerifyChallenge whose join
+// failed, so it can be used again until it expires
+func (v *Verifier) ReleaseChallenge(challenge *Challenge) error {
+	if v.redisClient == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+	if err := v.redisClient.GetClient().Del(ctx, ChallengeKey(challenge.Nonce)).Err(); err != nil {
+		return fmt.Errorf("failed to release challenge: %w", err)
+	}
+	return nil
+}
+
+// LeadingZeroBits returns the number of leading zero bits of
+// SHA-256(challenge + "." + solution)
+func LeadingZeroBits(challenge, solution string) int {
+	sum := sha256.Sum256([]byte(challenge + "." + solution))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// SolveChallenge finds a solution to a challenge by counting up from 0. It is
+// meant for tests and trusted clients; browsers and apps implement the same
+// search.
+func SolveChallenge(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if LeadingZeroBits(challenge, solution) >= difficulty {
+			return solution
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeadingZeroBits(t *testing.T) {
+	solution := SolveChallenge("challenge", 12)
+	if got := LeadingZeroBits("challenge", solution); got < 12 {
+		t.Errorf("Expected at least 12 zero bits, got %d", got)
+	}
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+
+	signed, challenge, err := verifier.IssueChallenge("event-1", "device-1", 8)
+	if err != nil {
+		t.Fatalf("IssueChallenge() failed: %v", err)
+	}
+	if challenge.Difficulty != 8 || challenge.ExpiresAt.Sub(challenge.IssuedAt) != ChallengeTTL {
+		t.Errorf("Unexpected challenge: %+v", challenge)
+	}
+
+	if _, err := verifier.VerifyChallenge(signed, SolveChallenge(signed, 8), "event-1", "device-1"); err != nil {
+		t.Errorf("VerifyChallenge() failed: %v", err)
+	}
+}
+
+func TestIssueChallenge_InvalidDifficulty(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+
+	for _, difficulty := range []int{0, MaxChallengeDifficulty + 1} {
+		if _, _, err := verifier.IssueChallenge("event-1", "device-1", difficulty); err == nil {
+			t.Errorf("Expected error for difficulty %d", difficulty)
+		}
+	}
+}
+
+func TestVerifyChallenge_Rejects(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+	signed, _, err := verifier.IssueChallenge("event-1", "device-1", 8)
+	if err != nil {
+		t.Fatalf("IssueChallenge() failed: %v", err)
+	}
+	solution := SolveChallenge(signed, 8)
+
+	// Find a solution that misses the difficulty
+	unsolved := "x"
+	for i := 0; LeadingZeroBits(signed, unsolved) >= 8; i++ {
+		unsolved = "x" + string(rune('a'+i))
+	}
+
+	expiredClaims, _ := json.Marshal(Challenge{
+		EventID: "event-1", DeviceID: "device-1", Nonce: "n", Difficulty: 1,
+		IssuedAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	expired := signTestJWS(`{"alg":"HS256","typ":"`+ChallengeType+`"}`, string(expiredClaims))
+
+	tests := []struct {
+		name      string
+		challenge string
+		solution  string
+		eventID   string
+		deviceID  string
+	}{
+		{"missing challenge", "", solution, "event-1", "device-1"},
+		{"missing solution", signed, "", "event-1", "device-1"},
+		{"other event", signed, solution, "event-2", "device-1"},
+		{"other device", signed, solution, "event-1", "device-2"},
+		{"unsolved", signed, unsolved, "event-1", "device-1"},
+		{"expired", expired, SolveChallenge(expired, 1), "event-1", "device-1"},
+		{"tampered", signed[:len(signed)-4] + "AAAA", solution, "event-1", "device-1"},
+		{"admission token", testToken(t), "0", "event-1", "device-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.VerifyChallenge(tt.challenge, tt.solution, tt.eventID, tt.deviceID)
+			if !errors.Is(err, ErrInvalidChallenge) {
+				t.Errorf("Expected ErrInvalidChallenge, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyChallenge_SingleUse(t *testing.T) {
+	verifier, _, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	signed, _, err := verifier.IssueChallenge("event-1", "device-1", 4)
+	if err != nil {
+		t.Fatalf("IssueChallenge() failed: %v", err)
+	}
+	solution := SolveChallenge(signed, 4)
+
+	// Verifying reserves the challenge, so a concurrent join fails
+	challenge, err := verifier.VerifyChallenge(signed, solution, "event-1", "device-1")
+	if err != nil {
+		t.Fatalf("VerifyChallenge() failed: %v", err)
+	}
+	if _, err := verifier.VerifyChallenge(signed, solution, "event-1", "device-1"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Expected reserved challenge to fail, got %v", err)
+	}
+
+	// A released challenge can be used again
+	if err := verifier.ReleaseChallenge(challenge); err != nil {
+		t.Fatalf("ReleaseChallenge() failed: %v", err)
+	}
+	if _, err := verifier.VerifyChallenge(signed, solution, "event-1", "device-1"); err != nil {
+		t.Errorf("Expected released challenge to verify, got %v", err)
+	}
+	if _, err := verifier.VerifyChallenge(signed, solution, "event-1", "device-1"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Expected used challenge to fail, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultIssuer is the "iss" claim of tokens when no issuer is configured
+	DefaultIssuer = "gatekeep"
+	// DefaultLeeway is the clock skew tolerated when checking "exp" and "nbf"
+	DefaultLeeway = 30 * time.Second
+)
+
+// tokenClaims is the encoded form of a TokenPayload: the registered JWT
+// claims (RFC 7519) that off-the-shelf libraries and gateways validate,
+// followed by the custom claims. The custom time claims keep full precision,
+// which revocation checks rely on.
+type tokenClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  audience     `json:"aud,omitempty"`
+	ExpiresAt *numericDate `json:"exp,omitempty"`
+	NotBefore *numericDate `json:"nbf,omitempty"`
+	IssuedAt  *numericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+	// Confirmation binds the token to a key (RFC 7800, RFC 9449)
+	Confirmation *confirmation `json:"cnf,omitempty"`
+
+	EventID       string            `json:"event_id"`
+	DeviceID      string            `json:"device_id"`
+	UserID        string            `json:"user_id"`
+	QueueID       string            `json:"queue_id"`
+	IssuedAtTime  time.Time         `json:"issued_at"`
+	ExpiresAtTime time.Time         `json:"expires_at"`
+	AdmittedAt    time.Time         `json:"admitted_at"`
+	Nonce         string            `json:"nonce"`
+	Entitlements  Entitlements      `json:"entitlements,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// confirmation is the "cnf" claim, holding a JWK SHA-256 thumbprint
+type confirmation struct {
+	JKT string `json:"jkt"`
+}
+
+// MarshalJSON encodes the payload with registered and custom claims. The
+// subject is the user, or the device for anonymous admissions.
+func (p TokenPayload) MarshalJSON() ([]byte, error) {
+	subject := p.UserID
+	if subject == "" {
+		subject = p.DeviceID
+	}
+
+	claims := tokenClaims{
+		Issuer:        p.Issuer,
+		Subject:       subject,
+		ExpiresAt:     newNumericDate(p.ExpiresAt),
+		NotBefore:     newNumericDate(p.NotBefore),
+		IssuedAt:      newNumericDate(p.IssuedAt),
+		ID:            p.Nonce,
+		EventID:       p.EventID,
+		DeviceID:      p.DeviceID,
+		UserID:        p.UserID,
+		QueueID:       p.QueueID,
+		IssuedAtTime:  p.IssuedAt,
+		ExpiresAtTime: p.ExpiresAt,
+		AdmittedAt:    p.AdmittedAt,
+		Nonce:         p.Nonce,
+		Entitlements:  p.Entitlements,
+		Metadata:      p.Metadata,
+	}
+	if p.EventID != "" {
+		claims.Audience = audience{p.EventID}
+	}
+	if p.KeyThumbprint != "" {
+		claims.Confirmation = &confirmation{JKT: p.KeyThumbprint}
+	}
+	return json.Marshal(claims)
+}
+
+// UnmarshalJSON decodes a payload. Custom claims take precedence; registered
+// claims fill in whatever is missing.
+func (p *TokenPayload) UnmarshalJSON(data []byte) error {
+	var claims tokenClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return err
+	}
+
+	*p = TokenPayload{
+		Issuer:       claims.Issuer,
+		EventID:      claims.EventID,
+		DeviceID:     claims.DeviceID,
+		UserID:       claims.UserID,
+		QueueID:      claims.QueueID,
+		IssuedAt:     claims.IssuedAtTime,
+		ExpiresAt:    claims.ExpiresAtTime,
+		NotBefore:    claims.NotBefore.toTime(),
+		AdmittedAt:   claims.AdmittedAt,
+		Nonce:        claims.Nonce,
+		Entitlements: claims.Entitlements,
+		Metadata:     claims.Metadata,
+	}
+	if p.EventID == "" && len(claims.Audience) == 1 {
+		p.EventID = claims.Audience[0]
+	}
+	if p.IssuedAt.IsZero() {
+		p.IssuedAt = claims.IssuedAt.toTime()
+	}
+	if p.ExpiresAt.IsZero() {
+		p.ExpiresAt = claims.ExpiresAt.toTime()
+	}
+	if p.Nonce == "" {
+		p.Nonce = claims.ID
+	}
+	if claims.Confirmation != nil {
+		p.KeyThumbprint = claims.Confirmation.JKT
+	}
+	return nil
+}
+
+// numericDate is a JWT NumericDate: seconds since the epoch
+type numericDate struct {
+	time.Time
+}
+
+// newNumericDate returns nil for the zero time, so the claim is omitted
+func newNumericDate(t time.Time) *numericDate {
+	if t.IsZero() {
+		return nil
+	}
+	return &numericDate{t}
+}
+
+// toTime returns the date, or the zero time if the claim was absent
+func (d *numericDate) toTime() time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	return d.Time
+}
+
+// MarshalJSON encodes the date as whole seconds
+func (d numericDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Unix())
+}
+
+// UnmarshalJSON accepts integer and fractional seconds
+func (d *numericDate) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return fmt.Errorf("invalid NumericDate: %w", err)
+	}
+	whole, frac := math.Modf(seconds)
+	d.Time = time.Unix(int64(whole), int64(frac*1e9)).UTC()
+	return nil
+}
+
+// audience is the "aud" claim, which may be a single string or an array
+type audience []string
+
+// MarshalJSON encodes a single audience as a string
+func (a audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON accepts a string or an array of strings
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("invalid audience: %w", err)
+	}
+	*a = multiple
+	return nil
+}
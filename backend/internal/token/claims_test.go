@@ -0,0 +1,218 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenPayload_RegisteredClaims(t *testing.T) {
+	issuedAt := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC)
+	payload := TokenPayload{
+		Issuer:     "gatekeep-test",
+		EventID:    "event-1",
+		DeviceID:   "device-1",
+		UserID:     "user-1",
+		QueueID:    "queue-1",
+		IssuedAt:   issuedAt,
+		ExpiresAt:  issuedAt.Add(time.Hour),
+		NotBefore:  issuedAt,
+		AdmittedAt: issuedAt,
+		Nonce:      "nonce-1",
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"iss":      "gatekeep-test",
+		"sub":      "user-1",
+		"aud":      "event-1",
+		"exp":      float64(issuedAt.Add(time.Hour).Unix()),
+		"nbf":      float64(issuedAt.Unix()),
+		"iat":      float64(issuedAt.Unix()),
+		"jti":      "nonce-1",
+		"event_id": "event-1",
+		"queue_id": "queue-1",
+	}
+	for claim, want := range expected {
+		if claims[claim] != want {
+			t.Errorf("Claim %s: expected %v, got %v", claim, want, claims[claim])
+		}
+	}
+
+	var decoded TokenPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if !decoded.IssuedAt.Equal(issuedAt) {
+		t.Errorf("IssuedAt lost precision: expected %v, got %v", issuedAt, decoded.IssuedAt)
+	}
+	if decoded.Issuer != payload.Issuer || decoded.Nonce != payload.Nonce || decoded.EventID != payload.EventID {
+		t.Errorf("Round trip mismatch: %+v", decoded)
+	}
+}
+
+func TestTokenPayload_SubjectFallsBackToDevice(t *testing.T) {
+	data, err := json.Marshal(TokenPayload{EventID: "event-1", DeviceID: "device-1"})
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if claims["sub"] != "device-1" {
+		t.Errorf("Expected sub device-1, got %v", claims["sub"])
+	}
+	if _, ok := claims["exp"]; ok {
+		t.Error("Expected exp to be omitted for a zero expiry")
+	}
+}
+
+func TestTokenPayload_DecodesRegisteredClaimsOnly(t *testing.T) {
+	data := `{"iss":"gatekeep","sub":"user-1","aud":["event-1"],"exp":1705316400,"iat":1705312800.5,"jti":"nonce-1"}`
+
+	var payload TokenPayload
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+
+	if payload.EventID != "event-1" {
+		t.Errorf("Expected event_id from aud, got %q", payload.EventID)
+	}
+	if payload.Nonce != "nonce-1" {
+		t.Errorf("Expected nonce from jti, got %q", payload.Nonce)
+	}
+	if want := time.Unix(1705316400, 0); !payload.ExpiresAt.Equal(want) {
+		t.Errorf("Expected expiry %v, got %v", want, payload.ExpiresAt)
+	}
+	if want := time.Unix(1705312800, 500000000); !payload.IssuedAt.Equal(want) {
+		t.Errorf("Expected issue time %v, got %v", want, payload.IssuedAt)
+	}
+}
+
+func TestVerifyToken_TimeAndIssuerClaims(t *testing.T) {
+	secret := "this-is-a-very-long-secret-key-that-is-at-least-32-characters"
+	generator := NewGenerator(nil, secret)
+	verifier := NewVerifier(nil, secret)
+	verifier.SetLeeway(30 * time.Second)
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		payload TokenPayload
+		wantErr error
+	}{
+		{"expired beyond leeway", TokenPayload{
+			Issuer: DefaultIssuer, IssuedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute),
+		}, ErrTokenExpired},
+		{"not yet valid", TokenPayload{
+			Issuer: DefaultIssuer, IssuedAt: now, ExpiresAt: now.Add(time.Hour), NotBefore: now.Add(time.Minute),
+		}, ErrTokenNotYetValid},
+		{"other issuer", TokenPayload{
+			Issuer: "someone-else", IssuedAt: now, ExpiresAt: now.Add(time.Hour),
+		}, ErrIssuerMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := generator.encode(tt.payload)
+			if err != nil {
+				t.Fatalf("encode() failed: %v", err)
+			}
+			if _, err := verifier.VerifyToken(token, ""); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyToken_Leeway(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	// Expired and not yet valid, but both within the leeway
+	now := time.Now()
+	token, err := generator.encode(TokenPayload{
+		Issuer:    DefaultIssuer,
+		EventID:   "event-1",
+		IssuedAt:  now.Add(-time.Hour),
+		ExpiresAt: now.Add(-10 * time.Second),
+		NotBefore: now.Add(10 * time.Second),
+		Nonce:     "nonce-leeway",
+	})
+	if err != nil {
+		t.Fatalf("encode() failed: %v", err)
+	}
+
+	if _, err := verifier.VerifyToken(token, "event-1"); err != nil {
+		t.Errorf("VerifyToken() failed within leeway: %v", err)
+	}
+
+	verifier.SetLeeway(0)
+	if _, err := verifier.VerifyToken(token, "event-1"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired without leeway, got %v", err)
+	}
+}
+
+func TestTokenPayload_ConfirmationClaim(t *testing.T) {
+	data, err := json.Marshal(TokenPayload{EventID: "event-1", KeyThumbprint: "thumbprint-1"})
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var claims struct {
+		Confirmation map[string]string `json:"cnf"`
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if claims.Confirmation["jkt"] != "thumbprint-1" {
+		t.Errorf("Expected cnf.jkt thumbprint-1, got %v", claims.Confirmation)
+	}
+
+	var decoded TokenPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if decoded.KeyThumbprint != "thumbprint-1" {
+		t.Errorf("Expected KeyThumbprint thumbprint-1, got %q", decoded.KeyThumbprint)
+	}
+}
+
+func TestTokenPayload_MetadataClaim(t *testing.T) {
+	data, err := json.Marshal(TokenPayload{EventID: "event-1", Metadata: map[string]string{"source": "newsletter"}})
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var decoded TokenPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if decoded.Metadata["source"] != "newsletter" {
+		t.Errorf("Expected metadata source newsletter, got %v", decoded.Metadata)
+	}
+
+	data, _ = json.Marshal(TokenPayload{EventID: "event-1"})
+	var claims map[string]interface{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if _, ok := claims["metadata"]; ok {
+		t.Error("Expected metadata to be omitted when empty")
+	}
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxEntitlementsSize bounds the encoded size of a token's entitlements, so
+// tokens stay well under MaxTokenLength
+const MaxEntitlementsSize = 1024
+
+// Entitlements describe what an admitted user may do, e.g.
+// {"max_tickets": 4, "sections": ["A", "B"]}. They are embedded in the token
+// as the signed "entitlements" claim and are opaque to gatekeep.
+type Entitlements map[string]interface{}
+
+// Validate checks that the entitlements fit in a token
+func (e Entitlements) Validate() error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("invalid entitlements: %w", err)
+	}
+	if len(data) > MaxEntitlementsSize {
+		return fmt.Errorf("entitlements must encode to at most %d bytes, got %d", MaxEntitlementsSize, len(data))
+	}
+	return nil
+}
+
+// MergeEntitlements returns the base entitlements with each key of override
+// replacing the base value. It returns nil if both are empty.
+func MergeEntitlements(base, override Entitlements) Entitlements {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(Entitlements, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEntitlements_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		entitlements Entitlements
+		wantErr      bool
+	}{
+		{"nil", nil, false},
+		{"small", Entitlements{"max_tickets": 4, "sections": []string{"A", "B"}}, false},
+		{"too large", Entitlements{"sections": strings.Repeat("A", MaxEntitlementsSize)}, true},
+		{"not encodable", Entitlements{"callback": func() {}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.entitlements.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeEntitlements(t *testing.T) {
+	merged := MergeEntitlements(
+		Entitlements{"max_tickets": 4, "sections": "A"},
+		Entitlements{"max_tickets": 8, "presale": true},
+	)
+
+	expected := Entitlements{"max_tickets": 8, "sections": "A", "presale": true}
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+	for key, want := range expected {
+		if merged[key] != want {
+			t.Errorf("Entitlement %s: expected %v, got %v", key, want, merged[key])
+		}
+	}
+
+	if merged := MergeEntitlements(nil, Entitlements{}); merged != nil {
+		t.Errorf("Expected nil for empty entitlements, got %v", merged)
+	}
+}
+
+func TestTokenPayload_EntitlementsClaim(t *testing.T) {
+	data, err := json.Marshal(TokenPayload{EventID: "event-1", Entitlements: Entitlements{"max_tickets": 4}})
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var decoded TokenPayload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() failed: %v", err)
+	}
+	if decoded.Entitlements["max_tickets"] != float64(4) {
+		t.Errorf("Expected max_tickets 4, got %v", decoded.Entitlements)
+	}
+
+	data, _ = json.Marshal(TokenPayload{EventID: "event-1"})
+	if strings.Contains(string(data), "entitlements") {
+		t.Errorf("Expected no entitlements claim, got %s", data)
+	}
+}
@@ -0,0 +1,39 @@
+package token
+
+import "errors"
+
+// Sentinel errors returned by token verification and lookups. Verification
+// errors are wrapped with additional context; match them with errors.Is.
+var (
+	// ErrMalformedToken is returned when a token cannot be parsed
+	ErrMalformedToken = errors.New("invalid token format")
+	// ErrInvalidSignature is returned when the token signature does not match
+	ErrInvalidSignature = errors.New("invalid token signature")
+	// ErrTokenExpired is returned when the token is past its expiry time
+	ErrTokenExpired = errors.New("token has expired")
+	// ErrEventMismatch is returned when the token was issued for another event
+	ErrEventMismatch = errors.New("token event_id mismatch")
+	// ErrIssuerMismatch is returned when the token was issued by another issuer
+	ErrIssuerMismatch = errors.New("token issuer mismatch")
+	// ErrTokenNotYetValid is returned when the token's "nbf" is in the future
+	ErrTokenNotYetValid = errors.New("token is not yet valid")
+	// ErrTokenRevoked is returned when the token has been revoked
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrNotFound is returned when no metadata is stored for the token
+	ErrNotFound = errors.New("token not found")
+	// ErrInvalidProof is returned when a bound token is presented without a
+	// valid proof of possession of its key
+	ErrInvalidProof = errors.New("invalid proof of possession")
+	// ErrMaxLifetimeReached is returned when refreshing a token would extend
+	// the admission past its maximum lifetime
+	ErrMaxLifetimeReached = errors.New("admission reached its maximum lifetime")
+	// ErrInvalidChallenge is returned when a proof-of-work challenge is
+	// missing, invalid, expired, reused or not solved
+	ErrInvalidChallenge = errors.New("invalid proof-of-work challenge")
+	// ErrInvalidRevocation is returned for a revocation filter that selects
+	// no tokens or an invalid time window
+	ErrInvalidRevocation = errors.New("invalid revocation filter")
+	// ErrTokenTooLarge is returned when an issued token would be longer than
+	// MaxTokenLength and so be rejected on verification
+	ErrTokenTooLarge = errors.New("token too large")
+)
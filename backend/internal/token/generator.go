@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,12 @@ import (
 const (
 	// DefaultTokenTTL is the default TTL for tokens (1 hour)
 	DefaultTokenTTL = 1 * time.Hour
+	// DefaultMaxTokenLifetime bounds how long refreshes can keep an admission
+	// alive, measured from the first token
+	DefaultMaxTokenLifetime = 2 * time.Hour
+	// MaxTokenLifetime is the upper bound for configured token TTLs and
+	// lifetimes
+	MaxTokenLifetime = 24 * time.Hour
 	// TokenHeaderAlgorithm is the algorithm used for signing
 	TokenHeaderAlgorithm = "HS256"
 	// TokenType is the token type
@@ -27,6 +34,8 @@ const (
 type Generator struct {
 	redisClient *redisclient.Client
 	secret      []byte
+	issuer      string
+	sealer      *Sealer
 	ctx         context.Context
 }
 
@@ -35,29 +44,169 @@ func NewGenerator(redisClient *redisclient.Client, secret string) *Generator {
 	return &Generator{
 		redisClient: redisClient,
 		secret:      []byte(secret),
+		issuer:      DefaultIssuer,
+		sealer:      NewSealer(secret),
 		ctx:         context.Background(),
 	}
 }
 
+// Seal encrypts an issued token for storage under its queue entry. Readers
+// open it with a Sealer for the same secret.
+func (g *Generator) Seal(tokenString, queueID string) (string, error) {
+	return g.sealer.Seal(tokenString, queueID)
+}
+
+// SetIssuer sets the "iss" claim of issued tokens
+func (g *Generator) SetIssuer(issuer string) {
+	g.issuer = issuer
+}
+
+// IssueRequest describes an admission token to issue
+type IssueRequest struct {
+	EventID  string
+	DeviceID string
+	UserID   string
+	QueueID  string
+	// TTL is the token lifetime; DefaultTokenTTL if zero
+	TTL time.Duration
+	// AdmittedAt carries the original admission time across refreshes; the
+	// issue time if zero
+	AdmittedAt time.Time
+	// KeyThumbprint binds the token to a device key, if set
+	KeyThumbprint string
+	// Entitlements are embedded as a signed claim
+	Entitlements Entitlements
+	// Metadata is embedded as a signed claim
+	Metadata map[string]string
+}
+
 // GenerateToken generates a new admission token
 func (g *Generator) GenerateToken(eventID, deviceID, userID, queueID string) (string, error) {
+	token, _, err := g.IssueToken(eventID, deviceID, userID, queueID)
+	return token, err
+}
+
+// IssueToken generates a new admission token with the default TTL and
+// returns it together with its payload
+func (g *Generator) IssueToken(eventID, deviceID, userID, queueID string) (string, *TokenPayload, error) {
+	return g.Issue(IssueRequest{
+		EventID:  eventID,
+		DeviceID: deviceID,
+		UserID:   userID,
+		QueueID:  queueID,
+	})
+}
+
+// Refresh issues a replacement for a verified token. The new token keeps the
+// original admission time and cannot outlive it by more than maxLifetime.
+// Revoking the old token is up to the caller.
+func (g *Generator) Refresh(old *TokenPayload, ttl, maxLifetime time.Duration) (string, *TokenPayload, error) {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	if err := CheckRefreshable(old, maxLifetime); err != nil {
+		return "", nil, err
+	}
+	admittedAt, deadline := refreshDeadline(old, maxLifetime)
+	if remaining := time.Until(deadline); ttl > remaining {
+		ttl = remaining
+	}
+
+	return g.Issue(IssueRequest{
+		EventID:       old.EventID,
+		DeviceID:      old.DeviceID,
+		UserID:        old.UserID,
+		QueueID:       old.QueueID,
+		TTL:           ttl,
+		AdmittedAt:    admittedAt,
+		KeyThumbprint: old.KeyThumbprint,
+		Entitlements:  old.Entitlements,
+		Metadata:      old.Metadata,
+	})
+}
+
+// CheckRefreshable returns ErrMaxLifetimeReached if refreshing the token
+// would extend its admission past maxLifetime
+func CheckRefreshable(old *TokenPayload, maxLifetime time.Duration) error {
+	admittedAt, deadline := refreshDeadline(old, maxLifetime)
+	if !time.Now().Before(deadline) {
+		return fmt.Errorf("%w: admitted at %s", ErrMaxLifetimeReached, admittedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// refreshDeadline returns when a token's admission started and when its
+// refreshes end
+func refreshDeadline(old *TokenPayload, maxLifetime time.Duration) (admittedAt, deadline time.Time) {
+	if maxLifetime <= 0 {
+		maxLifetime = DefaultMaxTokenLifetime
+	}
+	admittedAt = old.AdmittedAt
+	if admittedAt.IsZero() {
+		admittedAt = old.IssuedAt
+	}
+	return admittedAt, admittedAt.Add(maxLifetime)
+}
+
+// Issue generates a new admission token and returns it together with its
+// payload, so callers can report issue and expiry times
+func (g *Generator) Issue(req IssueRequest) (string, *TokenPayload, error) {
+	ttl := req.TTL
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
 	now := time.Now()
-	expiresAt := now.Add(DefaultTokenTTL)
+	expiresAt := now.Add(ttl)
+
+	admittedAt := req.AdmittedAt
+	if admittedAt.IsZero() {
+		admittedAt = now
+	}
 
 	// Generate nonce
 	nonce := uuid.New().String()
 
 	// Create payload
 	payload := TokenPayload{
-		EventID:   eventID,
-		DeviceID:  deviceID,
-		UserID:    userID,
-		QueueID:   queueID,
-		IssuedAt:  now,
-		ExpiresAt: expiresAt,
-		Nonce:     nonce,
+		Issuer:        g.issuer,
+		EventID:       req.EventID,
+		DeviceID:      req.DeviceID,
+		UserID:        req.UserID,
+		QueueID:       req.QueueID,
+		IssuedAt:      now,
+		ExpiresAt:     expiresAt,
+		NotBefore:     now,
+		AdmittedAt:    admittedAt,
+		Nonce:         nonce,
+		KeyThumbprint: req.KeyThumbprint,
+		Entitlements:  req.Entitlements,
+		Metadata:      req.Metadata,
+	}
+
+	token, err := g.encode(payload)
+	if err != nil {
+		return "", nil, err
 	}
 
+	// Store token metadata in Redis
+	if err := g.storeTokenMetadata(token, payload); err != nil {
+		return "", nil, fmt.Errorf("failed to store token metadata: %w", err)
+	}
+
+	slog.Info("token issued",
+		"token_hash", TokenHash(token),
+		"event_id", req.EventID,
+		"queue_id", req.QueueID,
+		"device_id", req.DeviceID,
+		"expires_at", expiresAt.UTC().Format(time.RFC3339),
+	)
+
+	return token, &payload, nil
+}
+
+// encode serializes and signs a payload as a compact JWS
+func (g *Generator) encode(payload TokenPayload) (string, error) {
 	// Create header
 	header := TokenHeader{
 		Algorithm: TokenHeaderAlgorithm,
@@ -83,14 +232,11 @@ func (g *Generator) GenerateToken(eventID, deviceID, userID, queueID string) (st
 	signature := g.createSignature(signatureInput)
 	signatureEncoded := base64.RawURLEncoding.EncodeToString(signature)
 
-	// Combine into token
+	// Combine into token; a longer token would fail verification
 	token := signatureInput + "." + signatureEncoded
-
-	// Store token metadata in Redis
-	if err := g.storeTokenMetadata(token, payload); err != nil {
-		return "", fmt.Errorf("failed to store token metadata: %w", err)
+	if len(token) > MaxTokenLength {
+		return "", fmt.Errorf("%w: %d bytes, at most %d", ErrTokenTooLarge, len(token), MaxTokenLength)
 	}
-
 	return token, nil
 }
 
@@ -103,8 +249,10 @@ func (g *Generator) createSignature(input string) []byte {
 
 // storeTokenMetadata stores token metadata in Redis with TTL
 func (g *Generator) storeTokenMetadata(token string, payload TokenPayload) error {
+	tokenHash := TokenHash(token)
 	metadata := TokenMetadata{
-		Token:     token,
+		TokenHash: tokenHash,
+		Nonce:     payload.Nonce,
 		EventID:   payload.EventID,
 		DeviceID:  payload.DeviceID,
 		UserID:    payload.UserID,
@@ -120,7 +268,7 @@ func (g *Generator) storeTokenMetadata(token string, payload TokenPayload) error
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	key := TokenKey(token)
+	key := TokenKey(tokenHash)
 	ctx, cancel := context.WithTimeout(g.ctx, 2*time.Second)
 	defer cancel()
 
@@ -2,6 +2,7 @@ package token
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -126,7 +127,7 @@ func TestGenerateToken_StoredInRedis(t *testing.T) {
 	}
 
 	// Verify token is stored in Redis
-	key := TokenKey(token)
+	key := TokenKey(TokenHash(token))
 	ctx := context.Background()
 	data, err := generator.redisClient.GetClient().Get(ctx, key).Result()
 	if err != nil {
@@ -151,7 +152,7 @@ func TestGenerateToken_TTL(t *testing.T) {
 	}
 
 	// Check TTL
-	key := TokenKey(token)
+	key := TokenKey(TokenHash(token))
 	ctx := context.Background()
 	ttl, err := generator.redisClient.GetClient().TTL(ctx, key).Result()
 	if err != nil {
@@ -246,3 +247,127 @@ func TestGenerateToken_DifferentInputs(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenHash(t *testing.T) {
+	hash := TokenHash("header.payload.signature")
+	if len(hash) != 64 {
+		t.Errorf("Expected 64 hex characters, got %d", len(hash))
+	}
+	if hash != TokenHash("header.payload.signature") {
+		t.Error("TokenHash() should be deterministic")
+	}
+	if hash == TokenHash("header.payload.other") {
+		t.Error("Different tokens should have different hashes")
+	}
+}
+
+func TestEncode_TooLarge(t *testing.T) {
+	generator := NewGenerator(nil, "this-is-a-very-long-secret-key-that-is-at-least-32-characters")
+	payload := TokenPayload{EventID: "event-1", DeviceID: "device-1"}
+	if _, err := generator.encode(payload); err != nil {
+		t.Fatalf("encode() failed: %v", err)
+	}
+
+	payload.Metadata = map[string]string{"note": strings.Repeat("x", MaxTokenLength)}
+	if _, err := generator.encode(payload); !errors.Is(err, ErrTokenTooLarge) {
+		t.Errorf("Expected ErrTokenTooLarge, got %v", err)
+	}
+}
+
+func TestGenerateToken_DoesNotStoreRawToken(t *testing.T) {
+	generator, cleanup := setupTestGenerator(t)
+	if generator == nil {
+		return
+	}
+	defer cleanup()
+
+	token, err := generator.GenerateToken("event-1", "device-1", "user-1", "queue-1")
+	if err != nil {
+		t.Fatalf("GenerateToken() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	client := generator.redisClient.GetClient()
+	if exists := client.Exists(ctx, "token:"+token).Val(); exists != 0 {
+		t.Error("Metadata must not be keyed by the raw token")
+	}
+	data, err := client.Get(ctx, TokenKey(TokenHash(token))).Result()
+	if err != nil {
+		t.Fatalf("Token metadata not found by hash: %v", err)
+	}
+	if strings.Contains(data, token) {
+		t.Error("Metadata must not contain the raw token")
+	}
+}
+
+func TestRefresh_MaxLifetimeReached(t *testing.T) {
+	// The lifetime check runs before anything is stored
+	generator := NewGenerator(nil, "secret")
+	old := &TokenPayload{
+		EventID:    "event-1",
+		IssuedAt:   time.Now().Add(-30 * time.Minute),
+		AdmittedAt: time.Now().Add(-3 * time.Hour),
+	}
+
+	if _, _, err := generator.Refresh(old, time.Hour, 2*time.Hour); !errors.Is(err, ErrMaxLifetimeReached) {
+		t.Errorf("Expected ErrMaxLifetimeReached, got %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	generator, cleanup := setupTestGenerator(t)
+	if generator == nil {
+		return
+	}
+	defer cleanup()
+
+	admittedAt := time.Now().Add(-90 * time.Minute).Truncate(time.Second)
+	old := &TokenPayload{
+		EventID:    "event-1",
+		DeviceID:   "device-1",
+		UserID:     "user-1",
+		QueueID:    "queue-1",
+		IssuedAt:   admittedAt,
+		AdmittedAt: admittedAt,
+	}
+
+	token, payload, err := generator.Refresh(old, time.Hour, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Refresh() failed: %v", err)
+	}
+	if token == "" {
+		t.Fatal("Refresh() returned empty token")
+	}
+	if !payload.AdmittedAt.Equal(admittedAt) {
+		t.Errorf("AdmittedAt mismatch: expected %v, got %v", admittedAt, payload.AdmittedAt)
+	}
+	if payload.QueueID != old.QueueID || payload.DeviceID != old.DeviceID {
+		t.Errorf("Refreshed payload lost identity: %+v", payload)
+	}
+
+	// Only 30 minutes of the 2 hour lifetime remain
+	deadline := admittedAt.Add(2 * time.Hour)
+	if payload.ExpiresAt.After(deadline) {
+		t.Errorf("Expected expiry capped at %v, got %v", deadline, payload.ExpiresAt)
+	}
+}
+
+func TestIssue_TTL(t *testing.T) {
+	generator, cleanup := setupTestGenerator(t)
+	if generator == nil {
+		return
+	}
+	defer cleanup()
+
+	_, payload, err := generator.Issue(IssueRequest{EventID: "event-1", DeviceID: "device-1", TTL: 10 * time.Minute})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+
+	if ttl := payload.ExpiresAt.Sub(payload.IssuedAt); ttl != 10*time.Minute {
+		t.Errorf("Expected 10m TTL, got %v", ttl)
+	}
+	if !payload.AdmittedAt.Equal(payload.IssuedAt) {
+		t.Errorf("Expected AdmittedAt to default to IssuedAt, got %v", payload.AdmittedAt)
+	}
+}
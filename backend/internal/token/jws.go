@@ -0,0 +1,79 @@
+package token
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxTokenLength bounds the size of admission tokens accepted for
+	// verification. Longer tokens are not issued.
+	MaxTokenLength = 4096
+	// MaxRevocationListLength bounds the size of signed revocation lists
+	MaxRevocationListLength = 8 << 20
+)
+
+// decodeJWS verifies a compact HS256 JWS of the given "typ" and returns its
+// decoded payload. Every part must be canonical unpadded base64url, the MAC is
+// compared in constant time, and the header must name exactly HS256 and the
+// expected type, so tokens of one kind cannot be replayed as another.
+func (v *Verifier) decodeJWS(compact string, maxLength int, typ string) ([]byte, error) {
+	if len(compact) > maxLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrMalformedToken, maxLength)
+	}
+	if n := strings.Count(compact, "."); n != 2 {
+		return nil, fmt.Errorf("%w: expected 3 parts, got %d", ErrMalformedToken, n+1)
+	}
+
+	headerEncoded, rest, _ := strings.Cut(compact, ".")
+	payloadEncoded, signatureEncoded, _ := strings.Cut(rest, ".")
+
+	headerJSON, err := decodeSegment(headerEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode header: %v", ErrMalformedToken, err)
+	}
+	payloadJSON, err := decodeSegment(payloadEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode payload: %v", ErrMalformedToken, err)
+	}
+	signature, err := decodeSegment(signatureEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode signature: %v", ErrMalformedToken, err)
+	}
+
+	expected := v.createSignature(headerEncoded + "." + payloadEncoded)
+	if !hmac.Equal(signature, expected) {
+		return nil, ErrInvalidSignature
+	}
+
+	var header TokenHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal header: %v", ErrMalformedToken, err)
+	}
+	if header.Algorithm != TokenHeaderAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrMalformedToken, header.Algorithm)
+	}
+	// "typ" is a media type, which compares case-insensitively
+	if !strings.EqualFold(header.Type, typ) {
+		return nil, fmt.Errorf("%w: unexpected typ %q", ErrMalformedToken, header.Type)
+	}
+
+	return payloadJSON, nil
+}
+
+// decodeSegment decodes unpadded base64url, rejecting any other encoding of
+// the same bytes: padding, the standard alphabet, line breaks and non-zero
+// trailing bits
+func decodeSegment(segment string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.Strict().DecodeString(segment)
+	if err != nil {
+		return nil, err
+	}
+	if base64.RawURLEncoding.EncodeToString(data) != segment {
+		return nil, fmt.Errorf("non-canonical base64url")
+	}
+	return data, nil
+}
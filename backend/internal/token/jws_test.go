@@ -0,0 +1,175 @@
+package token
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "this-is-a-very-long-secret-key-that-is-at-least-32-characters"
+
+// signTestJWS signs an arbitrary header and payload with the test secret
+func signTestJWS(header, payload string) string {
+	verifier := NewVerifier(nil, testSecret)
+	input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return input + "." + base64.RawURLEncoding.EncodeToString(verifier.createSignature(input))
+}
+
+// testToken returns a validly signed, unexpired admission token
+func testToken(t testing.TB) string {
+	t.Helper()
+	now := time.Now()
+	token, err := NewGenerator(nil, testSecret).encode(TokenPayload{
+		Issuer:    DefaultIssuer,
+		EventID:   "event-1",
+		DeviceID:  "device-1",
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Hour),
+		Nonce:     "nonce-1",
+	})
+	if err != nil {
+		t.Fatalf("encode() failed: %v", err)
+	}
+	return token
+}
+
+func TestParseToken_Valid(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+
+	payload, err := verifier.parseToken(testToken(t))
+	if err != nil {
+		t.Fatalf("parseToken() failed: %v", err)
+	}
+	if payload.EventID != "event-1" || payload.Nonce != "nonce-1" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestParseToken_Rejects(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+	token := testToken(t)
+	parts := strings.Split(token, ".")
+	payload := `{"event_id":"event-1","nonce":"n"}`
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"too long", strings.Repeat("a", MaxTokenLength+1), ErrMalformedToken},
+		{"two parts", parts[0] + "." + parts[1], ErrMalformedToken},
+		{"four parts", token + ".extra", ErrMalformedToken},
+		{"padded signature", token + "=", ErrMalformedToken},
+		{"standard alphabet", parts[0] + "." + parts[1] + "." + strings.NewReplacer("-", "+", "_", "/").Replace(parts[2]) + "+", ErrMalformedToken},
+		{"line break", parts[0] + "." + parts[1][:4] + "\n" + parts[1][4:] + "." + parts[2], ErrMalformedToken},
+		{"non-zero trailing bits", parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-1] + "B", ErrMalformedToken},
+		{"tampered payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + parts[2], ErrInvalidSignature},
+		{"truncated signature", parts[0] + "." + parts[1] + "." + parts[2][:8], ErrInvalidSignature},
+		{"alg none", signTestJWS(`{"alg":"none","typ":"JWT"}`, payload), ErrMalformedToken},
+		{"alg HS512", signTestJWS(`{"alg":"HS512","typ":"JWT"}`, payload), ErrMalformedToken},
+		{"missing typ", signTestJWS(`{"alg":"HS256"}`, payload), ErrMalformedToken},
+		{"revocation list typ", signTestJWS(`{"alg":"HS256","typ":"`+RevocationListType+`"}`, payload), ErrMalformedToken},
+		{"header not JSON", signTestJWS(`not json`, payload), ErrMalformedToken},
+		{"payload not JSON", signTestJWS(`{"alg":"HS256","typ":"JWT"}`, `not json`), ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.parseToken(tt.token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseToken_TypeIsCaseInsensitive(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+
+	token := signTestJWS(`{"alg":"HS256","typ":"jwt"}`, `{"event_id":"event-1"}`)
+	if _, err := verifier.parseToken(token); err != nil {
+		t.Errorf("parseToken() failed: %v", err)
+	}
+}
+
+func FuzzParseToken(f *testing.F) {
+	token := testToken(f)
+	f.Add(token)
+	f.Add("")
+	f.Add("..")
+	f.Add("a.b.c")
+	f.Add("e30.e30.e30")
+	f.Add(token + "=")
+	f.Add(strings.ReplaceAll(token, "-", "+"))
+	f.Add(signTestJWS(`{"alg":"none","typ":"JWT"}`, `{}`))
+	f.Add(signTestJWS(`{"alg":"HS256","typ":"JWT"}`, `{"aud":["a","b"],"exp":1e300,"iat":-1}`))
+	f.Add(signTestJWS(`{"alg":"HS256","typ":"JWT"}`, `{"exp":"soon"}`))
+	f.Add(signTestJWS(`{"alg":"HS256","typ":"JWT"}`, `null`))
+
+	verifier := NewVerifier(nil, testSecret)
+	f.Fuzz(func(t *testing.T, input string) {
+		payload, err := verifier.parseToken(input)
+		if err != nil {
+			if !errors.Is(err, ErrMalformedToken) && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("Unexpected error type: %v", err)
+			}
+			return
+		}
+
+		// Anything accepted must be a bounded, canonical, correctly signed token
+		if len(input) > MaxTokenLength {
+			t.Fatalf("Accepted %d byte token", len(input))
+		}
+		if payload == nil {
+			t.Fatal("Accepted token without payload")
+		}
+		parts := strings.Split(input, ".")
+		input2 := parts[0] + "." + parts[1]
+		if got := base64.RawURLEncoding.EncodeToString(verifier.createSignature(input2)); got != parts[2] {
+			t.Fatalf("Accepted token with wrong signature")
+		}
+	})
+}
+
+func FuzzTokenPayloadUnmarshal(f *testing.F) {
+	f.Add(`{}`)
+	f.Add(`{"aud":"event-1","exp":1705316400,"iat":1705312800.5,"jti":"n"}`)
+	f.Add(`{"aud":["a","b"],"nbf":-1e18}`)
+	f.Add(`{"event_id":"e","issued_at":"2024-01-15T10:30:00Z","expires_at":"bad"}`)
+	f.Add(`{"exp":1e400}`)
+	f.Add(`[]`)
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var payload TokenPayload
+		if err := payload.UnmarshalJSON([]byte(data)); err != nil {
+			return
+		}
+		// Whatever decodes must encode again
+		if _, err := payload.MarshalJSON(); err != nil {
+			t.Fatalf("MarshalJSON() failed after decoding %q: %v", data, err)
+		}
+	})
+}
+
+func FuzzParseRevocationList(f *testing.F) {
+	verifier := NewVerifier(nil, testSecret)
+	signed, err := verifier.SignRevocationList(&RevocationList{EventID: "event-1", Nonces: []string{"n"}})
+	if err != nil {
+		f.Fatalf("SignRevocationList() failed: %v", err)
+	}
+	f.Add(signed)
+	f.Add(testToken(f))
+	f.Add("a.b.c")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		list, err := ParseRevocationList(input, testSecret)
+		if err != nil {
+			return
+		}
+		if list == nil {
+			t.Fatal("Accepted revocation list without content")
+		}
+	})
+}
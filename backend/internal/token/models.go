@@ -1,18 +1,31 @@
 package token
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 )
 
-// TokenPayload represents the payload of a token
+// TokenPayload represents the payload of a token. It is encoded with
+// standard JWT claims; see tokenClaims.
 type TokenPayload struct {
-	EventID   string    `json:"event_id"`
-	DeviceID  string    `json:"device_id"`
-	UserID    string    `json:"user_id"`
-	QueueID   string    `json:"queue_id"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiresAt time.Time `json:"expires_at"`
-	Nonce     string    `json:"nonce"`
+	Issuer    string
+	EventID   string
+	DeviceID  string
+	UserID    string
+	QueueID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	NotBefore time.Time
+	// AdmittedAt is when the first token of this admission was issued
+	AdmittedAt time.Time
+	Nonce      string
+	// KeyThumbprint binds the token to a device key; see VerifyProof
+	KeyThumbprint string
+	// Entitlements describe what the admitted user may do
+	Entitlements Entitlements
+	// Metadata is the join metadata the event copies into tokens
+	Metadata map[string]string
 }
 
 // TokenHeader represents the token header
@@ -21,9 +34,11 @@ type TokenHeader struct {
 	Type      string `json:"typ"`
 }
 
-// TokenMetadata represents metadata stored in Redis for a token
+// TokenMetadata represents metadata stored in Redis for a token. The raw
+// token is never stored; it is identified by its hash.
 type TokenMetadata struct {
-	Token     string    `json:"token"`
+	TokenHash string    `json:"token_hash"`
+	Nonce     string    `json:"nonce"`
 	EventID   string    `json:"event_id"`
 	DeviceID  string    `json:"device_id"`
 	UserID    string    `json:"user_id"`
@@ -34,7 +49,14 @@ type TokenMetadata struct {
 	Used      bool      `json:"used"`
 }
 
-// TokenKey returns the Redis key for a token
-func TokenKey(token string) string {
-	return "token:" + token
+// TokenHash returns the hex SHA-256 hash identifying a token in Redis, logs
+// and audit entries
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenKey returns the Redis key for a token's metadata, given its hash
+func TokenKey(tokenHash string) string {
+	return "token:" + tokenHash
 }
@@ -0,0 +1,233 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Device binding follows DPoP (RFC 9449): a client registers a public key at
+// join, its admission tokens carry the key's RFC 7638 thumbprint in the "cnf"
+// claim, and each request presenting a bound token must carry a fresh proof
+// signed with the private key. A copied token is useless without the key.
+
+const (
+	// ProofType is the "typ" header of proof-of-possession proofs
+	ProofType = "dpop+jwt"
+	// ProofMaxAge is how far a proof's "iat" may be from now
+	ProofMaxAge = time.Minute
+)
+
+// JWK is a public key registered for device binding. P-256 keys (ES256) and
+// Ed25519 keys (EdDSA) are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Thumbprint validates the key and returns its RFC 7638 SHA-256 thumbprint
+func (k *JWK) Thumbprint() (string, error) {
+	if _, err := k.publicKey(); err != nil {
+		return "", err
+	}
+
+	// Required members only, in lexicographic order
+	var canonical string
+	switch k.Kty {
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, k.Crv, k.Kty, k.X, k.Y)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, k.Crv, k.Kty, k.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// algorithm returns the JWS algorithm used with the key
+func (k *JWK) algorithm() string {
+	if k.Kty == "OKP" {
+		return "EdDSA"
+	}
+	return "ES256"
+}
+
+// publicKey decodes and validates the key
+func (k *JWK) publicKey() (interface{}, error) {
+	switch {
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, errX := decodeSegment(k.X)
+		y, errY := decodeSegment(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid P-256 key coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("P-256 key is not on the curve")
+		}
+		return key, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := decodeSegment(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s/%s (expected EC/P-256 or OKP/Ed25519)", k.Kty, k.Crv)
+	}
+}
+
+// proofHeader is the protected header of a proof
+type proofHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	JWK       *JWK   `json:"jwk"`
+}
+
+// proofClaims are the claims of a proof
+type proofClaims struct {
+	ID              string       `json:"jti"`
+	Method          string       `json:"htm"`
+	URL             string       `json:"htu"`
+	IssuedAt        *numericDate `json:"iat"`
+	AccessTokenHash string       `json:"ath"`
+}
+
+// ProofReplayKey returns the Redis key marking a proof as used
+func ProofReplayKey(thumbprint, jti string) string {
+	return "token:proof:" + thumbprint + ":" + jti
+}
+
+// VerifyProof checks the proof-of-possession for a request presenting a
+// verified token. Unbound tokens need no proof. A bound token needs a proof
+// signed by its key, for this request's method and URL, issued within
+// ProofMaxAge and carrying the token's hash. Proofs are single use when the
+// verifier has Redis.
+func (v *Verifier) VerifyProof(proof string, payload *TokenPayload, accessToken, method, requestURL string) error {
+	if payload.KeyThumbprint == "" {
+		return nil
+	}
+	if proof == "" {
+		return fmt.Errorf("%w: token is bound to a key, proof required", ErrInvalidProof)
+	}
+	if len(proof) > MaxTokenLength {
+		return fmt.Errorf("%w: longer than %d bytes", ErrInvalidProof, MaxTokenLength)
+	}
+	if n := strings.Count(proof, "."); n != 2 {
+		return fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalidProof, n+1)
+	}
+
+	headerEncoded, rest, _ := strings.Cut(proof, ".")
+	claimsEncoded, signatureEncoded, _ := strings.Cut(rest, ".")
+	headerJSON, errHeader := decodeSegment(headerEncoded)
+	claimsJSON, errClaims := decodeSegment(claimsEncoded)
+	signature, errSignature := decodeSegment(signatureEncoded)
+	if errHeader != nil || errClaims != nil || errSignature != nil {
+		return fmt.Errorf("%w: invalid base64url", ErrInvalidProof)
+	}
+
+	var header proofHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.JWK == nil {
+		return fmt.Errorf("%w: invalid header", ErrInvalidProof)
+	}
+	if !strings.EqualFold(header.Type, ProofType) {
+		return fmt.Errorf("%w: unexpected typ %q", ErrInvalidProof, header.Type)
+	}
+
+	thumbprint, err := header.JWK.Thumbprint()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	if thumbprint != payload.KeyThumbprint {
+		return fmt.Errorf("%w: key does not match the token", ErrInvalidProof)
+	}
+	if header.Algorithm != header.JWK.algorithm() {
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidProof, header.Algorithm)
+	}
+	if !verifyProofSignature(header.JWK, headerEncoded+"."+claimsEncoded, signature) {
+		return fmt.Errorf("%w: invalid signature", ErrInvalidProof)
+	}
+
+	var claims proofClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return fmt.Errorf("%w: invalid claims", ErrInvalidProof)
+	}
+	if claims.ID == "" {
+		return fmt.Errorf("%w: jti is required", ErrInvalidProof)
+	}
+	if claims.Method != method {
+		return fmt.Errorf("%w: htm %q does not match %s", ErrInvalidProof, claims.Method, method)
+	}
+	if !sameURL(claims.URL, requestURL) {
+		return fmt.Errorf("%w: htu %q does not match the request", ErrInvalidProof, claims.URL)
+	}
+	if claims.IssuedAt == nil {
+		return fmt.Errorf("%w: iat is required", ErrInvalidProof)
+	}
+	if age := time.Since(claims.IssuedAt.Time); age > ProofMaxAge+v.leeway || age < -ProofMaxAge-v.leeway {
+		return fmt.Errorf("%w: iat outside the allowed window", ErrInvalidProof)
+	}
+	tokenHash := sha256.Sum256([]byte(accessToken))
+	if claims.AccessTokenHash != base64.RawURLEncoding.EncodeToString(tokenHash[:]) {
+		return fmt.Errorf("%w: ath does not match the token", ErrInvalidProof)
+	}
+
+	if v.redisClient == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+	fresh, err := v.redisClient.GetClient().SetNX(ctx, ProofReplayKey(thumbprint, claims.ID), 1, 2*(ProofMaxAge+v.leeway)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record proof: %w", err)
+	}
+	if !fresh {
+		return fmt.Errorf("%w: proof already used", ErrInvalidProof)
+	}
+	return nil
+}
+
+// verifyProofSignature checks a JWS signature made with the key
+func verifyProofSignature(key *JWK, input string, signature []byte) bool {
+	publicKey, err := key.publicKey()
+	if err != nil {
+		return false
+	}
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		// JWS encodes ES256 signatures as r || s
+		if len(signature) != 64 {
+			return false
+		}
+		digest := sha256.Sum256([]byte(input))
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(publicKey, digest[:], r, s)
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, []byte(input), signature)
+	}
+	return false
+}
+
+// sameURL compares an htu claim with the request URL, ignoring query,
+// fragment and the case of scheme and host
+func sameURL(htu, requestURL string) bool {
+	a, errA := url.Parse(htu)
+	b, errB := url.Parse(requestURL)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
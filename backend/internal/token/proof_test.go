@@ -0,0 +1,231 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testProofURL = "https://gatekeep.example.com/admission/refresh"
+
+// testKey is a device key pair used to sign proofs
+type testKey struct {
+	jwk    *JWK
+	signer crypto.Signer
+}
+
+func newECKey(t *testing.T) *testKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return &testKey{
+		jwk: &JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(x),
+			Y:   base64.RawURLEncoding.EncodeToString(y),
+		},
+		signer: key,
+	}
+}
+
+func newEdKey(t *testing.T) *testKey {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return &testKey{
+		jwk:    &JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(public)},
+		signer: private,
+	}
+}
+
+// sign creates a proof with the given claims
+func (k *testKey) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]interface{}{"typ": ProofType, "alg": k.jwk.algorithm(), "jwk": k.jwk})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch signer := k.signer.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		r, s, err := ecdsa.Sign(rand.Reader, signer, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign proof: %v", err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(signer, []byte(input))
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// proofClaimsFor returns valid proof claims for a token
+func proofClaimsFor(accessToken string) map[string]interface{} {
+	hash := sha256.Sum256([]byte(accessToken))
+	return map[string]interface{}{
+		"jti": "proof-" + time.Now().Format(time.RFC3339Nano),
+		"htm": "POST",
+		"htu": testProofURL,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(hash[:]),
+	}
+}
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// RFC 8037 appendix A.2 key and A.3 thumbprint
+	key := &JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatalf("Thumbprint() failed: %v", err)
+	}
+	if thumbprint != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("Unexpected thumbprint: %s", thumbprint)
+	}
+}
+
+func TestJWK_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		key  JWK
+	}{
+		{"unsupported kty", JWK{Kty: "RSA"}},
+		{"unsupported curve", JWK{Kty: "EC", Crv: "P-384", X: "AA", Y: "AA"}},
+		{"short coordinates", JWK{Kty: "EC", Crv: "P-256", X: "AA", Y: "AA"}},
+		{"not on curve", JWK{Kty: "EC", Crv: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(make([]byte, 32)),
+			Y: base64.RawURLEncoding.EncodeToString(make([]byte, 32))}},
+		{"short Ed25519", JWK{Kty: "OKP", Crv: "Ed25519", X: "AA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.key.Thumbprint(); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestVerifyProof(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+	accessToken := "header.payload.signature"
+
+	for _, key := range []*testKey{newECKey(t), newEdKey(t)} {
+		t.Run(key.jwk.Kty, func(t *testing.T) {
+			thumbprint, err := key.jwk.Thumbprint()
+			if err != nil {
+				t.Fatalf("Thumbprint() failed: %v", err)
+			}
+			payload := &TokenPayload{KeyThumbprint: thumbprint}
+
+			if err := verifier.VerifyProof(key.sign(t, proofClaimsFor(accessToken)), payload, accessToken, "POST", testProofURL+"?x=1"); err != nil {
+				t.Errorf("VerifyProof() failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyProof_Rejects(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+	accessToken := "header.payload.signature"
+	key := newECKey(t)
+	thumbprint, _ := key.jwk.Thumbprint()
+	payload := &TokenPayload{KeyThumbprint: thumbprint}
+
+	withClaim := func(claim string, value interface{}) string {
+		claims := proofClaimsFor(accessToken)
+		if value == nil {
+			delete(claims, claim)
+		} else {
+			claims[claim] = value
+		}
+		return key.sign(t, claims)
+	}
+	valid := key.sign(t, proofClaimsFor(accessToken))
+
+	tests := []struct {
+		name  string
+		proof string
+	}{
+		{"missing", ""},
+		{"malformed", "not-a-proof"},
+		{"other key", newECKey(t).sign(t, proofClaimsFor(accessToken))},
+		{"tampered", valid[:len(valid)-4] + "AAAA"},
+		{"wrong method", withClaim("htm", "GET")},
+		{"wrong url", withClaim("htu", "https://evil.example.com/admission/refresh")},
+		{"stale", withClaim("iat", time.Now().Add(-10*time.Minute).Unix())},
+		{"missing iat", withClaim("iat", nil)},
+		{"missing jti", withClaim("jti", nil)},
+		{"other token", withClaim("ath", "bm90LXRoZS10b2tlbg")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.VerifyProof(tt.proof, payload, accessToken, "POST", testProofURL)
+			if !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("Expected ErrInvalidProof, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyProof_UnboundToken(t *testing.T) {
+	verifier := NewVerifier(nil, testSecret)
+	if err := verifier.VerifyProof("", &TokenPayload{}, "token", "POST", testProofURL); err != nil {
+		t.Errorf("Expected unbound token to need no proof, got %v", err)
+	}
+}
+
+func TestVerifyProof_Replay(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	key := newEdKey(t)
+	thumbprint, _ := key.jwk.Thumbprint()
+	accessToken, payload, err := generator.Issue(IssueRequest{EventID: "event-1", DeviceID: "device-1", KeyThumbprint: thumbprint})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+
+	verified, err := verifier.VerifyToken(accessToken, "event-1")
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+	if verified.KeyThumbprint != payload.KeyThumbprint {
+		t.Fatalf("Expected cnf thumbprint %s, got %s", payload.KeyThumbprint, verified.KeyThumbprint)
+	}
+
+	proof := key.sign(t, proofClaimsFor(accessToken))
+	if err := verifier.VerifyProof(proof, verified, accessToken, "POST", testProofURL); err != nil {
+		t.Fatalf("VerifyProof() failed: %v", err)
+	}
+	if err := verifier.VerifyProof(proof, verified, accessToken, "POST", testProofURL); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("Expected replayed proof to fail, got %v", err)
+	}
+}
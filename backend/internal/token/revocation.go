@@ -0,0 +1,260 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bulk revocation is recorded as "not before" epochs rather than by touching
+// individual tokens: a token is revoked if it was issued at or before the
+// epoch of its event, user or device. Time windows are kept in a sorted set
+// scored by their end in microseconds, which float scores represent exactly.
+// Verification therefore costs one pipelined round trip regardless of how
+// many tokens were revoked. Records only need to outlive the tokens they
+// cover, so they expire after RevocationRetention.
+
+const (
+	// RevocationRetention is how long revocation records are kept. Tokens
+	// issued before a record are expired by then.
+	RevocationRetention = MaxTokenLifetime
+
+	revocationFieldEvent = "event"
+)
+
+// RevocationFilter selects the tokens to revoke. Leaving EventID empty applies
+// user, device and time window revocations across all events.
+type RevocationFilter struct {
+	EventID      string    `json:"event_id,omitempty"`
+	UserID       string    `json:"user_id,omitempty"`
+	DeviceID     string    `json:"device_id,omitempty"`
+	IssuedAfter  time.Time `json:"issued_after,omitempty"`
+	IssuedBefore time.Time `json:"issued_before,omitempty"`
+}
+
+// hasWindow reports whether the filter selects a time window
+func (f RevocationFilter) hasWindow() bool {
+	return !f.IssuedAfter.IsZero() || !f.IssuedBefore.IsZero()
+}
+
+// RevocationKey returns the Redis hash of revocation epochs for an event, or
+// the global hash when eventID is empty
+func RevocationKey(eventID string) string {
+	if eventID == "" {
+		return "token:revocations:global"
+	}
+	return "token:revocations:event:" + eventID
+}
+
+// RevocationWindowsKey returns the Redis sorted set of revoked issue windows
+// for an event, or the global set when eventID is empty
+func RevocationWindowsKey(eventID string) string {
+	if eventID == "" {
+		return "token:revocation-windows:global"
+	}
+	return "token:revocation-windows:event:" + eventID
+}
+
+// RevokedNoncesKey returns the Redis sorted set of individually revoked token
+// nonces for an event, scored by token expiry
+func RevokedNoncesKey(eventID string) string {
+	return "token:revoked-nonces:event:" + eventID
+}
+
+// RevocationVersionKey returns the Redis counter bumped on every revocation
+// for an event, or for global revocations when eventID is empty
+func RevocationVersionKey(eventID string) string {
+	if eventID == "" {
+		return "token:revocation-version:global"
+	}
+	return "token:revocation-version:event:" + eventID
+}
+
+// RevokeTokens revokes every token matching the filter that has been issued
+// so far. Tokens issued afterwards are unaffected.
+//
+//   - event_id alone revokes all tokens of the event
+//   - user_id and/or device_id revoke that subject's tokens, within event_id
+//     if given
+//   - issued_after/issued_before revoke tokens issued in the window, within
+//     event_id if given; issued_before defaults to now
+func (v *Verifier) RevokeTokens(filter RevocationFilter) error {
+	now := time.Now()
+	hasSubject := filter.UserID != "" || filter.DeviceID != ""
+
+	switch {
+	case filter.hasWindow() && hasSubject:
+		return fmt.Errorf("%w: a time window cannot be combined with user_id or device_id", ErrInvalidRevocation)
+	case !filter.hasWindow() && !hasSubject && filter.EventID == "":
+		return fmt.Errorf("%w: one of event_id, user_id, device_id or a time window is required", ErrInvalidRevocation)
+	}
+
+	from, to := filter.IssuedAfter, filter.IssuedBefore
+	if to.IsZero() || to.After(now) {
+		to = now
+	}
+	if filter.hasWindow() && !from.Before(to) {
+		return fmt.Errorf("%w: issued_after must be before issued_before", ErrInvalidRevocation)
+	}
+
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	pipe := v.redisClient.GetClient().TxPipeline()
+
+	if filter.hasWindow() {
+		var start int64
+		if !from.IsZero() {
+			start = from.UnixNano()
+		}
+		key := RevocationWindowsKey(filter.EventID)
+		pipe.ZAdd(ctx, key, redis.Z{
+			Score:  float64(to.UnixMicro()),
+			Member: fmt.Sprintf("%d:%d", start, to.UnixNano()),
+		})
+		// Windows whose tokens have all expired are no longer needed
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-RevocationRetention).UnixMicro(), 10))
+		pipe.Expire(ctx, key, RevocationRetention)
+	} else {
+		epoch := strconv.FormatInt(now.UnixNano(), 10)
+		fields := make([]string, 0, 4)
+		if filter.UserID != "" {
+			fields = append(fields, revocationField("user", filter.UserID), epoch)
+		}
+		if filter.DeviceID != "" {
+			fields = append(fields, revocationField("device", filter.DeviceID), epoch)
+		}
+		if !hasSubject {
+			fields = append(fields, revocationFieldEvent, epoch)
+		}
+		key := RevocationKey(filter.EventID)
+		pipe.HSet(ctx, key, fields)
+		pipe.Expire(ctx, key, RevocationRetention)
+	}
+	pipe.Incr(ctx, RevocationVersionKey(filter.EventID))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record revocation: %w", err)
+	}
+
+	slog.Info("tokens revoked",
+		"event_id", filter.EventID,
+		"user_id", filter.UserID,
+		"device_id", filter.DeviceID,
+		"issued_after", from,
+		"issued_before", to,
+	)
+	return nil
+}
+
+// revokeNonce adds a single token to its event's revocation list
+func (v *Verifier) revokeNonce(payload *TokenPayload) error {
+	if payload.Nonce == "" {
+		return fmt.Errorf("%w: token has no nonce", ErrMalformedToken)
+	}
+
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	key := RevokedNoncesKey(payload.EventID)
+	pipe := v.redisClient.GetClient().TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(payload.ExpiresAt.Unix()), Member: payload.Nonce})
+	// Expired tokens fail verification anyway
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	pipe.Incr(ctx, RevocationVersionKey(payload.EventID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// claimNonce revokes the payload's nonce unless it already was, and reports
+// whether this call revoked it
+func (v *Verifier) claimNonce(payload *TokenPayload) (bool, error) {
+	if payload.Nonce == "" {
+		return false, fmt.Errorf("%w: token has no nonce", ErrMalformedToken)
+	}
+
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	key := RevokedNoncesKey(payload.EventID)
+	pipe := v.redisClient.GetClient().TxPipeline()
+	// Expired tokens fail verification anyway
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	added := pipe.ZAddNX(ctx, key, redis.Z{Score: float64(payload.ExpiresAt.Unix()), Member: payload.Nonce})
+	pipe.Incr(ctx, RevocationVersionKey(payload.EventID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return added.Val() == 1, nil
+}
+
+// checkRevocations returns ErrTokenRevoked if the payload's nonce was revoked
+// or it is covered by a bulk revocation
+func (v *Verifier) checkRevocations(payload *TokenPayload) error {
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	issuedAt := payload.IssuedAt.UnixNano()
+	issuedAtScore := strconv.FormatInt(payload.IssuedAt.UnixMicro(), 10)
+	userField := revocationField("user", payload.UserID)
+	deviceField := revocationField("device", payload.DeviceID)
+
+	pipe := v.redisClient.GetClient().Pipeline()
+	eventEpochs := pipe.HMGet(ctx, RevocationKey(payload.EventID), revocationFieldEvent, userField, deviceField)
+	globalEpochs := pipe.HMGet(ctx, RevocationKey(""), userField, deviceField)
+	eventWindows := pipe.ZRangeByScore(ctx, RevocationWindowsKey(payload.EventID), &redis.ZRangeBy{Min: issuedAtScore, Max: "+inf"})
+	globalWindows := pipe.ZRangeByScore(ctx, RevocationWindowsKey(""), &redis.ZRangeBy{Min: issuedAtScore, Max: "+inf"})
+	nonce := pipe.ZScore(ctx, RevokedNoncesKey(payload.EventID), payload.Nonce)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to check revocations: %w", err)
+	}
+
+	if nonce.Err() == nil {
+		return ErrTokenRevoked
+	}
+
+	for _, epochs := range [][]interface{}{eventEpochs.Val(), globalEpochs.Val()} {
+		for _, value := range epochs {
+			if epoch, ok := parseEpoch(value); ok && issuedAt <= epoch {
+				return ErrTokenRevoked
+			}
+		}
+	}
+
+	// Windows are returned only if they end at or after the issue time
+	for _, windows := range [][]string{eventWindows.Val(), globalWindows.Val()} {
+		for _, window := range windows {
+			from, _, ok := strings.Cut(window, ":")
+			if !ok {
+				continue
+			}
+			if start, err := strconv.ParseInt(from, 10, 64); err == nil && issuedAt >= start {
+				return ErrTokenRevoked
+			}
+		}
+	}
+
+	return nil
+}
+
+// revocationField returns the hash field holding a subject's revocation epoch
+func revocationField(kind, id string) string {
+	return kind + ":" + id
+}
+
+// parseEpoch parses an HMGET value holding a UnixNano epoch
+func parseEpoch(value interface{}) (int64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	epoch, err := strconv.ParseInt(s, 10, 64)
+	return epoch, err == nil
+}
@@ -0,0 +1,207 @@
+package token
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationListType is the "typ" header of signed revocation lists, which
+// keeps them from being accepted as admission tokens and vice versa
+const RevocationListType = "gatekeep-revocations+jwt"
+
+// RevocationList is a snapshot of every revocation that applies to an
+// event's unexpired tokens, for verifiers without Redis access. It includes
+// revocations made across all events.
+type RevocationList struct {
+	EventID     string    `json:"event_id"`
+	Version     string    `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Nonces of individually revoked tokens
+	Nonces []string `json:"nonces"`
+	// Tokens issued at or before these times are revoked
+	RevokedBefore *time.Time           `json:"revoked_before,omitempty"`
+	Users         map[string]time.Time `json:"users,omitempty"`
+	Devices       map[string]time.Time `json:"devices,omitempty"`
+	// Tokens issued within these windows are revoked
+	Windows []RevokedWindow `json:"windows,omitempty"`
+}
+
+// RevokedWindow is a revoked range of issue times, inclusive
+type RevokedWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Revokes reports whether the list revokes a token with the given payload
+func (l *RevocationList) Revokes(payload *TokenPayload) bool {
+	for _, nonce := range l.Nonces {
+		if nonce == payload.Nonce {
+			return true
+		}
+	}
+	if l.RevokedBefore != nil && !payload.IssuedAt.After(*l.RevokedBefore) {
+		return true
+	}
+	if epoch, ok := l.Users[payload.UserID]; ok && !payload.IssuedAt.After(epoch) {
+		return true
+	}
+	if epoch, ok := l.Devices[payload.DeviceID]; ok && !payload.IssuedAt.After(epoch) {
+		return true
+	}
+	for _, window := range l.Windows {
+		if !payload.IssuedAt.Before(window.From) && !payload.IssuedAt.After(window.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// RevocationListVersion returns the current version of an event's revocation
+// list. It changes whenever a revocation affecting the event is recorded.
+func (v *Verifier) RevocationListVersion(eventID string) (string, error) {
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	values, err := v.redisClient.GetClient().MGet(ctx, RevocationVersionKey(eventID), RevocationVersionKey("")).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get revocation list version: %w", err)
+	}
+
+	versions := make([]string, len(values))
+	for i, value := range values {
+		versions[i] = "0"
+		if s, ok := value.(string); ok {
+			versions[i] = s
+		}
+	}
+	return strings.Join(versions, "."), nil
+}
+
+// RevocationList builds an event's current revocation list
+func (v *Verifier) RevocationList(eventID string) (*RevocationList, error) {
+	if eventID == "" {
+		return nil, fmt.Errorf("event_id is required")
+	}
+
+	version, err := v.RevocationListVersion(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	pipe := v.redisClient.GetClient().Pipeline()
+	nonces := pipe.ZRangeByScore(ctx, RevokedNoncesKey(eventID), &redis.ZRangeBy{
+		Min: strconv.FormatInt(now.Unix(), 10),
+		Max: "+inf",
+	})
+	eventEpochs := pipe.HGetAll(ctx, RevocationKey(eventID))
+	globalEpochs := pipe.HGetAll(ctx, RevocationKey(""))
+	windowsMin := strconv.FormatInt(now.Add(-RevocationRetention).UnixMicro(), 10)
+	eventWindows := pipe.ZRangeByScore(ctx, RevocationWindowsKey(eventID), &redis.ZRangeBy{Min: windowsMin, Max: "+inf"})
+	globalWindows := pipe.ZRangeByScore(ctx, RevocationWindowsKey(""), &redis.ZRangeBy{Min: windowsMin, Max: "+inf"})
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to build revocation list: %w", err)
+	}
+
+	list := &RevocationList{
+		EventID:     eventID,
+		Version:     version,
+		GeneratedAt: now.UTC(),
+		Nonces:      nonces.Val(),
+		Users:       make(map[string]time.Time),
+		Devices:     make(map[string]time.Time),
+		Windows:     []RevokedWindow{},
+	}
+	sort.Strings(list.Nonces)
+
+	for _, epochs := range []map[string]string{eventEpochs.Val(), globalEpochs.Val()} {
+		for field, value := range epochs {
+			epoch, ok := parseEpoch(value)
+			if !ok {
+				continue
+			}
+			at := time.Unix(0, epoch).UTC()
+			kind, id, _ := strings.Cut(field, ":")
+			switch kind {
+			case revocationFieldEvent:
+				list.RevokedBefore = &at
+			case "user":
+				mergeEpoch(list.Users, id, at)
+			case "device":
+				mergeEpoch(list.Devices, id, at)
+			}
+		}
+	}
+
+	for _, windows := range [][]string{eventWindows.Val(), globalWindows.Val()} {
+		for _, window := range windows {
+			from, to, ok := strings.Cut(window, ":")
+			if !ok {
+				continue
+			}
+			start, errFrom := strconv.ParseInt(from, 10, 64)
+			end, errTo := strconv.ParseInt(to, 10, 64)
+			if errFrom != nil || errTo != nil {
+				continue
+			}
+			list.Windows = append(list.Windows, RevokedWindow{
+				From: time.Unix(0, start).UTC(),
+				To:   time.Unix(0, end).UTC(),
+			})
+		}
+	}
+
+	return list, nil
+}
+
+// SignRevocationList encodes a revocation list as a compact JWS signed with
+// the token secret, so any HS256 JWT library can verify it
+func (v *Verifier) SignRevocationList(list *RevocationList) (string, error) {
+	headerJSON, err := json.Marshal(TokenHeader{Algorithm: TokenHeaderAlgorithm, Type: RevocationListType})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal header: %w", err)
+	}
+	payloadJSON, err := json.Marshal(list)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal revocation list: %w", err)
+	}
+
+	signatureInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+	signature := base64.RawURLEncoding.EncodeToString(v.createSignature(signatureInput))
+	return signatureInput + "." + signature, nil
+}
+
+// ParseRevocationList verifies a signed revocation list and decodes it. It
+// needs only the token secret, not Redis.
+func ParseRevocationList(signed string, secret string) (*RevocationList, error) {
+	verifier := &Verifier{secret: []byte(secret)}
+	payloadJSON, err := verifier.decodeJWS(signed, MaxRevocationListLength, RevocationListType)
+	if err != nil {
+		return nil, err
+	}
+
+	var list RevocationList
+	if err := json.Unmarshal(payloadJSON, &list); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal revocation list: %v", ErrMalformedToken, err)
+	}
+	return &list, nil
+}
+
+// mergeEpoch keeps the latest epoch per subject
+func mergeEpoch(epochs map[string]time.Time, id string, at time.Time) {
+	if current, ok := epochs[id]; !ok || at.After(current) {
+		epochs[id] = at
+	}
+}
@@ -0,0 +1,125 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevocationList_Revokes(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	before := base.Add(-time.Hour)
+	list := &RevocationList{
+		Nonces:        []string{"revoked-nonce"},
+		RevokedBefore: &before,
+		Users:         map[string]time.Time{"user-1": base},
+		Devices:       map[string]time.Time{"device-1": base},
+		Windows:       []RevokedWindow{{From: base.Add(time.Hour), To: base.Add(2 * time.Hour)}},
+	}
+
+	tests := []struct {
+		name    string
+		payload TokenPayload
+		want    bool
+	}{
+		{"revoked nonce", TokenPayload{Nonce: "revoked-nonce", IssuedAt: base.Add(3 * time.Hour)}, true},
+		{"before event epoch", TokenPayload{Nonce: "n", IssuedAt: before.Add(-time.Minute)}, true},
+		{"user epoch", TokenPayload{Nonce: "n", UserID: "user-1", IssuedAt: base}, true},
+		{"user issued later", TokenPayload{Nonce: "n", UserID: "user-1", IssuedAt: base.Add(time.Minute)}, false},
+		{"device epoch", TokenPayload{Nonce: "n", DeviceID: "device-1", IssuedAt: base.Add(-time.Minute)}, true},
+		{"inside window", TokenPayload{Nonce: "n", IssuedAt: base.Add(90 * time.Minute)}, true},
+		{"after window", TokenPayload{Nonce: "n", IssuedAt: base.Add(3 * time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Revokes(&tt.payload); got != tt.want {
+				t.Errorf("Revokes() = %v, expected %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignRevocationList_RoundTrip(t *testing.T) {
+	secret := "this-is-a-very-long-secret-key-that-is-at-least-32-characters"
+	verifier := NewVerifier(nil, secret)
+
+	list := &RevocationList{
+		EventID:     "event-1",
+		Version:     "3.1",
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Nonces:      []string{"a", "b"},
+	}
+	signed, err := verifier.SignRevocationList(list)
+	if err != nil {
+		t.Fatalf("SignRevocationList() failed: %v", err)
+	}
+
+	parsed, err := ParseRevocationList(signed, secret)
+	if err != nil {
+		t.Fatalf("ParseRevocationList() failed: %v", err)
+	}
+	if parsed.Version != list.Version || len(parsed.Nonces) != 2 {
+		t.Errorf("Round trip mismatch: %+v", parsed)
+	}
+
+	if _, err := ParseRevocationList(signed, "a-different-secret-that-is-also-long-enough"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature with wrong secret, got %v", err)
+	}
+
+	parts := strings.Split(signed, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := ParseRevocationList(tampered, secret); err == nil {
+		t.Error("Expected error for tampered list, got nil")
+	}
+}
+
+func TestParseRevocationList_RejectsAdmissionToken(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	token, err := generator.GenerateToken("event-1", "device-1", "user-1", "queue-1")
+	if err != nil {
+		t.Fatalf("GenerateToken() failed: %v", err)
+	}
+	if _, err := ParseRevocationList(token, string(verifier.secret)); !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("Expected ErrMalformedToken, got %v", err)
+	}
+}
+
+func TestRevocationList_IncludesRevokedNonce(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	token, payload, err := generator.IssueToken("event-rl", "device-1", "user-1", "queue-1")
+	if err != nil {
+		t.Fatalf("IssueToken() failed: %v", err)
+	}
+
+	before, err := verifier.RevocationListVersion("event-rl")
+	if err != nil {
+		t.Fatalf("RevocationListVersion() failed: %v", err)
+	}
+
+	if err := verifier.RevokeToken(token); err != nil {
+		t.Fatalf("RevokeToken() failed: %v", err)
+	}
+
+	list, err := verifier.RevocationList("event-rl")
+	if err != nil {
+		t.Fatalf("RevocationList() failed: %v", err)
+	}
+	if list.Version == before {
+		t.Errorf("Expected version to change from %s", before)
+	}
+	if !list.Revokes(payload) {
+		t.Errorf("Expected list to revoke nonce %s, got %v", payload.Nonce, list.Nonces)
+	}
+}
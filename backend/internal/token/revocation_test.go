@@ -0,0 +1,95 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRevokeTokens_InvalidFilter(t *testing.T) {
+	verifier := &Verifier{}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		filter RevocationFilter
+	}{
+		{"empty", RevocationFilter{}},
+		{"window with subject", RevocationFilter{UserID: "user-1", IssuedAfter: now.Add(-time.Hour)}},
+		{"inverted window", RevocationFilter{IssuedAfter: now.Add(-time.Minute), IssuedBefore: now.Add(-time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifier.RevokeTokens(tt.filter); !errors.Is(err, ErrInvalidRevocation) {
+				t.Errorf("Expected ErrInvalidRevocation, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRevokeTokens(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	tests := []struct {
+		name    string
+		filter  RevocationFilter
+		revoked []string // queue IDs expected to be revoked
+	}{
+		{"by event", RevocationFilter{EventID: "event-a"}, []string{"q1", "q2"}},
+		{"by user in event", RevocationFilter{EventID: "event-a", UserID: "user-1"}, []string{"q1"}},
+		{"by user across events", RevocationFilter{UserID: "user-1"}, []string{"q1", "q3"}},
+		{"by device", RevocationFilter{DeviceID: "device-2"}, []string{"q2"}},
+		{"by window", RevocationFilter{EventID: "event-b", IssuedAfter: time.Now().Add(-time.Minute)}, []string{"q3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup()
+
+			tokens := map[string]string{}
+			for queueID, claims := range map[string][3]string{
+				"q1": {"event-a", "device-1", "user-1"},
+				"q2": {"event-a", "device-2", "user-2"},
+				"q3": {"event-b", "device-3", "user-1"},
+			} {
+				token, err := generator.GenerateToken(claims[0], claims[1], claims[2], queueID)
+				if err != nil {
+					t.Fatalf("GenerateToken() failed: %v", err)
+				}
+				tokens[queueID] = token
+			}
+
+			if err := verifier.RevokeTokens(tt.filter); err != nil {
+				t.Fatalf("RevokeTokens() failed: %v", err)
+			}
+
+			revoked := map[string]bool{}
+			for _, queueID := range tt.revoked {
+				revoked[queueID] = true
+			}
+			for queueID, token := range tokens {
+				_, err := verifier.VerifyToken(token, "")
+				if revoked[queueID] && !errors.Is(err, ErrTokenRevoked) {
+					t.Errorf("%s: expected ErrTokenRevoked, got %v", queueID, err)
+				}
+				if !revoked[queueID] && err != nil {
+					t.Errorf("%s: expected valid token, got %v", queueID, err)
+				}
+			}
+
+			// Tokens issued after the revocation are unaffected
+			token, err := generator.GenerateToken("event-a", "device-1", "user-1", "q4")
+			if err != nil {
+				t.Fatalf("GenerateToken() failed: %v", err)
+			}
+			if _, err := verifier.VerifyToken(token, ""); err != nil {
+				t.Errorf("Expected token issued after revocation to be valid, got %v", err)
+			}
+		})
+	}
+}
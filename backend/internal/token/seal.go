@@ -0,0 +1,63 @@
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// sealInfo separates the sealing key from other keys derived from the secret
+const sealInfo = "gatekeep admission token seal"
+
+// Sealer encrypts issued tokens so they can be kept at rest and handed to the
+// client on every status read until they expire. Sealed tokens are bound to
+// their queue entry.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a sealer with an AES-256-GCM key derived from secret.
+// The key size is fixed, so it panics only if the platform lacks AES-GCM.
+func NewSealer(secret string) *Sealer {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, sealInfo, 32)
+	if err != nil {
+		panic(fmt.Sprintf("token: failed to derive sealing key: %v", err))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(fmt.Sprintf("token: %v", err))
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Sprintf("token: %v", err))
+	}
+	return &Sealer{aead: aead}
+}
+
+// Seal encrypts the token for the queue entry
+func (s *Sealer) Seal(tokenString, queueID string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(tokenString), []byte(queueID))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a token sealed for the queue entry
+func (s *Sealer) Open(sealed, queueID string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", fmt.Errorf("invalid sealed token")
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(queueID))
+	if err != nil {
+		return "", fmt.Errorf("invalid sealed token")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,38 @@
+package token
+
+import "testing"
+
+func TestSealer(t *testing.T) {
+	sealer := NewSealer(testSecret)
+
+	sealed, err := sealer.Seal("header.payload.signature", "queue-1")
+	if err != nil {
+		t.Fatalf("Seal() failed: %v", err)
+	}
+	opened, err := sealer.Open(sealed, "queue-1")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if opened != "header.payload.signature" {
+		t.Errorf("Expected the sealed token back, got %q", opened)
+	}
+
+	tests := []struct {
+		name    string
+		sealer  *Sealer
+		sealed  string
+		queueID string
+	}{
+		{"other queue entry", sealer, sealed, "queue-2"},
+		{"other secret", NewSealer("another-secret-that-is-at-least-32-characters"), sealed, "queue-1"},
+		{"tampered", sealer, sealed[:len(sealed)-2] + "AA", "queue-1"},
+		{"garbage", sealer, "not-sealed", "queue-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.sealer.Open(tt.sealed, tt.queueID); err == nil {
+				t.Error("Expected Open() to fail")
+			}
+		})
+	}
+}
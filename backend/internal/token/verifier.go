@@ -4,14 +4,15 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"gatekeep/internal/metrics"
 	redisclient "gatekeep/internal/redis"
 )
 
@@ -19,6 +20,8 @@ import (
 type Verifier struct {
 	redisClient *redisclient.Client
 	secret      []byte
+	issuer      string
+	leeway      time.Duration
 	ctx         context.Context
 }
 
@@ -27,61 +30,112 @@ func NewVerifier(redisClient *redisclient.Client, secret string) *Verifier {
 	return &Verifier{
 		redisClient: redisClient,
 		secret:      []byte(secret),
+		issuer:      DefaultIssuer,
+		leeway:      DefaultLeeway,
 		ctx:         context.Background(),
 	}
 }
 
-// VerifyToken verifies a token and returns the payload
-func (v *Verifier) VerifyToken(token string, expectedEventID string) (*TokenPayload, error) {
-	if token == "" {
-		return nil, fmt.Errorf("token is required")
-	}
-
-	// Split token into parts
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid token format: expected 3 parts, got %d", len(parts))
-	}
+// SetIssuer sets the "iss" claim tokens must carry
+func (v *Verifier) SetIssuer(issuer string) {
+	v.issuer = issuer
+}
 
-	headerEncoded := parts[0]
-	payloadEncoded := parts[1]
-	signatureEncoded := parts[2]
+// SetLeeway sets the clock skew tolerated when checking "exp" and "nbf"
+func (v *Verifier) SetLeeway(leeway time.Duration) {
+	v.leeway = leeway
+}
 
-	// Verify signature
-	signatureInput := headerEncoded + "." + payloadEncoded
-	expectedSignature := v.createSignature(signatureInput)
-	expectedSignatureEncoded := base64.RawURLEncoding.EncodeToString(expectedSignature)
+// VerifyToken verifies a token and returns the payload
+func (v *Verifier) VerifyToken(token string, expectedEventID string) (*TokenPayload, error) {
+	payload, err := v.verifyToken(token, expectedEventID)
+	metrics.TokenVerifications.WithLabelValues(VerificationResult(err)).Inc()
+	return payload, err
+}
 
-	if signatureEncoded != expectedSignatureEncoded {
-		return nil, fmt.Errorf("invalid token signature")
+// VerificationResult labels the outcome of a verification for metrics:
+// "valid", the reason a token was rejected, or "error" when verification
+// could not complete
+func VerificationResult(err error) string {
+	switch {
+	case err == nil:
+		return "valid"
+	case errors.Is(err, ErrMalformedToken):
+		return "malformed"
+	case errors.Is(err, ErrInvalidSignature):
+		return "invalid_signature"
+	case errors.Is(err, ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, ErrTokenNotYetValid):
+		return "not_yet_valid"
+	case errors.Is(err, ErrIssuerMismatch):
+		return "issuer_mismatch"
+	case errors.Is(err, ErrEventMismatch):
+		return "event_mismatch"
+	case errors.Is(err, ErrTokenRevoked):
+		return "revoked"
 	}
+	return "error"
+}
 
-	// Decode payload
-	payloadJSON, err := base64.RawURLEncoding.DecodeString(payloadEncoded)
+// verifyToken checks a token's signature, times, issuer, event and
+// revocation
+func (v *Verifier) verifyToken(token string, expectedEventID string) (*TokenPayload, error) {
+	payload, err := v.parseToken(token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode payload: %w", err)
+		return nil, err
 	}
 
-	var payload TokenPayload
-	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	// Check expiry and not-before, allowing for clock skew
+	now := time.Now()
+	if now.After(payload.ExpiresAt.Add(v.leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if !payload.NotBefore.IsZero() && now.Add(v.leeway).Before(payload.NotBefore) {
+		return nil, ErrTokenNotYetValid
 	}
 
-	// Check expiry
-	if time.Now().After(payload.ExpiresAt) {
-		return nil, fmt.Errorf("token has expired")
+	// Tokens issued before the "iss" claim was introduced carry none
+	if payload.Issuer != "" && payload.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: got %s", ErrIssuerMismatch, payload.Issuer)
 	}
 
 	// Validate event_id if provided
 	if expectedEventID != "" && payload.EventID != expectedEventID {
-		return nil, fmt.Errorf("token event_id mismatch: expected %s, got %s", expectedEventID, payload.EventID)
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrEventMismatch, expectedEventID, payload.EventID)
+	}
+
+	// Check revocation by nonce and bulk revocations of the event, user,
+	// device or issue window
+	if err := v.checkRevocations(payload); err != nil {
+		return nil, err
 	}
 
 	// Optional: Check Redis for revocation or single-use
-	if err := v.checkTokenMetadata(token); err != nil {
+	if err := v.checkTokenMetadata(TokenHash(token)); err != nil {
 		return nil, err
 	}
 
+	return payload, nil
+}
+
+// parseToken checks a token's signature and decodes its payload. It does not
+// check expiry or revocation.
+func (v *Verifier) parseToken(token string) (*TokenPayload, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: token is required", ErrMalformedToken)
+	}
+
+	payloadJSON, err := v.decodeJWS(token, MaxTokenLength, TokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload TokenPayload
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal payload: %v", ErrMalformedToken, err)
+	}
+
 	return &payload, nil
 }
 
@@ -93,13 +147,9 @@ func (v *Verifier) createSignature(input string) []byte {
 }
 
 // checkTokenMetadata checks token metadata in Redis
-func (v *Verifier) checkTokenMetadata(token string) error {
-	key := TokenKey(token)
-	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
-	defer cancel()
-
-	data, err := v.redisClient.GetClient().Get(ctx, key).Result()
-	if err == redis.Nil {
+func (v *Verifier) checkTokenMetadata(tokenHash string) error {
+	metadata, err := v.GetTokenMetadataByHash(tokenHash)
+	if err == ErrNotFound {
 		// Token not found in Redis - might be expired or invalid
 		// We still allow verification if signature is valid
 		return nil
@@ -108,13 +158,8 @@ func (v *Verifier) checkTokenMetadata(token string) error {
 		return fmt.Errorf("failed to check token metadata: %w", err)
 	}
 
-	var metadata TokenMetadata
-	if err := json.Unmarshal([]byte(data), &metadata); err != nil {
-		return fmt.Errorf("failed to unmarshal metadata: %w", err)
-	}
-
 	if metadata.Revoked {
-		return fmt.Errorf("token has been revoked")
+		return ErrTokenRevoked
 	}
 
 	return nil
@@ -122,13 +167,18 @@ func (v *Verifier) checkTokenMetadata(token string) error {
 
 // GetTokenMetadata retrieves token metadata from Redis
 func (v *Verifier) GetTokenMetadata(token string) (*TokenMetadata, error) {
-	key := TokenKey(token)
+	return v.GetTokenMetadataByHash(TokenHash(token))
+}
+
+// GetTokenMetadataByHash retrieves token metadata from Redis by token hash
+func (v *Verifier) GetTokenMetadataByHash(tokenHash string) (*TokenMetadata, error) {
+	key := TokenKey(tokenHash)
 	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
 	defer cancel()
 
 	data, err := v.redisClient.GetClient().Get(ctx, key).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("token metadata not found")
+		return nil, ErrNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token metadata: %w", err)
@@ -142,15 +192,89 @@ func (v *Verifier) GetTokenMetadata(token string) (*TokenMetadata, error) {
 	return &metadata, nil
 }
 
-// RevokeToken revokes a token (admin operation)
+// RevokeToken revokes a token (admin operation). The token's nonce is added
+// to its event's revocation list, so revocation does not depend on the
+// token's metadata still being in Redis.
 func (v *Verifier) RevokeToken(token string) error {
-	key := TokenKey(token)
+	payload, err := v.parseToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := v.revokeNonce(payload); err != nil {
+		return err
+	}
+	logRevoked(TokenHash(token), payload)
+
+	return v.markRevoked(TokenHash(token))
+}
+
+// ClaimToken revokes a verified token that is being exchanged, e.g. for a
+// refreshed one. The revocation is atomic: of concurrent claims of the same
+// token only one succeeds, the others fail with ErrTokenRevoked, so a token is
+// exchanged at most once.
+func (v *Verifier) ClaimToken(token string, payload *TokenPayload) error {
+	claimed, err := v.claimNonce(payload)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("%w: token was already exchanged", ErrTokenRevoked)
+	}
+	logRevoked(TokenHash(token), payload)
+
+	// The nonce revocation is authoritative; the metadata flag is informational
+	if err := v.markRevoked(TokenHash(token)); err != nil {
+		slog.Warn("failed to mark claimed token as revoked", "token_hash", TokenHash(token), "error", err)
+	}
+	return nil
+}
+
+// RevokeTokenByHash revokes a token identified by its hash, e.g. taken from
+// logs or the audit trail. The token's metadata must still be in Redis.
+func (v *Verifier) RevokeTokenByHash(tokenHash string) error {
+	metadata, err := v.GetTokenMetadataByHash(tokenHash)
+	if err != nil {
+		return err
+	}
+
+	payload := &TokenPayload{
+		EventID:   metadata.EventID,
+		DeviceID:  metadata.DeviceID,
+		UserID:    metadata.UserID,
+		QueueID:   metadata.QueueID,
+		IssuedAt:  metadata.IssuedAt,
+		ExpiresAt: metadata.ExpiresAt,
+		Nonce:     metadata.Nonce,
+	}
+	if err := v.revokeNonce(payload); err != nil {
+		return err
+	}
+	logRevoked(tokenHash, payload)
+
+	return v.markRevoked(tokenHash)
+}
+
+// logRevoked logs the revocation of a single token
+func logRevoked(tokenHash string, payload *TokenPayload) {
+	slog.Info("token revoked",
+		"token_hash", tokenHash,
+		"event_id", payload.EventID,
+		"queue_id", payload.QueueID,
+		"device_id", payload.DeviceID,
+	)
+}
+
+// markRevoked flags a token's metadata as revoked, if it still exists
+func (v *Verifier) markRevoked(tokenHash string) error {
+	key := TokenKey(tokenHash)
 	ctx, cancel := context.WithTimeout(v.ctx, 2*time.Second)
 	defer cancel()
 
 	data, err := v.redisClient.GetClient().Get(ctx, key).Result()
 	if err == redis.Nil {
-		return fmt.Errorf("token not found")
+		// Metadata already expired; the nonce revocation is sufficient
+		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("failed to get token metadata: %w", err)
@@ -168,6 +292,5 @@ func (v *Verifier) RevokeToken(token string) error {
 	}
 
 	// Update with same TTL
-	ttl := v.redisClient.GetClient().TTL(ctx, key).Val()
-	return v.redisClient.GetClient().Set(ctx, key, metadataJSON, ttl).Err()
+	return v.redisClient.GetClient().Set(ctx, key, metadataJSON, redis.KeepTTL).Err()
 }
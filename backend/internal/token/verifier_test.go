@@ -2,7 +2,10 @@ package token
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -242,8 +245,8 @@ func TestGetTokenMetadata(t *testing.T) {
 		t.Fatal("GetTokenMetadata() returned nil")
 	}
 
-	if metadata.Token != token {
-		t.Errorf("Token mismatch: expected %s, got %s", token, metadata.Token)
+	if metadata.TokenHash != TokenHash(token) {
+		t.Errorf("TokenHash mismatch: expected %s, got %s", TokenHash(token), metadata.TokenHash)
 	}
 	if metadata.EventID != "event-1" {
 		t.Errorf("EventID mismatch: expected 'event-1', got %s", metadata.EventID)
@@ -311,6 +314,50 @@ func TestRevokeToken(t *testing.T) {
 	}
 }
 
+func TestClaimToken_Concurrent(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	token, err := generator.GenerateToken("event-1", "device-1", "user-1", "queue-claim")
+	if err != nil {
+		t.Fatalf("GenerateToken() failed: %v", err)
+	}
+	payload, err := verifier.VerifyToken(token, "event-1")
+	if err != nil {
+		t.Fatalf("VerifyToken() failed: %v", err)
+	}
+
+	const claims = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < claims; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := verifier.ClaimToken(token, payload)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else if !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("Expected ErrTokenRevoked, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("Expected exactly 1 successful claim, got %d", succeeded)
+	}
+	if _, err := verifier.VerifyToken(token, "event-1"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected claimed token to be revoked, got %v", err)
+	}
+}
+
 func TestRevokeToken_NotFound(t *testing.T) {
 	verifier, _, cleanup := setupTestVerifier(t)
 	if verifier == nil {
@@ -371,3 +418,53 @@ func TestToken_EndToEnd(t *testing.T) {
 		t.Error("ExpiresAt is before IssuedAt")
 	}
 }
+
+func TestRevokeTokenByHash(t *testing.T) {
+	verifier, generator, cleanup := setupTestVerifier(t)
+	if verifier == nil {
+		return
+	}
+	defer cleanup()
+
+	token, err := generator.GenerateToken("event-1", "device-1", "user-1", "queue-1")
+	if err != nil {
+		t.Fatalf("GenerateToken() failed: %v", err)
+	}
+
+	if err := verifier.RevokeTokenByHash(TokenHash(token)); err != nil {
+		t.Fatalf("RevokeTokenByHash() failed: %v", err)
+	}
+
+	if _, err := verifier.VerifyToken(token, "event-1"); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected ErrTokenRevoked, got %v", err)
+	}
+
+	if err := verifier.RevokeTokenByHash(TokenHash("unknown")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown hash, got %v", err)
+	}
+}
+
+func TestVerificationResult(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, "valid"},
+		{fmt.Errorf("%w: token is required", ErrMalformedToken), "malformed"},
+		{ErrInvalidSignature, "invalid_signature"},
+		{ErrTokenExpired, "expired"},
+		{ErrTokenNotYetValid, "not_yet_valid"},
+		{fmt.Errorf("%w: got other", ErrIssuerMismatch), "issuer_mismatch"},
+		{fmt.Errorf("%w: expected a, got b", ErrEventMismatch), "event_mismatch"},
+		{ErrTokenRevoked, "revoked"},
+		{errors.New("redis: connection refused"), "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := VerificationResult(tt.err); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
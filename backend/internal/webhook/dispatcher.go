@@ -0,0 +1,542 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	redisclient "gatekeep/internal/redis"
+)
+
+const (
+	// PendingKey is the sorted set of deliveries awaiting an attempt, scored
+	// by the unix milliseconds of their next attempt
+	PendingKey = "webhook:pending"
+	// DeliveryTTL is how long delivery records, including dead letters, are
+	// kept
+	DeliveryTTL = 7 * 24 * time.Hour
+
+	deliveryTimeout = 10 * time.Second
+	// deliveryLease pushes claimed deliveries into the future so other
+	// instances skip them; a crashed instance's claims are retried after it
+	deliveryLease = 30 * time.Second
+	pollInterval  = time.Second
+	batchSize     = 20
+)
+
+// SubscriptionsKey returns the hash of an event's subscriptions by id
+func SubscriptionsKey(eventID string) string {
+	return fmt.Sprintf("webhook:subscriptions:%s", eventID)
+}
+
+// DeliveryKey returns the key of a delivery record
+func DeliveryKey(deliveryID string) string {
+	return fmt.Sprintf("webhook:delivery:%s", deliveryID)
+}
+
+// DeadKey returns the sorted set of an event's dead-lettered deliveries,
+// scored by the unix milliseconds they were dead-lettered
+func DeadKey(eventID string) string {
+	return fmt.Sprintf("webhook:dead:%s", eventID)
+}
+
+// claimScript claims due deliveries by moving them to the end of the lease
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], id)
+end
+return ids
+`)
+
+// Delivery is one event sent to one subscription
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	Event          Event      `json:"event"`
+	Attempts       int        `json:"attempts"`
+	LastStatus     int        `json:"last_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeadAt         *time.Time `json:"dead_at,omitempty"`
+}
+
+// Dispatcher stores subscriptions and delivers events to them. Deliveries
+// are queued in Redis, so they survive restarts and are shared by instances.
+type Dispatcher struct {
+	redisClient *redisclient.Client
+	httpClient  *http.Client
+	// allowPrivateNetworks permits subscriptions to private and local
+	// addresses
+	allowPrivateNetworks bool
+	ctx                  context.Context
+	cancel               context.CancelFunc
+	wg                   sync.WaitGroup
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(redisClient *redisclient.Client) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		redisClient: redisClient,
+		httpClient:  newHTTPClient(false),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// SetAllowPrivateNetworks permits webhooks to loopback, private and
+// link-local addresses, e.g. for receivers inside the deployment. It must be
+// called before Start.
+func (d *Dispatcher) SetAllowPrivateNetworks(allow bool) {
+	d.allowPrivateNetworks = allow
+	d.httpClient = newHTTPClient(allow)
+}
+
+// Start starts the delivery worker
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.run()
+}
+
+// Stop stops the delivery worker; deliveries in flight are retried later
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			// Drain the backlog a batch at a time
+			for {
+				claimed, err := d.processDue()
+				if err != nil {
+					slog.Error("failed to process webhook deliveries", "error", err)
+				}
+				if claimed < batchSize || d.ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Subscribe stores a subscription, generating its id and, when none is
+// given, its signing secret. The secret is only returned here.
+func (d *Dispatcher) Subscribe(sub Subscription) (*Subscription, error) {
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+	if !d.allowPrivateNetworks {
+		if err := checkHost(sub.URL); err != nil {
+			return nil, err
+		}
+	}
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = time.Now().UTC()
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		sub.Secret = "whsec_" + hex.EncodeToString(secret)
+	}
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	if err := d.redisClient.GetClient().HSet(ctx, SubscriptionsKey(sub.EventID), sub.ID, data).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// Subscriptions returns an event's subscriptions without their secrets
+func (d *Dispatcher) Subscriptions(eventID string) ([]*Subscription, error) {
+	subs, err := d.subscriptions(eventID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	return subs, nil
+}
+
+// Unsubscribe removes a subscription; its pending deliveries are dropped
+func (d *Dispatcher) Unsubscribe(eventID, subscriptionID string) error {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	removed, err := d.redisClient.GetClient().HDel(ctx, SubscriptionsKey(eventID), subscriptionID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove subscription: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+	return nil
+}
+
+func (d *Dispatcher) subscriptions(eventID string) ([]*Subscription, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	values, err := d.redisClient.GetClient().HGetAll(ctx, SubscriptionsKey(eventID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	subs := make([]*Subscription, 0, len(values))
+	for _, value := range values {
+		var sub Subscription
+		if err := json.Unmarshal([]byte(value), &sub); err != nil {
+			continue
+		}
+		subs = append(subs, &sub)
+	}
+	return subs, nil
+}
+
+func (d *Dispatcher) subscription(ctx context.Context, eventID, subscriptionID string) (*Subscription, error) {
+	data, err := d.redisClient.GetClient().HGet(ctx, SubscriptionsKey(eventID), subscriptionID).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: subscription %s", ErrNotFound, subscriptionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscription: %w", err)
+	}
+
+	var sub Subscription
+	if err := json.Unmarshal([]byte(data), &sub); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal subscription: %w", err)
+	}
+	return &sub, nil
+}
+
+// Emit queues an event for every subscription of its event that wants it.
+// Emitting never fails the caller: errors are logged. A nil Dispatcher
+// ignores events, so webhooks are optional for its callers.
+func (d *Dispatcher) Emit(event Event) {
+	if d == nil {
+		return
+	}
+	if err := d.enqueue(event); err != nil {
+		slog.Error("failed to queue webhook", "type", event.Type, "event_id", event.EventID, "queue_id", event.QueueID, "error", err)
+	}
+}
+
+func (d *Dispatcher) enqueue(event Event) error {
+	subs, err := d.subscriptions(event.EventID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = now
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	pipe := d.redisClient.GetClient().Pipeline()
+	queued := 0
+	for _, sub := range subs {
+		if !sub.Wants(event.Type) {
+			continue
+		}
+		delivery := &Delivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			EventID:        event.EventID,
+			Event:          event,
+			CreatedAt:      now,
+			NextAttemptAt:  now,
+		}
+		data, err := json.Marshal(delivery)
+		if err != nil {
+			return fmt.Errorf("failed to marshal delivery: %w", err)
+		}
+		pipe.Set(ctx, DeliveryKey(delivery.ID), data, DeliveryTTL)
+		pipe.ZAdd(ctx, PendingKey, redis.Z{Score: float64(now.UnixMilli()), Member: delivery.ID})
+		queued++
+	}
+	if queued == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to queue deliveries: %w", err)
+	}
+	return nil
+}
+
+// processDue claims due deliveries and attempts them concurrently, returning
+// how many were claimed
+func (d *Dispatcher) processDue() (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	ids, err := claimScript.Run(ctx, d.redisClient.GetClient(), []string{PendingKey},
+		now.UnixMilli(), batchSize, now.Add(deliveryLease).UnixMilli()).StringSlice()
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := d.attempt(id); err != nil {
+				slog.Warn("webhook delivery failed", "delivery_id", id, "error", err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	return len(ids), nil
+}
+
+// attempt sends a claimed delivery once, then removes it, schedules a retry
+// or dead-letters it
+func (d *Dispatcher) attempt(deliveryID string) error {
+	ctx, cancel := context.WithTimeout(d.ctx, deliveryTimeout+2*time.Second)
+	defer cancel()
+
+	client := d.redisClient.GetClient()
+	delivery, err := d.delivery(ctx, deliveryID)
+	if errors.Is(err, ErrNotFound) {
+		client.ZRem(ctx, PendingKey, deliveryID)
+		return err
+	}
+	if err != nil {
+		// Left pending; the lease expires and the delivery is retried
+		return err
+	}
+
+	sub, err := d.subscription(ctx, delivery.EventID, delivery.SubscriptionID)
+	if errors.Is(err, ErrNotFound) {
+		// The subscription was removed; drop its deliveries
+		client.ZRem(ctx, PendingKey, deliveryID)
+		client.Del(ctx, DeliveryKey(deliveryID))
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	status, sendErr := d.send(ctx, sub, delivery)
+	if d.ctx.Err() != nil {
+		// Shutting down; the lease expires and the delivery is retried
+		return nil
+	}
+	if sendErr == nil {
+		pipe := client.TxPipeline()
+		pipe.ZRem(ctx, PendingKey, deliveryID)
+		pipe.Del(ctx, DeliveryKey(deliveryID))
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = sendErr.Error()
+	now := time.Now().UTC()
+
+	pipe := client.TxPipeline()
+	if delivery.Attempts >= MaxAttempts {
+		delivery.DeadAt = &now
+		pipe.ZRem(ctx, PendingKey, deliveryID)
+		pipe.ZAdd(ctx, DeadKey(delivery.EventID), redis.Z{Score: float64(now.UnixMilli()), Member: deliveryID})
+	} else {
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+		pipe.ZAdd(ctx, PendingKey, redis.Z{Score: float64(delivery.NextAttemptAt.UnixMilli()), Member: deliveryID})
+	}
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	pipe.Set(ctx, DeliveryKey(deliveryID), data, DeliveryTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to reschedule delivery: %w", err)
+	}
+	return nil
+}
+
+// send posts the signed event to the subscription URL. Any 2xx response is a
+// success; the status is returned for the delivery record.
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery *Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Gatekeep-Webhook/1.0")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) delivery(ctx context.Context, deliveryID string) (*Delivery, error) {
+	data, err := d.redisClient.GetClient().Get(ctx, DeliveryKey(deliveryID)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("%w: delivery %s", ErrNotFound, deliveryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery: %w", err)
+	}
+
+	var delivery Delivery
+	if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// DeadLetters returns a page of an event's dead-lettered deliveries, most
+// recent first, and their total
+func (d *Dispatcher) DeadLetters(eventID string, offset, limit int) ([]*Delivery, int64, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	client := d.redisClient.GetClient()
+	deadKey := DeadKey(eventID)
+
+	// Forget dead letters whose records have expired
+	cutoff := time.Now().Add(-DeliveryTTL).UnixMilli()
+	client.ZRemRangeByScore(ctx, deadKey, "-inf", strconv.FormatInt(cutoff, 10))
+
+	total, err := client.ZCard(ctx, deadKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	if limit <= 0 {
+		return []*Delivery{}, total, nil
+	}
+
+	ids, err := client.ZRevRange(ctx, deadKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	deliveries := make([]*Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := d.delivery(ctx, id)
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, total, nil
+}
+
+// Replay queues a dead-lettered delivery for immediate delivery with a fresh
+// set of attempts
+func (d *Dispatcher) Replay(eventID, deliveryID string) error {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	defer cancel()
+
+	client := d.redisClient.GetClient()
+	delivery, err := d.delivery(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.EventID != eventID {
+		return fmt.Errorf("%w: delivery %s", ErrNotFound, deliveryID)
+	}
+
+	removed, err := client.ZRem(ctx, DeadKey(eventID), deliveryID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to replay delivery: %w", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%w: dead letter %s", ErrNotFound, deliveryID)
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts = 0
+	delivery.DeadAt = nil
+	delivery.NextAttemptAt = now
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	pipe := client.TxPipeline()
+	pipe.Set(ctx, DeliveryKey(deliveryID), data, DeliveryTTL)
+	pipe.ZAdd(ctx, PendingKey, redis.Z{Score: float64(now.UnixMilli()), Member: deliveryID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to replay delivery: %w", err)
+	}
+	return nil
+}
+
+// ReplayAll replays every dead-lettered delivery of an event and returns how
+// many were queued
+func (d *Dispatcher) ReplayAll(eventID string) (int, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, 2*time.Second)
+	ids, err := d.redisClient.GetClient().ZRange(ctx, DeadKey(eventID), 0, -1).Result()
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	replayed := 0
+	for _, id := range ids {
+		if err := d.Replay(eventID, id); err != nil {
+			continue
+		}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -0,0 +1,274 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"gatekeep/internal/config"
+	redisclient "gatekeep/internal/redis"
+)
+
+func setupTestDispatcher(t *testing.T) (*Dispatcher, func()) {
+	cfg := &config.Config{
+		Port:          8080,
+		RedisAddr:     "localhost:6379",
+		RedisPassword: "",
+		TokenSecret:   "this-is-a-very-long-secret-key-that-is-at-least-32-characters",
+		AdminAPIKey:   "admin-key",
+		LogLevel:      "info",
+		MetricsPort:   9090,
+	}
+
+	redisClient, err := redisclient.NewClient(cfg)
+	if err != nil {
+		t.Skipf("Skipping test: Redis not available: %v", err)
+		return nil, nil
+	}
+
+	dispatcher := NewDispatcher(redisClient)
+	// Test receivers listen on loopback
+	dispatcher.SetAllowPrivateNetworks(true)
+
+	cleanup := func() {
+		ctx := context.Background()
+		client := redisClient.GetClient()
+		iter := client.Scan(ctx, 0, "webhook:*", 100).Iterator()
+		for iter.Next(ctx) {
+			client.Del(ctx, iter.Val())
+		}
+	}
+	cleanup()
+
+	return dispatcher, cleanup
+}
+
+// testReceiver records webhook deliveries and fails them while failing is set
+type testReceiver struct {
+	mu      sync.Mutex
+	failing bool
+	events  []Event
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if err := VerifySignature("secret", req.Header.Get(SignatureHeader), body, DefaultTolerance); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var event Event
+	_ = json.Unmarshal(body, &event)
+	r.events = append(r.events, event)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *testReceiver) received() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// makeDue moves every pending delivery's next attempt to now
+func makeDue(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx := context.Background()
+	client := d.redisClient.GetClient()
+	ids, err := client.ZRange(ctx, PendingKey, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("Failed to list pending deliveries: %v", err)
+	}
+	for _, id := range ids {
+		client.ZAdd(ctx, PendingKey, redis.Z{Score: 0, Member: id})
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	dispatcher, cleanup := setupTestDispatcher(t)
+	if dispatcher == nil {
+		return
+	}
+	defer cleanup()
+
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if _, err := dispatcher.Subscribe(Subscription{
+		EventID: "event-1",
+		URL:     server.URL,
+		Secret:  "secret",
+		Events:  []string{EventAdmissionGranted},
+	}); err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+
+	subs, err := dispatcher.Subscriptions("event-1")
+	if err != nil {
+		t.Fatalf("Subscriptions() failed: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Secret != "" {
+		t.Fatalf("Expected one subscription without its secret, got %+v", subs)
+	}
+
+	dispatcher.Emit(Event{Type: EventJoined, EventID: "event-1", QueueID: "q-1"})
+	dispatcher.Emit(Event{Type: EventAdmissionGranted, EventID: "event-1", QueueID: "q-1", TokenHash: "abc"})
+	dispatcher.Emit(Event{Type: EventAdmissionGranted, EventID: "event-2", QueueID: "q-2"})
+
+	claimed, err := dispatcher.processDue()
+	if err != nil {
+		t.Fatalf("processDue() failed: %v", err)
+	}
+	if claimed != 1 {
+		t.Errorf("Expected 1 delivery, got %d", claimed)
+	}
+
+	events := receiver.received()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 received event, got %d", len(events))
+	}
+	if events[0].Type != EventAdmissionGranted || events[0].TokenHash != "abc" || events[0].ID == "" {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+
+	pending, _ := dispatcher.redisClient.GetClient().ZCard(context.Background(), PendingKey).Result()
+	if pending != 0 {
+		t.Errorf("Expected no pending deliveries, got %d", pending)
+	}
+}
+
+func TestDispatcher_DeadLetterAndReplay(t *testing.T) {
+	dispatcher, cleanup := setupTestDispatcher(t)
+	if dispatcher == nil {
+		return
+	}
+	defer cleanup()
+
+	receiver := &testReceiver{failing: true}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if _, err := dispatcher.Subscribe(Subscription{EventID: "event-1", URL: server.URL, Secret: "secret"}); err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	dispatcher.Emit(Event{Type: EventLeft, EventID: "event-1", QueueID: "q-1"})
+
+	// Every failed attempt is retried with backoff until MaxAttempts
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		makeDue(t, dispatcher)
+		if _, err := dispatcher.processDue(); err != nil {
+			t.Fatalf("processDue() failed: %v", err)
+		}
+	}
+
+	dead, total, err := dispatcher.DeadLetters("event-1", 0, 10)
+	if err != nil {
+		t.Fatalf("DeadLetters() failed: %v", err)
+	}
+	if total != 1 || len(dead) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", total)
+	}
+	if dead[0].Attempts != MaxAttempts || dead[0].LastStatus != http.StatusInternalServerError || dead[0].DeadAt == nil {
+		t.Errorf("Unexpected dead letter: %+v", dead[0])
+	}
+
+	if err := dispatcher.Replay("event-2", dead[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound replaying another event's delivery, got %v", err)
+	}
+
+	receiver.mu.Lock()
+	receiver.failing = false
+	receiver.mu.Unlock()
+
+	replayed, err := dispatcher.ReplayAll("event-1")
+	if err != nil {
+		t.Fatalf("ReplayAll() failed: %v", err)
+	}
+	if replayed != 1 {
+		t.Errorf("Expected 1 replayed delivery, got %d", replayed)
+	}
+	if _, err := dispatcher.processDue(); err != nil {
+		t.Fatalf("processDue() failed: %v", err)
+	}
+	if events := receiver.received(); len(events) != 1 || events[0].Type != EventLeft {
+		t.Errorf("Expected the replayed event to be delivered, got %+v", events)
+	}
+
+	if _, total, _ := dispatcher.DeadLetters("event-1", 0, 10); total != 0 {
+		t.Errorf("Expected no dead letters after replay, got %d", total)
+	}
+	if err := dispatcher.Replay("event-1", dead[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound replaying a delivered event, got %v", err)
+	}
+}
+
+func TestDispatcher_Unsubscribe(t *testing.T) {
+	dispatcher, cleanup := setupTestDispatcher(t)
+	if dispatcher == nil {
+		return
+	}
+	defer cleanup()
+
+	sub, err := dispatcher.Subscribe(Subscription{EventID: "event-1", URL: "https://example.com/hooks"})
+	if err != nil {
+		t.Fatalf("Subscribe() failed: %v", err)
+	}
+	if sub.Secret == "" {
+		t.Error("Expected a generated secret")
+	}
+
+	if err := dispatcher.Unsubscribe("event-1", sub.ID); err != nil {
+		t.Fatalf("Unsubscribe() failed: %v", err)
+	}
+	if err := dispatcher.Unsubscribe("event-1", sub.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcher_AttemptErrors(t *testing.T) {
+	dispatcher, cleanup := setupTestDispatcher(t)
+	if dispatcher == nil {
+		return
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	client := dispatcher.redisClient.GetClient()
+
+	// A delivery that cannot be read stays pending for a retry
+	client.Set(ctx, DeliveryKey("unreadable"), "{", DeliveryTTL)
+	client.ZAdd(ctx, PendingKey, redis.Z{Score: 0, Member: "unreadable"})
+	// A missing delivery or subscription drops it
+	client.ZAdd(ctx, PendingKey, redis.Z{Score: 0, Member: "missing"})
+	orphan, _ := json.Marshal(Delivery{ID: "orphan", EventID: "event-1", SubscriptionID: "removed"})
+	client.Set(ctx, DeliveryKey("orphan"), orphan, DeliveryTTL)
+	client.ZAdd(ctx, PendingKey, redis.Z{Score: 0, Member: "orphan"})
+
+	if claimed, err := dispatcher.processDue(); err != nil || claimed != 3 {
+		t.Fatalf("Expected 3 claimed deliveries, got %d (%v)", claimed, err)
+	}
+
+	pending, err := client.ZRange(ctx, PendingKey, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("Failed to list pending deliveries: %v", err)
+	}
+	if len(pending) != 1 || pending[0] != "unreadable" {
+		t.Errorf("Expected only the unreadable delivery to stay pending, got %v", pending)
+	}
+	if exists, _ := client.Exists(ctx, DeliveryKey("orphan")).Result(); exists != 0 {
+		t.Error("Expected the orphaned delivery to be deleted")
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// blockedAddress reports whether webhooks may not be sent to the address:
+// loopback, private, link-local and unspecified addresses reach the service's
+// own network rather than a subscriber's
+func blockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsUnspecified()
+}
+
+// checkHost rejects subscription URLs naming a blocked address directly.
+// Host names are checked when they are resolved for each delivery.
+func checkHost(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid url", ErrInvalidSubscription)
+	}
+	if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil && blockedAddress(addr) {
+		return fmt.Errorf("%w: url must not point to a private or local address", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// newHTTPClient creates the client delivering webhooks. Unless private
+// networks are allowed, it refuses to connect to blocked addresses after
+// DNS resolution, so host names resolving to internal services cannot be
+// used either. Redirects are not followed and count as failed deliveries.
+func newHTTPClient(allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: deliveryTimeout, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid address %q: %w", address, err)
+			}
+			if blockedAddress(addrPort.Addr()) {
+				return fmt.Errorf("address %s is not allowed for webhooks", addrPort.Addr())
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on our behalf, bypassing the address check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
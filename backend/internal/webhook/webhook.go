@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types delivered to subscribers
+const (
+	EventJoined           = "joined"
+	EventAdmissionGranted = "admission_granted"
+	EventExpired          = "expired"
+	EventRevoked          = "revoked"
+	EventLeft             = "left"
+)
+
+// EventTypes lists every event type in delivery documentation order
+var EventTypes = []string{EventJoined, EventAdmissionGranted, EventExpired, EventRevoked, EventLeft}
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "Gatekeep-Signature"
+	EventHeader     = "Gatekeep-Event"
+	DeliveryHeader  = "Gatekeep-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is
+	// dead-lettered
+	MaxAttempts = 10
+	// BaseBackoff is the delay before the first retry; it doubles per attempt
+	BaseBackoff = time.Second
+	// MaxBackoff caps the delay between retries
+	MaxBackoff = time.Hour
+	// DefaultTolerance is how old a signature VerifySignature accepts
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidSubscription is returned for subscriptions with a bad URL or
+	// unknown event types
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	// ErrNotFound is returned for unknown subscriptions and deliveries
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalidSignature is returned by VerifySignature
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Event is the payload delivered to subscribers. Admissions carry the token
+// hash, never the token itself.
+type Event struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	EventID    string            `json:"event_id"`
+	QueueID    string            `json:"queue_id,omitempty"`
+	DeviceID   string            `json:"device_id,omitempty"`
+	UserID     string            `json:"user_id,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	TokenHash  string            `json:"token_hash,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// Subscription sends an event's webhooks of the listed types, or all types
+// when Events is empty, to a URL
+type Subscription struct {
+	ID        string    `json:"id"`
+	EventID   string    `json:"event_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the URL and event types
+func (s *Subscription) Validate() error {
+	if s.EventID == "" {
+		return fmt.Errorf("%w: event_id is required", ErrInvalidSubscription)
+	}
+	parsed, err := url.Parse(s.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	for _, eventType := range s.Events {
+		if !knownEventType(eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, eventType)
+		}
+	}
+	return nil
+}
+
+// Wants reports whether the subscription receives events of the type
+func (s *Subscription) Wants(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func knownEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the signature header value for a body sent at the given time:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + computeSignature(secret, t, body)
+}
+
+// VerifySignature checks a signature header against the body, rejecting
+// signatures older than tolerance to prevent replays
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := computeSignature(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+}
+
+func computeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before retrying after the given number of failed
+// attempts: 1s, 2s, 4s, ... capped at MaxBackoff
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	delay := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"type":"joined"}`)
+	now := time.Now()
+	valid := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		wantErr bool
+	}{
+		{"valid", "secret", valid, body, false},
+		{"rotated secret", "secret", Sign("old", now, body) + ",v1=" + computeSignature("secret", timestamp, body), body, false},
+		{"wrong secret", "other", valid, body, true},
+		{"tampered body", "secret", valid, []byte(`{"type":"left"}`), true},
+		{"stale", "secret", Sign("secret", now.Add(-10*time.Minute), body), body, true},
+		{"future", "secret", Sign("secret", now.Add(10*time.Minute), body), body, true},
+		{"missing signature", "secret", "t=123", body, true},
+		{"malformed", "secret", "garbage", body, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, tt.body, DefaultTolerance)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected valid signature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{12, 2048 * time.Second},
+		{13, MaxBackoff},
+		{100, MaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d): expected %v, got %v", tt.attempts, tt.want, got)
+		}
+	}
+}
+
+func TestSubscription_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{"valid", Subscription{EventID: "event-1", URL: "https://example.com/hooks"}, false},
+		{"valid with events", Subscription{EventID: "event-1", URL: "http://hooks.internal", Events: []string{EventJoined, EventLeft}}, false},
+		{"missing event", Subscription{URL: "https://example.com/hooks"}, true},
+		{"relative URL", Subscription{EventID: "event-1", URL: "/hooks"}, true},
+		{"other scheme", Subscription{EventID: "event-1", URL: "ftp://example.com/hooks"}, true},
+		{"unknown event type", Subscription{EventID: "event-1", URL: "https://example.com/hooks", Events: []string{"paused"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sub.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidSubscription) {
+				t.Errorf("Expected ErrInvalidSubscription, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected valid subscription, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSubscription_Wants(t *testing.T) {
+	all := Subscription{}
+	some := Subscription{Events: []string{EventAdmissionGranted}}
+
+	if !all.Wants(EventLeft) {
+		t.Error("Expected a subscription without events to want every type")
+	}
+	if !some.Wants(EventAdmissionGranted) || some.Wants(EventJoined) {
+		t.Error("Expected a subscription to want only its listed types")
+	}
+}
+
+func TestDispatcher_Send(t *testing.T) {
+	status := http.StatusNoContent
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.SetAllowPrivateNetworks(true)
+	sub := &Subscription{ID: "sub-1", EventID: "event-1", URL: server.URL, Secret: "secret"}
+	delivery := &Delivery{
+		ID:    "delivery-1",
+		Event: Event{ID: "evt-1", Type: EventAdmissionGranted, EventID: "event-1", TokenHash: "abc"},
+	}
+
+	if _, err := dispatcher.send(context.Background(), sub, delivery); err != nil {
+		t.Fatalf("send() failed: %v", err)
+	}
+	if got := received.Header.Get(EventHeader); got != EventAdmissionGranted {
+		t.Errorf("Expected event header %s, got %s", EventAdmissionGranted, got)
+	}
+	if got := received.Header.Get(DeliveryHeader); got != "delivery-1" {
+		t.Errorf("Expected delivery header delivery-1, got %s", got)
+	}
+	if err := VerifySignature("secret", received.Header.Get(SignatureHeader), receivedBody, DefaultTolerance); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	code, err := dispatcher.send(context.Background(), sub, delivery)
+	if err == nil {
+		t.Error("Expected error for a 503 response")
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", code)
+	}
+}
+
+func TestDispatcher_SendBlocksPrivateNetworks(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+	sub := &Subscription{ID: "sub-1", EventID: "event-1", URL: server.URL, Secret: "secret"}
+	delivery := &Delivery{ID: "delivery-1", Event: Event{ID: "evt-1", Type: EventJoined, EventID: "event-1"}}
+
+	if _, err := dispatcher.send(context.Background(), sub, delivery); err == nil {
+		t.Error("Expected delivery to a loopback address to fail")
+	}
+	if called {
+		t.Error("Expected no request to reach the loopback receiver")
+	}
+	if _, err := dispatcher.Subscribe(Subscription{EventID: "event-1", URL: server.URL}); !errors.Is(err, ErrInvalidSubscription) {
+		t.Errorf("Expected ErrInvalidSubscription for a loopback URL, got %v", err)
+	}
+}
+
+func TestDispatcher_SendDoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.SetAllowPrivateNetworks(true)
+	sub := &Subscription{ID: "sub-1", EventID: "event-1", URL: server.URL, Secret: "secret"}
+	delivery := &Delivery{ID: "delivery-1", Event: Event{ID: "evt-1", Type: EventJoined, EventID: "event-1"}}
+
+	code, err := dispatcher.send(context.Background(), sub, delivery)
+	if err == nil {
+		t.Error("Expected a redirect to fail the delivery")
+	}
+	if code != http.StatusFound {
+		t.Errorf("Expected status 302, got %d", code)
+	}
+	if redirected {
+		t.Error("Expected the redirect not to be followed")
+	}
+}
+
+func TestBlockedAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := blockedAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}